@@ -0,0 +1,354 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Purposes of single-use action tokens sent by email
+const (
+	purposePasswordReset     = "password_reset"
+	purposeEmailVerification = "email_verification"
+)
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 24 * time.Hour
+)
+
+var (
+	ErrInvalidActionToken = errors.New("invalid action token")
+	ErrActionTokenUsed    = errors.New("action token already used")
+)
+
+// ActionClaims represents the claims of a single-use token sent by email.
+// The subject holds the user id and the token id is recorded once the token is used.
+type ActionClaims struct {
+	Purpose string `json:"purpose"`
+	Email   string `json:"email"`
+	// PasswordFingerprint ties a reset token to the password it replaces,
+	// so the token stops working once the password changes
+	PasswordFingerprint string `json:"pwf,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// ForgotPasswordRequest represents the password reset request payload
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest represents the new password payload
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// VerifyEmailRequest represents the email verification payload
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// generateActionToken creates a signed single-use token for the user
+func generateActionToken(userID int, email, purpose, passwordFingerprint string, ttl time.Duration) (string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := ActionClaims{
+		Purpose:             purpose,
+		Email:               email,
+		PasswordFingerprint: passwordFingerprint,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.Itoa(userID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	return signToken(claims)
+}
+
+// parseActionToken validates the signature, expiry and purpose of an action token
+func parseActionToken(tokenString, purpose string) (*ActionClaims, int, error) {
+	var claims ActionClaims
+
+	_, err := jwt.ParseWithClaims(tokenString, &claims, verificationKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || claims.Purpose != purpose || claims.ID == "" {
+		return nil, 0, ErrInvalidActionToken
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return nil, 0, ErrInvalidActionToken
+	}
+
+	return &claims, userID, nil
+}
+
+// markActionTokenUsed records the token id so the token cannot be used again
+func markActionTokenUsed(tx *sql.Tx, claims *ActionClaims) error {
+	_, err := tx.Exec("INSERT INTO used_tokens (jti, expires_at) VALUES (?, ?)", claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return ErrActionTokenUsed
+		}
+		return fmt.Errorf("failed to mark token as used: %w", err)
+	}
+
+	return nil
+}
+
+// passwordFingerprint returns a short digest of the stored password hash
+func passwordFingerprint(passwordHash string) string {
+	hash := sha256.Sum256([]byte(passwordHash))
+	return hex.EncodeToString(hash[:8])
+}
+
+// actionLink builds a frontend link carrying the token
+func actionLink(path, token string) string {
+	return frontendURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// sendVerificationEmail sends an email verification link to the user
+func sendVerificationEmail(userID int, email string) error {
+	token, err := generateActionToken(userID, email, purposeEmailVerification, "", emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	body := "Здравствуйте!\r\n\r\n" +
+		"Чтобы подтвердить адрес электронной почты, перейдите по ссылке:\r\n" +
+		actionLink("/verify-email", token) + "\r\n\r\n" +
+		"Ссылка действительна 24 часа."
+
+	return sendEmail(email, "Подтверждение адреса электронной почты", body)
+}
+
+// sendPasswordResetEmail sends a password reset link to the user
+func sendPasswordResetEmail(userID int, email, passwordHash string) error {
+	token, err := generateActionToken(userID, email, purposePasswordReset, passwordFingerprint(passwordHash), passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	body := "Здравствуйте!\r\n\r\n" +
+		"Для сброса пароля перейдите по ссылке:\r\n" +
+		actionLink("/reset-password", token) + "\r\n\r\n" +
+		"Ссылка действительна 1 час. Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо."
+
+	return sendEmail(email, "Сброс пароля", body)
+}
+
+// handleForgotPassword emails a password reset link. The response does not reveal
+// whether the email is registered.
+func handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	var userID int
+	var passwordHash string
+	err := db.QueryRow("SELECT id, password_hash FROM users WHERE email = ?", req.Email).Scan(&userID, &passwordHash)
+	if err == nil {
+		// Send in the background so the response time does not depend on whether the user exists
+		go func() {
+			if err := sendPasswordResetEmail(userID, req.Email, passwordHash); err != nil {
+				log.Printf("Failed to send password reset email to user %d: %v", userID, err)
+			}
+		}()
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Failed to look up user for password reset: %v", err)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleResetPassword sets a new password using a reset token and logs out all sessions
+func handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Password) < minPasswordLength {
+		http.Error(w, fmt.Sprintf("Пароль должен содержать не менее %d символов", minPasswordLength), http.StatusBadRequest)
+		return
+	}
+	if len(req.Password) > maxPasswordLength {
+		http.Error(w, fmt.Sprintf("Пароль должен занимать не более %d байт", maxPasswordLength), http.StatusBadRequest)
+		return
+	}
+
+	claims, userID, err := parseActionToken(req.Token, purposePasswordReset)
+	if err != nil {
+		http.Error(w, "Ссылка недействительна или устарела", http.StatusBadRequest)
+		return
+	}
+
+	newHash, err := hashPassword(req.Password)
+	if err != nil {
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	if err := resetPassword(userID, claims, newHash); err != nil {
+		if errors.Is(err, ErrInvalidActionToken) || errors.Is(err, ErrActionTokenUsed) {
+			http.Error(w, "Ссылка недействительна или устарела", http.StatusBadRequest)
+			return
+		}
+		log.Printf("Failed to reset password for user %d: %v", userID, err)
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	// A password reset may follow a compromise, so end every existing session
+	if err := revokeUserSessions(userID); err != nil {
+		log.Printf("Failed to revoke sessions of user %d: %v", userID, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resetPassword consumes the reset token and stores the new password hash
+func resetPassword(userID int, claims *ActionClaims, newHash string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var currentHash string
+	err = tx.QueryRow("SELECT password_hash FROM users WHERE id = ? FOR UPDATE", userID).Scan(&currentHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidActionToken
+		}
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+
+	if passwordFingerprint(currentHash) != claims.PasswordFingerprint {
+		return ErrInvalidActionToken
+	}
+
+	if err := markActionTokenUsed(tx, claims); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE users SET password_hash = ? WHERE id = ?", newHash, userID); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// handleVerifyEmail marks the email of the token's user as verified
+func handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	claims, userID, err := parseActionToken(req.Token, purposeEmailVerification)
+	if err != nil {
+		http.Error(w, "Ссылка недействительна или устарела", http.StatusBadRequest)
+		return
+	}
+
+	if err := verifyEmail(userID, claims); err != nil {
+		if errors.Is(err, ErrInvalidActionToken) || errors.Is(err, ErrActionTokenUsed) {
+			http.Error(w, "Ссылка недействительна или устарела", http.StatusBadRequest)
+			return
+		}
+		log.Printf("Failed to verify email of user %d: %v", userID, err)
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verifyEmail consumes the verification token if it was issued for the current email
+func verifyEmail(userID int, claims *ActionClaims) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE users SET email_verified = TRUE WHERE id = ? AND email = ?", userID, claims.Email)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	// Zero rows means either the email changed since the link was sent or it is already verified
+	if n, _ := res.RowsAffected(); n == 0 {
+		var verified bool
+		err := tx.QueryRow("SELECT email_verified FROM users WHERE id = ? AND email = ?", userID, claims.Email).Scan(&verified)
+		if err != nil || !verified {
+			return ErrInvalidActionToken
+		}
+	}
+
+	if err := markActionTokenUsed(tx, claims); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// handleResendVerification sends a new verification link to the authenticated user
+func handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	claims, _ := claimsFromContext(r.Context())
+
+	user, err := getUserByID(claims.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if user.EmailVerified {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := sendVerificationEmail(user.ID, user.Email); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		http.Error(w, "Failed to send email", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// cleanupUsedTokens periodically forgets used tokens that have expired anyway
+func cleanupUsedTokens() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := db.Exec("DELETE FROM used_tokens WHERE expires_at < NOW()"); err != nil {
+			log.Printf("Failed to clean up used tokens: %v", err)
+		}
+	}
+}
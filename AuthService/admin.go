@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Account-wide roles. Event-scoped roles such as co-organizer are managed by the Backend.
+const (
+	RoleAttendee  = "attendee"
+	RoleOrganizer = "organizer"
+	RoleAdmin     = "admin"
+)
+
+var validRoles = map[string]bool{
+	RoleAttendee:  true,
+	RoleOrganizer: true,
+	RoleAdmin:     true,
+}
+
+// bootstrapAdmin grants the admin role to the account named by BOOTSTRAP_ADMIN_EMAIL, so the first
+// admin exists before anyone can call handleSetUserRole. The account must already be registered and
+// have a verified email; otherwise nothing changes and the operator restarts the service after verifying.
+func bootstrapAdmin(email string) error {
+	if email == "" {
+		return nil
+	}
+
+	res, err := db.Exec("UPDATE users SET role = ? WHERE email = ? AND email_verified = TRUE", RoleAdmin, email)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("Granted the admin role to %s", email)
+		return nil
+	}
+
+	var role string
+	err = db.QueryRow("SELECT role FROM users WHERE email = ? AND email_verified = TRUE", email).Scan(&role)
+	if err != nil || role != RoleAdmin {
+		log.Printf("Bootstrap admin %s is not a registered account with a verified email yet", email)
+	}
+	return nil
+}
+
+// SetRoleRequest represents the role assignment payload
+type SetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// handleSetUserRole changes the account-wide role of a user
+func handleSetUserRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if !validRoles[req.Role] {
+		http.Error(w, "Unknown role", http.StatusBadRequest)
+		return
+	}
+
+	res, err := db.Exec("UPDATE users SET role = ? WHERE id = ?", req.Role, userID)
+	if err != nil {
+		http.Error(w, "Failed to update role", http.StatusInternalServerError)
+		return
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		// MySQL reports zero affected rows when the role is unchanged, so check existence explicitly
+		var exists bool
+		if err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = ?)", userID).Scan(&exists); err != nil || !exists {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,15 +2,34 @@ module AuthService
 
 go 1.23.4
 
-require github.com/go-sql-driver/mysql v1.9.0
+require (
+	github.com/go-sql-driver/mysql v1.9.0
+	github.com/golang-migrate/migrate/v4 v4.18.2
+	github.com/segmentio/kafka-go v0.4.47
+	golang.org/x/crypto v0.32.0
+	google.golang.org/grpc v1.70.0
+)
+
+require (
+	github.com/hashicorp/errwrap v1.1.0 // indirect
+	github.com/hashicorp/go-multierror v1.1.1 // indirect
+	github.com/klauspost/compress v1.15.11 // indirect
+	github.com/pierrec/lz4/v4 v4.1.16 // indirect
+	go.uber.org/atomic v1.7.0 // indirect
+	golang.org/x/net v0.33.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20241202173237-19429a94021a // indirect
+	google.golang.org/protobuf v1.36.5 // indirect
+)
 
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
-	github.com/go-chi/chi/v5 v5.2.1 // indirect
+	github.com/TkMaxim9/EventOrganizationApp/proto v0.0.0-20250217173252-428784ef2eca
+	github.com/go-chi/chi/v5 v5.2.1
 	github.com/go-chi/cors v1.2.1
-	github.com/golang-jwt/jwt/v5 v5.2.1 // indirect
-	github.com/klauspost/compress v1.15.9 // indirect
-	github.com/lib/pq v1.10.9 // indirect
-	github.com/pierrec/lz4/v4 v4.1.15 // indirect
-	github.com/segmentio/kafka-go v0.4.47 // indirect
+	github.com/golang-jwt/jwt/v5 v5.2.1
+	github.com/lib/pq v1.10.9
 )
+
+replace github.com/TkMaxim9/EventOrganizationApp/proto => ../proto
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log"
+	"net"
+	"strings"
+
+	authpb "github.com/TkMaxim9/EventOrganizationApp/proto/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// maxListUsers bounds the number of ids in a single ListUsersByIds call
+const maxListUsers = 500
+
+// authGRPCServer implements the AuthService gRPC API for other services
+type authGRPCServer struct {
+	authpb.UnimplementedAuthServiceServer
+}
+
+// serveGRPC starts the gRPC API on the address and blocks until it stops.
+// Every call must carry the shared service token.
+func serveGRPC(addr, token string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	s := grpc.NewServer(grpc.UnaryInterceptor(requireServiceToken(token)))
+	authpb.RegisterAuthServiceServer(s, &authGRPCServer{})
+
+	log.Printf("gRPC server starting on %s", addr)
+	return s.Serve(lis)
+}
+
+// requireServiceToken rejects calls without the service token in the "authorization" metadata
+func requireServiceToken(token string) grpc.UnaryServerInterceptor {
+	expected := []byte("Bearer " + token)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) != 1 || subtle.ConstantTimeCompare([]byte(values[0]), expected) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "service token is required")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// ValidateToken reports whether an access token is valid and its session is not revoked.
+// Invalid tokens are not an error, they are reported as inactive.
+func (s *authGRPCServer) ValidateToken(ctx context.Context, req *authpb.ValidateTokenRequest) (*authpb.ValidateTokenResponse, error) {
+	token := strings.TrimPrefix(req.GetToken(), "Bearer ")
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	claims, err := parseAccessToken(token)
+	if err != nil {
+		return &authpb.ValidateTokenResponse{}, nil
+	}
+
+	active, err := isSessionActive(claims.SessionID)
+	if err != nil {
+		log.Printf("Failed to check session %s: %v", claims.SessionID, err)
+		return nil, status.Error(codes.Unavailable, "failed to check session")
+	}
+	if !active {
+		return &authpb.ValidateTokenResponse{}, nil
+	}
+
+	return &authpb.ValidateTokenResponse{
+		Active: true,
+		User: &authpb.User{
+			Id:            int64(claims.UserID),
+			Email:         claims.Email,
+			Role:          claims.Role,
+			EmailVerified: claims.EmailVerified,
+		},
+		SessionId: claims.SessionID,
+		ExpiresAt: claims.ExpiresAt.Unix(),
+	}, nil
+}
+
+// GetUser returns a user by id
+func (s *authGRPCServer) GetUser(ctx context.Context, req *authpb.GetUserRequest) (*authpb.GetUserResponse, error) {
+	if req.GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "id must be positive")
+	}
+
+	user, err := getUserByID(int(req.GetId()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		log.Printf("Failed to get user %d: %v", req.GetId(), err)
+		return nil, status.Error(codes.Internal, "failed to get user")
+	}
+
+	return &authpb.GetUserResponse{User: user.proto()}, nil
+}
+
+// ListUsersByIds returns the known users among the ids in ascending id order
+func (s *authGRPCServer) ListUsersByIds(ctx context.Context, req *authpb.ListUsersByIdsRequest) (*authpb.ListUsersByIdsResponse, error) {
+	ids := req.GetIds()
+	if len(ids) == 0 {
+		return &authpb.ListUsersByIdsResponse{}, nil
+	}
+	if len(ids) > maxListUsers {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d ids per request", maxListUsers)
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, email, role, email_verified, first_name, last_name
+		FROM users
+		WHERE id IN (`+placeholders+`)
+		ORDER BY id
+	`, args...)
+	if err != nil {
+		log.Printf("Failed to list users: %v", err)
+		return nil, status.Error(codes.Internal, "failed to list users")
+	}
+	defer rows.Close()
+
+	resp := &authpb.ListUsersByIdsResponse{}
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(&user.ID, &user.Email, &user.Role, &user.EmailVerified, &user.FirstName, &user.LastName); err != nil {
+			log.Printf("Failed to scan user: %v", err)
+			return nil, status.Error(codes.Internal, "failed to list users")
+		}
+		resp.Users = append(resp.Users, user.proto())
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Failed to read users: %v", err)
+		return nil, status.Error(codes.Internal, "failed to list users")
+	}
+
+	return resp, nil
+}
+
+// BackfillProfile sets the names of an account that has none. Accounts created before AuthService
+// stored names only have them in the Backend, which sends them back after linking the account.
+func (s *authGRPCServer) BackfillProfile(ctx context.Context, req *authpb.BackfillProfileRequest) (*authpb.BackfillProfileResponse, error) {
+	if req.GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "id must be positive")
+	}
+	if err := validateNames(req.GetFirstName(), req.GetLastName()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	user, err := backfillProfile(int(req.GetId()), req.GetFirstName(), req.GetLastName())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		log.Printf("Failed to backfill profile of user %d: %v", req.GetId(), err)
+		return nil, status.Error(codes.Internal, "failed to backfill profile")
+	}
+
+	return &authpb.BackfillProfileResponse{User: user.proto()}, nil
+}
+
+// proto converts the user to its gRPC representation
+func (u *User) proto() *authpb.User {
+	return &authpb.User{
+		Id:            int64(u.ID),
+		Email:         u.Email,
+		Role:          u.Role,
+		EmailVerified: u.EmailVerified,
+		FirstName:     u.FirstName,
+		LastName:      u.LastName,
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// keyRetention is how long a retired key stays published in the JWKS so that tokens
+// signed with it shortly before rotation can still be verified. It covers the
+// longest-lived signed token, the email verification link.
+const keyRetention = emailVerificationTTL + time.Hour
+
+// keyReloadInterval limits how often an unknown kid triggers a reload from the database
+const keyReloadInterval = 10 * time.Second
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// signingKey is an Ed25519 key pair used to sign tokens
+type signingKey struct {
+	ID         string
+	PrivateKey ed25519.PrivateKey
+	CreatedAt  time.Time
+	RetiredAt  *time.Time
+}
+
+// JWK represents a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+// JWKS represents a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// keyRing holds the active signing key and the keys that are still published for verification
+type keyRing struct {
+	mu         sync.RWMutex
+	active     *signingKey
+	keys       map[string]*signingKey
+	loadedAt   time.Time
+	rotateEach time.Duration
+}
+
+var keys = &keyRing{keys: make(map[string]*signingKey)}
+
+// initKeys loads the signing keys, rotating if there is no fresh active key,
+// and keeps them up to date in the background
+func initKeys(rotateEach time.Duration) error {
+	keys.rotateEach = rotateEach
+
+	if err := keys.ensureFresh(); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := keys.ensureFresh(); err != nil {
+				log.Printf("Failed to refresh signing keys: %v", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// ensureFresh reloads keys from the database and rotates the active key when it is too old
+func (k *keyRing) ensureFresh() error {
+	if err := k.load(); err != nil {
+		return err
+	}
+
+	k.mu.RLock()
+	needsRotation := k.active == nil || time.Since(k.active.CreatedAt) > k.rotateEach
+	k.mu.RUnlock()
+
+	if needsRotation {
+		return k.rotate()
+	}
+
+	return nil
+}
+
+// load reads the active and recently retired keys from the database
+func (k *keyRing) load() error {
+	rows, err := db.Query(`
+		SELECT kid, private_key, created_at, retired_at
+		FROM signing_keys
+		WHERE retired_at IS NULL OR retired_at > ?
+	`, time.Now().Add(-keyRetention))
+	if err != nil {
+		return fmt.Errorf("failed to query signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	loaded := make(map[string]*signingKey)
+	var active *signingKey
+
+	for rows.Next() {
+		var key signingKey
+		var privatePEM string
+		var retiredAt sql.NullTime
+
+		if err := rows.Scan(&key.ID, &privatePEM, &key.CreatedAt, &retiredAt); err != nil {
+			return fmt.Errorf("failed to scan signing key: %w", err)
+		}
+
+		key.PrivateKey, err = decodePrivateKey(privatePEM)
+		if err != nil {
+			return fmt.Errorf("failed to decode signing key %s: %w", key.ID, err)
+		}
+
+		if retiredAt.Valid {
+			key.RetiredAt = &retiredAt.Time
+		} else if active == nil || key.CreatedAt.After(active.CreatedAt) {
+			active = &key
+		}
+
+		loaded[key.ID] = &key
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read signing keys: %w", err)
+	}
+
+	k.mu.Lock()
+	k.keys = loaded
+	k.active = active
+	k.loadedAt = time.Now()
+	k.mu.Unlock()
+
+	return nil
+}
+
+// rotate generates a new active key and retires the previous ones
+func (k *keyRing) rotate() error {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	privatePEM, err := encodePrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+
+	kid := keyID(privateKey.Public().(ed25519.PublicKey))
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE signing_keys SET retired_at = NOW() WHERE retired_at IS NULL"); err != nil {
+		return fmt.Errorf("failed to retire signing keys: %w", err)
+	}
+
+	if _, err := tx.Exec("INSERT INTO signing_keys (kid, private_key) VALUES (?, ?)", kid, privatePEM); err != nil {
+		return fmt.Errorf("failed to store signing key: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit signing key: %w", err)
+	}
+
+	log.Printf("Signing key rotated, new kid %s", kid)
+
+	return k.load()
+}
+
+// current returns the key new tokens are signed with
+func (k *keyRing) current() (*signingKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if k.active == nil {
+		return nil, ErrUnknownKey
+	}
+
+	return k.active, nil
+}
+
+// publicKey returns the verification key for kid, reloading once if another
+// instance may have rotated keys in the meantime
+func (k *keyRing) publicKey(kid string) (ed25519.PublicKey, error) {
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	stale := time.Since(k.loadedAt) > keyReloadInterval
+	k.mu.RUnlock()
+
+	if !ok && stale {
+		if err := k.load(); err != nil {
+			return nil, err
+		}
+
+		k.mu.RLock()
+		key, ok = k.keys[kid]
+		k.mu.RUnlock()
+	}
+
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return key.PrivateKey.Public().(ed25519.PublicKey), nil
+}
+
+// jwks returns the public part of every published key, newest first
+func (k *keyRing) jwks() JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(k.keys))}
+	published := make([]*signingKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		published = append(published, key)
+	}
+	sort.Slice(published, func(i, j int) bool {
+		return published[i].CreatedAt.After(published[j].CreatedAt)
+	})
+
+	for _, key := range published {
+		set.Keys = append(set.Keys, JWK{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(key.PrivateKey.Public().(ed25519.PublicKey)),
+			KeyID:     key.ID,
+			Algorithm: "EdDSA",
+			Use:       "sig",
+		})
+	}
+
+	return set
+}
+
+// handleJWKS publishes the verification keys
+func handleJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(keys.jwks())
+}
+
+// handleRotateKeys forces a signing key rotation
+func handleRotateKeys(w http.ResponseWriter, r *http.Request) {
+	if err := keys.rotate(); err != nil {
+		log.Printf("Failed to rotate signing key: %v", err)
+		http.Error(w, "Failed to rotate signing key", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// keyID derives the kid from the RFC 7638 thumbprint of the public key
+func keyID(publicKey ed25519.PublicKey) string {
+	thumbprintInput := fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`,
+		base64.RawURLEncoding.EncodeToString(publicKey))
+	hash := sha256.Sum256([]byte(thumbprintInput))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func encodePrivateKey(key ed25519.PrivateKey) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode signing key: %w", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+func decodePrivateKey(privatePEM string) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, errors.New("invalid PEM block")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an Ed25519 key")
+	}
+
+	return privateKey, nil
+}
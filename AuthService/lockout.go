@@ -0,0 +1,267 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Reasons recorded for login attempts
+const (
+	loginOK              = "ok"
+	loginUnknownUser     = "unknown_user"
+	loginInvalidPassword = "invalid_password"
+	loginThrottled       = "throttled"
+	// loginMFARequired means the password was correct and the login waits for the second factor
+	loginMFARequired = "mfa_required"
+	loginInvalidMFA  = "invalid_mfa"
+)
+
+// loginLimit describes when failed attempts start slowing down and locking out a key
+type loginLimit struct {
+	// delayAfter is the number of failures allowed without waiting
+	delayAfter int
+	// lockAfter is the number of failures that locks the key for lockoutDuration
+	lockAfter int
+	// resetOnSuccess discards failures before the last successful login for the key
+	resetOnSuccess bool
+}
+
+var (
+	// accountLimit applies to attempts for a single email
+	accountLimit = loginLimit{delayAfter: 3, lockAfter: 10, resetOnSuccess: true}
+	// ipLimit applies to attempts from a single address across all emails. It is purely
+	// time-based, otherwise logging into one's own account would reset it between guesses.
+	ipLimit = loginLimit{delayAfter: 20, lockAfter: 100}
+)
+
+const (
+	// failureWindow is how far back failed attempts are counted
+	failureWindow   = 15 * time.Minute
+	lockoutDuration = 15 * time.Minute
+	maxLoginDelay   = time.Minute
+
+	loginAttemptRetention = 90 * 24 * time.Hour
+	maxLoginAttemptsPage  = 500
+)
+
+var (
+	trustProxyHeaders = getEnvOrDefault("TRUST_PROXY_HEADERS", "false") == "true"
+
+	// dummyPasswordHash is compared against when the email is unknown, so that
+	// the response time does not reveal whether the account exists
+	dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcryptCost)
+)
+
+// LoginAttempt represents an audit record of a login attempt
+type LoginAttempt struct {
+	ID        int64  `json:"id"`
+	Email     string `json:"email"`
+	UserID    *int   `json:"userId,omitempty"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"userAgent"`
+	Success   bool   `json:"success"`
+	Reason    string `json:"reason"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// loginRetryAfter returns how long the client must wait before the next attempt
+// for the email or from the address. Zero means the attempt may proceed.
+func loginRetryAfter(email, ip string) (time.Duration, error) {
+	accountWait, err := retryAfter("email", email, accountLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	ipWait, err := retryAfter("ip", ip, ipLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(accountWait, ipWait), nil
+}
+
+// retryAfter counts recent failures for the key within failureWindow, and with
+// resetOnSuccess only those since its last successful login. Failures above
+// delayAfter double the wait each time, and reaching lockAfter locks the key
+// for lockoutDuration.
+func retryAfter(column, value string, limit loginLimit) (time.Duration, error) {
+	since := time.Now().Add(-failureWindow)
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*), MAX(created_at)
+		FROM login_attempts
+		WHERE %[1]s = ? AND success = FALSE AND reason NOT IN (?, ?) AND created_at > ?`, column)
+	args := []any{value, loginThrottled, loginMFARequired, since}
+	if limit.resetOnSuccess {
+		query += fmt.Sprintf(`
+		  AND created_at > COALESCE(
+			(SELECT MAX(created_at) FROM login_attempts WHERE %[1]s = ? AND success = TRUE), '1970-01-02')`, column)
+		args = append(args, value)
+	}
+
+	var failures int
+	var lastFailure sql.NullTime
+	err := db.QueryRow(query, args...).Scan(&failures, &lastFailure)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count login failures: %w", err)
+	}
+
+	if failures <= limit.delayAfter || !lastFailure.Valid {
+		return 0, nil
+	}
+
+	var wait time.Duration
+	if failures >= limit.lockAfter {
+		wait = lockoutDuration
+	} else {
+		exp := float64(failures - limit.delayAfter - 1)
+		wait = min(time.Duration(math.Pow(2, exp))*time.Second, maxLoginDelay)
+	}
+
+	return max(time.Until(lastFailure.Time.Add(wait)), 0), nil
+}
+
+// retryAfterHeader formats the wait in whole seconds for the Retry-After header
+func retryAfterHeader(wait time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(wait.Seconds())))
+}
+
+// checkDummyPassword spends the same time as a real password check
+func checkDummyPassword(password string) {
+	bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+}
+
+// recordLoginAttempt stores an audit record of the attempt
+func recordLoginAttempt(r *http.Request, email string, userID *int, reason string) {
+	userAgent := r.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO login_attempts (email, user_id, ip, user_agent, success, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, email, userID, clientIP(r), userAgent, reason == loginOK, reason, time.Now())
+	if err != nil {
+		log.Printf("Failed to record login attempt: %v", err)
+	}
+}
+
+// clientIP returns the address of the client. Proxy headers are only trusted
+// when TRUST_PROXY_HEADERS is set, otherwise they would let clients pick their own address.
+func clientIP(r *http.Request) string {
+	if trustProxyHeaders {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(first)
+		}
+		if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+			return realIP
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// handleListLoginAttempts returns failed login attempts, newest first. Supports filtering
+// by email, ip, userId and since (RFC 3339); all=true includes successful logins.
+func handleListLoginAttempts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	conditions := []string{"1 = 1"}
+	var args []any
+
+	if query.Get("all") != "true" {
+		conditions = append(conditions, "success = FALSE")
+	}
+	if email := query.Get("email"); email != "" {
+		conditions = append(conditions, "email = ?")
+		args = append(args, email)
+	}
+	if ip := query.Get("ip"); ip != "" {
+		conditions = append(conditions, "ip = ?")
+		args = append(args, ip)
+	}
+	if userID := query.Get("userId"); userID != "" {
+		id, err := strconv.Atoi(userID)
+		if err != nil {
+			http.Error(w, "Invalid userId", http.StatusBadRequest)
+			return
+		}
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, id)
+	}
+	if since := query.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			http.Error(w, "Invalid since, expected RFC 3339", http.StatusBadRequest)
+			return
+		}
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, t)
+	}
+
+	limit := 100
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxLoginAttemptsPage)
+	}
+	args = append(args, limit)
+
+	rows, err := db.Query(`
+		SELECT id, email, user_id, ip, user_agent, success, reason, created_at
+		FROM login_attempts
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY id DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		log.Printf("Failed to query login attempts: %v", err)
+		http.Error(w, "Failed to load login attempts", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	attempts := []LoginAttempt{}
+	for rows.Next() {
+		var a LoginAttempt
+		var createdAt time.Time
+		if err := rows.Scan(&a.ID, &a.Email, &a.UserID, &a.IP, &a.UserAgent, &a.Success, &a.Reason, &createdAt); err != nil {
+			http.Error(w, "Failed to load login attempts", http.StatusInternalServerError)
+			return
+		}
+		a.CreatedAt = createdAt.Format(time.RFC3339)
+		attempts = append(attempts, a)
+	}
+
+	writeJSON(w, http.StatusOK, attempts)
+}
+
+// cleanupLoginAttempts periodically removes audit records past their retention
+func cleanupLoginAttempts() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := db.Exec("DELETE FROM login_attempts WHERE created_at < ?", time.Now().Add(-loginAttemptRetention)); err != nil {
+			log.Printf("Failed to clean up login attempts: %v", err)
+		}
+	}
+}
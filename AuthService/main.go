@@ -1,38 +1,63 @@
 package main
 
 import (
-	// "context"
-	"crypto/sha256"
 	"database/sql"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/mail"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/go-chi/cors"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	_ "github.com/go-sql-driver/mysql"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/go-sql-driver/mysql"
+	"github.com/golang-migrate/migrate/v4"
+	migratemysql "github.com/golang-migrate/migrate/v4/database/mysql"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
-	// "github.com/segmentio/kafka-go"
 )
 
 // User represents a user in the database
 type User struct {
-	ID       int    `json:"id"`
-	Email    string `json:"email"`
-	Password string `json:"password"` // Stored as hash
+	ID            int    `json:"id"`
+	Email         string `json:"email"`
+	Password      string `json:"password"` // Stored as hash
+	Role          string `json:"role"`
+	EmailVerified bool   `json:"emailVerified"`
+	FirstName     string `json:"firstName"`
+	LastName      string `json:"lastName"`
 }
 
 type UserRes struct {
-	ID    int    `json:"id"`
-	Email string `json:"email"` // Stored as hash
+	ID            int    `json:"id"`
+	Email         string `json:"email"` // Stored as hash
+	Role          string `json:"role"`
+	EmailVerified bool   `json:"emailVerified"`
+	FirstName     string `json:"firstName"`
+	LastName      string `json:"lastName"`
+}
+
+// response returns the public representation of the user
+func (u *User) response() UserRes {
+	return UserRes{
+		ID:            u.ID,
+		Email:         u.Email,
+		Role:          u.Role,
+		EmailVerified: u.EmailVerified,
+		FirstName:     u.FirstName,
+		LastName:      u.LastName,
+	}
+}
+
+// eventData returns the fields propagated to the Backend
+func (u *User) eventData() UserEventData {
+	return UserEventData{ID: u.ID, Email: u.Email, FirstName: u.FirstName, LastName: u.LastName}
 }
 
 // LoginRequest represents the login request payload
@@ -41,26 +66,47 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
-// UserCreatedEvent represents the Kafka event for new user creation
-type UserCreatedEvent struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+// RegisterRequest represents the sign-up request payload
+type RegisterRequest struct {
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
 }
 
+// minPasswordLength is the minimal accepted password length on sign-up
+const minPasswordLength = 8
+
+// maxPasswordLength is the longest password in bytes that bcrypt accepts
+const maxPasswordLength = 72
+
+// ErrEmailTaken is returned when a user with the same email already exists
+var ErrEmailTaken = errors.New("email already registered")
+
+// Errors returned by authenticateUser. Both are reported to the client the same way.
+var (
+	ErrUnknownUser     = errors.New("user not found")
+	ErrInvalidPassword = errors.New("invalid password")
+)
+
 // JWTResponse represents the JWT token response
 type JWTResponse struct {
-	Token   string  `json:"token"`
-	Expires string  `json:"expires"`
-	User    UserRes `json:"user"`
+	Token          string  `json:"token"`
+	Expires        string  `json:"expires"`
+	RefreshToken   string  `json:"refreshToken"`
+	RefreshExpires string  `json:"refreshExpires"`
+	User           UserRes `json:"user"`
 }
 
 // Global variables
 var (
-	db          *sql.DB
-	jwtSecret   = []byte(getEnvOrDefault("JWT_SECRET", "your-secret-key"))
-	kafkaTopic  = getEnvOrDefault("KAFKA_TOPIC", "user-created")
-	kafkaBroker = getEnvOrDefault("KAFKA_BROKER", "localhost:9092")
-	dbConnStr   = getEnvOrDefault("DB_CONN_STR", "root:3392Mm!!@tcp(127.0.0.1:3306)/AuthDB?multiStatements=true&parseTime=true")
+	db                  *sql.DB
+	keyRotationInterval = getDurationOrDefault("JWT_KEY_ROTATION_INTERVAL", 30*24*time.Hour)
+	emailSenderAddr     = getEnvOrDefault("EMAIL_SENDER_ADDR", "localhost:2282")
+	frontendURL         = getEnvOrDefault("FRONTEND_URL", "http://localhost:5173")
+	dbConnStr           = getEnvOrDefault("DB_CONN_STR", "root:3392Mm!!@tcp(127.0.0.1:3306)/AuthDB?multiStatements=true&parseTime=true")
+	kafkaBrokers        = strings.Split(getEnvOrDefault("KAFKA_BROKERS", "localhost:9092"), ",")
+	usersTopic          = getEnvOrDefault("KAFKA_USERS_TOPIC", "users")
 )
 
 func main() {
@@ -72,23 +118,31 @@ func main() {
 	}
 	defer db.Close()
 
-	// Create users table if it doesn't exist
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS users (
-			id SERIAL PRIMARY KEY,
-			email VARCHAR(255) UNIQUE NOT NULL,
-			password_hash VARCHAR(255) NOT NULL
-		)
-	`)
-	if err != nil {
-		log.Fatalf("Failed to create users table: %v", err)
+	// Apply database migrations
+	if err := runMigrations(db); err != nil {
+		log.Fatalf("Failed to apply migrations: %v", err)
+	}
+
+	// Promote the configured first admin, who can then assign roles to others
+	if err := bootstrapAdmin(os.Getenv("BOOTSTRAP_ADMIN_EMAIL")); err != nil {
+		log.Fatalf("Failed to bootstrap admin: %v", err)
 	}
 
-	createUser("ser228@mail.ru", "12341234")
-	createUser("alex555@mail.ru", "12341234")
+	// Load signing keys, generating the first one if needed
+	if err := initKeys(keyRotationInterval); err != nil {
+		log.Fatalf("Failed to initialize signing keys: %v", err)
+	}
+
+	// Connect to EmailSenderService for account emails
+	if err := initEmailClient(emailSenderAddr); err != nil {
+		log.Fatalf("Failed to initialize email client: %v", err)
+	}
 
-	// Start Kafka consumer in a goroutine
-	// go consumeKafkaMessages()
+	go cleanupUsedTokens()
+	go cleanupLoginAttempts()
+
+	// Publish account changes so the Backend keeps its users in sync
+	go runOutboxRelay(kafkaBrokers, usersTopic)
 
 	// Set up HTTP server with Chi router
 	r := chi.NewRouter()
@@ -107,9 +161,55 @@ func main() {
 	r.Use(middleware.Recoverer)
 
 	// Define routes
+	r.Post("/register", handleRegister)
 	r.Post("/login", handleLogin)
+	r.Post("/login/mfa", handleLoginMFA)
+	r.Post("/mfa/totp/enroll", handleEnrollTOTP)
+	r.Post("/mfa/totp/confirm", handleConfirmTOTP)
+	r.Post("/refresh", handleRefresh)
+	r.Post("/logout", handleLogout)
+	r.Post("/introspect", handleIntrospect)
+	r.Get("/.well-known/jwks.json", handleJWKS)
+	r.Post("/password/forgot", handleForgotPassword)
+	r.Post("/password/reset", handleResetPassword)
+	r.Post("/email/verify", handleVerifyEmail)
+
+	r.Group(func(r chi.Router) {
+		r.Use(requireAuth)
+
+		r.Post("/logout/all", handleLogoutAll)
+		r.Post("/email/verify/resend", handleResendVerification)
+		r.Post("/mfa/totp/disable", handleDisableTOTP)
+		r.Get("/me", handleGetMe)
+		r.Put("/me", handleUpdateProfile)
+		r.Delete("/me", handleDeleteAccount)
+
+		r.Group(func(r chi.Router) {
+			r.Use(requireRole(RoleAdmin))
+
+			r.Put("/admin/users/{id}/role", handleSetUserRole)
+			r.Delete("/admin/users/{id}", handleDeleteUser)
+			r.Post("/admin/keys/rotate", handleRotateKeys)
+			r.Get("/admin/mfa-policy", handleGetMFAPolicy)
+			r.Put("/admin/mfa-policy", handleSetMFAPolicy)
+			r.Get("/admin/login-attempts", handleListLoginAttempts)
+		})
+	})
 
 	// Start the server
+	// Serve the gRPC API for other services alongside the HTTP API. It exposes account data,
+	// so it listens on an internal address and accepts only callers holding the service token.
+	grpcAddr := getEnvOrDefault("GRPC_ADDR", "127.0.0.1:2283")
+	grpcToken := os.Getenv("GRPC_SERVICE_TOKEN")
+	if grpcToken == "" {
+		log.Fatalf("GRPC_SERVICE_TOKEN must be set")
+	}
+	go func() {
+		if err := serveGRPC(grpcAddr, grpcToken); err != nil {
+			log.Fatalf("Failed to start gRPC server: %v", err)
+		}
+	}()
+
 	port := getEnvOrDefault("PORT", "8080")
 	log.Printf("Server starting on port %s", port)
 	if err := http.ListenAndServe(":"+port, r); err != nil {
@@ -117,6 +217,53 @@ func main() {
 	}
 }
 
+// handleRegister creates a new user account and logs it in
+func handleRegister(w http.ResponseWriter, r *http.Request) {
+	var req RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := mail.ParseAddress(req.Email); err != nil {
+		http.Error(w, "Некорректный email", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Password) < minPasswordLength {
+		http.Error(w, fmt.Sprintf("Пароль должен содержать не менее %d символов", minPasswordLength), http.StatusBadRequest)
+		return
+	}
+	if len(req.Password) > maxPasswordLength {
+		http.Error(w, fmt.Sprintf("Пароль должен занимать не более %d байт", maxPasswordLength), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateNames(req.FirstName, req.LastName); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := createUser(req)
+	if err != nil {
+		if errors.Is(err, ErrEmailTaken) {
+			http.Error(w, "Пользователь с такой почтой уже зарегистрирован", http.StatusConflict)
+			return
+		}
+		log.Printf("Failed to create user: %v", err)
+		http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		return
+	}
+
+	go func() {
+		if err := sendVerificationEmail(user.ID, user.Email); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+	}()
+
+	issueTokens(w, user, http.StatusCreated)
+}
+
 // handleLogin handles user authentication and JWT token generation
 func handleLogin(w http.ResponseWriter, r *http.Request) {
 	var loginReq LoginRequest
@@ -125,131 +272,182 @@ func handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Slow down and lock out repeated failures for the account and the client address
+	wait, err := loginRetryAfter(loginReq.Email, clientIP(r))
+	if err != nil {
+		log.Printf("Failed to check login attempts: %v", err)
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		recordLoginAttempt(r, loginReq.Email, nil, loginThrottled)
+		w.Header().Set("Retry-After", retryAfterHeader(wait))
+		http.Error(w, "Слишком много попыток входа, повторите позже", http.StatusTooManyRequests)
+		return
+	}
+
 	// Check if user exists and password is correct
 	user, err := authenticateUser(loginReq.Email, loginReq.Password)
 	if err != nil {
+		switch {
+		case errors.Is(err, ErrUnknownUser):
+			recordLoginAttempt(r, loginReq.Email, nil, loginUnknownUser)
+		case errors.Is(err, ErrInvalidPassword):
+			recordLoginAttempt(r, loginReq.Email, &user.ID, loginInvalidPassword)
+		default:
+			log.Printf("Failed to authenticate user: %v", err)
+			http.Error(w, "Failed to log in", http.StatusInternalServerError)
+			return
+		}
 		http.Error(w, "Неверный логин или пароль", http.StatusUnauthorized)
 		return
 	}
 
-	// Generate JWT token
-	token, expiresAt, err := generateJWT(user)
+	// Users with 2FA, or whose role requires it, get a short-lived mfa token instead of a session.
+	// The login only counts as successful once the second factor is verified.
+	challenge, err := loginSecondFactor(user)
 	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		log.Printf("Failed to check second factor of user %d: %v", user.ID, err)
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
 		return
 	}
+	if challenge != nil {
+		recordLoginAttempt(r, loginReq.Email, &user.ID, loginMFARequired)
+		writeJSON(w, http.StatusOK, challenge)
+		return
+	}
+	recordLoginAttempt(r, loginReq.Email, &user.ID, loginOK)
 
-	// Return JWT token
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(JWTResponse{
-		Token:   token,
-		Expires: expiresAt.Format(time.RFC3339),
-		User:    UserRes{ID: user.ID, Email: user.Email},
-	})
+	// Start a session and return access and refresh tokens
+	issueTokens(w, user, http.StatusOK)
 }
 
-// authenticateUser checks if the email/password combination is valid
+// authenticateUser checks if the email/password combination is valid. Unknown emails
+// take as long to check as wrong passwords. With ErrInvalidPassword the user is
+// returned as well so that the failure can be attributed to the account.
 func authenticateUser(email, password string) (*User, error) {
 	var user User
 	var passwordHash string
 
 	// Query the database for the user
-	err := db.QueryRow("SELECT id, email, password_hash FROM users WHERE email = ?", email).Scan(
-		&user.ID, &user.Email, &passwordHash,
+	err := db.QueryRow("SELECT id, email, password_hash, role, email_verified, first_name, last_name FROM users WHERE email = ?", email).Scan(
+		&user.ID, &user.Email, &passwordHash, &user.Role, &user.EmailVerified, &user.FirstName, &user.LastName,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("user not found")
+			checkDummyPassword(password)
+			return nil, ErrUnknownUser
 		}
 		return nil, err
 	}
 
 	// Verify password
-	if hashPassword(password) != passwordHash {
-		return nil, errors.New("invalid password")
+	ok, needsRehash := verifyPassword(passwordHash, password)
+	if !ok {
+		return &user, ErrInvalidPassword
+	}
+
+	// Transparently upgrade legacy hashes now that the plaintext password is known
+	if needsRehash {
+		if err := updatePasswordHash(user.ID, password); err != nil {
+			log.Printf("Failed to rehash password for user %d: %v", user.ID, err)
+		}
 	}
 
 	return &user, nil
 }
 
-// generateJWT creates a new JWT token for the authenticated user
-func generateJWT(user *User) (string, time.Time, error) {
-	expiresAt := time.Now().Add(24 * time.Hour)
+// runMigrations applies pending migrations from the migrations directory
+func runMigrations(db *sql.DB) error {
+	driver, err := migratemysql.WithInstance(db, &migratemysql.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to create MySQL driver: %w", err)
+	}
+
+	m, err := migrate.NewWithDatabaseInstance("file://./migrations", "mysql", driver)
+	if err != nil {
+		return fmt.Errorf("failed to initialize migrations: %w", err)
+	}
 
-	claims := jwt.MapClaims{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"exp":     expiresAt.Unix(),
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
+	return nil
+}
+
+// getUserByID loads a user by id
+func getUserByID(id int) (*User, error) {
+	var user User
+
+	err := db.QueryRow("SELECT id, email, role, email_verified, first_name, last_name FROM users WHERE id = ?", id).Scan(
+		&user.ID, &user.Email, &user.Role, &user.EmailVerified, &user.FirstName, &user.LastName,
+	)
 	if err != nil {
-		return "", time.Time{}, err
+		return nil, err
 	}
 
-	return tokenString, expiresAt, nil
+	return &user, nil
 }
 
-// consumeKafkaMessages listens for new user creation events from Kafka
-// func consumeKafkaMessages() {
-// 	// Configure Kafka reader
-// 	reader := kafka.NewReader(kafka.ReaderConfig{
-// 		Brokers:  []string{kafkaBroker},
-// 		Topic:    kafkaTopic,
-// 		GroupID:  "auth-service",
-// 		MinBytes: 10e3,
-// 		MaxBytes: 10e6,
-// 	})
-// 	defer reader.Close()
-
-// 	log.Printf("Kafka consumer started on topic: %s", kafkaTopic)
-
-// 	for {
-// 		msg, err := reader.ReadMessage(context.Background())
-// 		if err != nil {
-// 			log.Printf("Error reading Kafka message: %v", err)
-// 			continue
-// 		}
-
-// 		// Process the message
-// 		var event UserCreatedEvent
-// 		if err := json.Unmarshal(msg.Value, &event); err != nil {
-// 			log.Printf("Error unmarshaling Kafka message: %v", err)
-// 			continue
-// 		}
-
-// 		log.Printf("Received user creation event for: %s", event.Email)
-
-// 		// Create user in database
-// 		if err := createUser(event.Email, event.Password); err != nil {
-// 			log.Printf("Error creating user: %v", err)
-// 			continue
-// 		}
-
-// 		log.Printf("User created successfully: %s", event.Email)
-// 	}
-// }
-
-// createUser adds a new user to the database with a hashed password
-func createUser(email, password string) error {
+// createUser adds a new user to the database with a hashed password and enqueues a user.created event
+func createUser(req RegisterRequest) (*User, error) {
 	// Hash the password
-	passwordHash := hashPassword(password)
+	passwordHash, err := hashPassword(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
 	// Insert the user into the database
-	_, err := db.Exec("INSERT INTO users (email, password_hash) VALUES (?, ?)", email, passwordHash)
+	res, err := tx.Exec(
+		"INSERT INTO users (email, password_hash, first_name, last_name) VALUES (?, ?, ?, ?)",
+		req.Email, passwordHash, req.FirstName, req.LastName,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return nil, ErrEmailTaken
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	return nil
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user id: %w", err)
+	}
+
+	user := &User{ID: int(id), Email: req.Email, Role: RoleAttendee, FirstName: req.FirstName, LastName: req.LastName}
+
+	// The Backend creates its copy of the user with the same id from this event
+	if err := enqueueUserEvent(tx, eventUserCreated, user.eventData()); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit user: %w", err)
+	}
+
+	return user, nil
 }
 
-// hashPassword creates a SHA-256 hash of the password
-// Note: In a production environment, use a more secure hashing algorithm like bcrypt
-func hashPassword(password string) string {
-	hash := sha256.Sum256([]byte(password))
-	return hex.EncodeToString(hash[:])
+// updatePasswordHash replaces the stored password hash of the user
+func updatePasswordHash(userID int, password string) error {
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if _, err := db.Exec("UPDATE users SET password_hash = ? WHERE id = ?", passwordHash, userID); err != nil {
+		return fmt.Errorf("failed to update password hash: %w", err)
+	}
+
+	return nil
 }
 
 // getEnvOrDefault returns the value of an environment variable or a default value
@@ -259,3 +457,14 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getDurationOrDefault returns the duration from an environment variable or a default value
+func getDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+		log.Printf("Invalid duration in %s, using default %s", key, defaultValue)
+	}
+	return defaultValue
+}
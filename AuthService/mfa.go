@@ -0,0 +1,618 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Purposes of the limited tokens issued between the password check and the second factor
+const (
+	purposeMFA           = "mfa"
+	purposeMFAEnrollment = "mfa_enrollment"
+)
+
+const (
+	totpIssuer = "EventOrganization"
+	totpPeriod = 30
+	totpDigits = 6
+	// totpSkew is the number of periods accepted before and after the current one
+	totpSkew = 1
+
+	mfaTokenTTL = 5 * time.Minute
+	// maxMFAAttempts is how many wrong codes a single mfa token tolerates
+	maxMFAAttempts = 5
+
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrMFANotEnabled   = errors.New("mfa not enabled")
+	ErrInvalidMFACode  = errors.New("invalid mfa code")
+	ErrMFAAlreadyExist = errors.New("mfa already enabled")
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFAChallengeResponse is returned by /login instead of tokens when a second factor is needed
+type MFAChallengeResponse struct {
+	MFARequired           bool   `json:"mfaRequired,omitempty"`
+	MFAEnrollmentRequired bool   `json:"mfaEnrollmentRequired,omitempty"`
+	MFAToken              string `json:"mfaToken"`
+	Expires               string `json:"expires"`
+}
+
+// MFALoginRequest represents the second login step payload
+type MFALoginRequest struct {
+	MFAToken     string `json:"mfaToken"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// MFACodeRequest represents a payload carrying a TOTP or recovery code
+type MFACodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// EnrollResponse carries the new TOTP secret
+type EnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+}
+
+// ConfirmResponse carries the recovery codes and, when enrollment was forced at login, the session tokens
+type ConfirmResponse struct {
+	RecoveryCodes []string     `json:"recoveryCodes"`
+	Session       *JWTResponse `json:"session,omitempty"`
+}
+
+// MFAPolicy represents whether a role must use two-factor authentication
+type MFAPolicy struct {
+	Role     string `json:"role"`
+	Required bool   `json:"required"`
+}
+
+// mfaAttempts counts wrong codes per mfa token id
+var mfaAttempts = struct {
+	sync.Mutex
+	count map[string]int
+}{count: make(map[string]int)}
+
+// loginSecondFactor decides whether a successful password check needs a second factor.
+// It returns nil if tokens may be issued right away.
+func loginSecondFactor(user *User) (*MFAChallengeResponse, error) {
+	enabled, err := isMFAEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	purpose := ""
+	switch {
+	case enabled:
+		purpose = purposeMFA
+	default:
+		required, err := isMFARequired(user.Role)
+		if err != nil {
+			return nil, err
+		}
+		if required {
+			purpose = purposeMFAEnrollment
+		}
+	}
+
+	if purpose == "" {
+		return nil, nil
+	}
+
+	token, err := generateActionToken(user.ID, user.Email, purpose, "", mfaTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAChallengeResponse{
+		MFARequired:           purpose == purposeMFA,
+		MFAEnrollmentRequired: purpose == purposeMFAEnrollment,
+		MFAToken:              token,
+		Expires:               time.Now().Add(mfaTokenTTL).Format(time.RFC3339),
+	}, nil
+}
+
+// handleLoginMFA completes a login by verifying the second factor
+func handleLoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req MFALoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	claims, userID, err := parseActionToken(req.MFAToken, purposeMFA)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !allowMFAAttempt(claims) {
+		http.Error(w, "Слишком много попыток, войдите заново", http.StatusTooManyRequests)
+		return
+	}
+
+	if err := verifySecondFactor(userID, req.Code, req.RecoveryCode); err != nil {
+		if !errors.Is(err, ErrInvalidMFACode) {
+			log.Printf("Failed to verify second factor of user %d: %v", userID, err)
+		}
+		recordMFAFailure(claims)
+		recordLoginAttempt(r, claims.Email, &userID, loginInvalidMFA)
+		http.Error(w, "Неверный код", http.StatusUnauthorized)
+		return
+	}
+
+	if err := consumeMFAToken(claims); err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := getUserByID(userID)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	recordLoginAttempt(r, claims.Email, &userID, loginOK)
+
+	issueTokens(w, user, http.StatusOK)
+}
+
+// handleEnrollTOTP generates a new TOTP secret for the user. It accepts an access token
+// or the enrollment token issued by /login when the user's role requires 2FA.
+func handleEnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, email, _, ok := enrollmentUser(w, r)
+	if !ok {
+		return
+	}
+
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		http.Error(w, "Failed to generate secret", http.StatusInternalServerError)
+		return
+	}
+	encoded := base32NoPadding.EncodeToString(secret)
+
+	// A pending secret may be replaced, an enabled one must be disabled first
+	res, err := db.Exec(`
+		INSERT INTO mfa_totp (user_id, secret) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE secret = IF(enabled_at IS NULL, VALUES(secret), secret)
+	`, userID, encoded)
+	if err != nil {
+		log.Printf("Failed to store TOTP secret of user %d: %v", userID, err)
+		http.Error(w, "Failed to enroll", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, "Двухфакторная аутентификация уже включена", http.StatusConflict)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, EnrollResponse{
+		Secret:          encoded,
+		ProvisioningURI: provisioningURI(email, encoded),
+	})
+}
+
+// handleConfirmTOTP enables TOTP once the user proves the authenticator works and returns recovery codes
+func handleConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, email, pending, ok := enrollmentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if pending != nil && !allowMFAAttempt(pending) {
+		http.Error(w, "Слишком много попыток, войдите заново", http.StatusTooManyRequests)
+		return
+	}
+
+	codes, err := confirmTOTP(userID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidMFACode):
+			if pending != nil {
+				recordMFAFailure(pending)
+				recordLoginAttempt(r, email, &userID, loginInvalidMFA)
+			}
+			http.Error(w, "Неверный код", http.StatusBadRequest)
+		case errors.Is(err, ErrMFAAlreadyExist):
+			http.Error(w, "Двухфакторная аутентификация уже включена", http.StatusConflict)
+		case errors.Is(err, ErrMFANotEnabled):
+			http.Error(w, "Сначала начните подключение", http.StatusBadRequest)
+		default:
+			log.Printf("Failed to confirm TOTP of user %d: %v", userID, err)
+			http.Error(w, "Failed to confirm", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	resp := ConfirmResponse{RecoveryCodes: codes}
+
+	// Enrollment forced at login finishes the login as well
+	if pending != nil {
+		if err := consumeMFAToken(pending); err == nil {
+			user, err := getUserByID(userID)
+			if err == nil {
+				resp.Session, err = startSession(user)
+			}
+			if err != nil {
+				log.Printf("Failed to start session for user %d: %v", userID, err)
+			} else {
+				recordLoginAttempt(r, email, &userID, loginOK)
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleDisableTOTP turns off two-factor authentication after checking a current code
+func handleDisableTOTP(w http.ResponseWriter, r *http.Request) {
+	claims, _ := claimsFromContext(r.Context())
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	required, err := isMFARequired(claims.Role)
+	if err != nil {
+		http.Error(w, "Failed to disable", http.StatusInternalServerError)
+		return
+	}
+	if required {
+		http.Error(w, "Двухфакторная аутентификация обязательна для вашей роли", http.StatusForbidden)
+		return
+	}
+
+	if err := verifySecondFactor(claims.UserID, req.Code, req.RecoveryCode); err != nil {
+		http.Error(w, "Неверный код", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, "Failed to disable", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM mfa_totp WHERE user_id = ?", claims.UserID); err != nil {
+		http.Error(w, "Failed to disable", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = ?", claims.UserID); err != nil {
+		http.Error(w, "Failed to disable", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to disable", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetMFAPolicy lists roles with their 2FA requirement
+func handleGetMFAPolicy(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.Query("SELECT role, required FROM mfa_policy ORDER BY role")
+	if err != nil {
+		http.Error(w, "Failed to load policy", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	policies := []MFAPolicy{}
+	for rows.Next() {
+		var p MFAPolicy
+		if err := rows.Scan(&p.Role, &p.Required); err != nil {
+			http.Error(w, "Failed to load policy", http.StatusInternalServerError)
+			return
+		}
+		policies = append(policies, p)
+	}
+
+	writeJSON(w, http.StatusOK, policies)
+}
+
+// handleSetMFAPolicy requires or stops requiring 2FA for a role
+func handleSetMFAPolicy(w http.ResponseWriter, r *http.Request) {
+	var req MFAPolicy
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if !validRoles[req.Role] {
+		http.Error(w, "Unknown role", http.StatusBadRequest)
+		return
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO mfa_policy (role, required) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE required = VALUES(required)
+	`, req.Role, req.Required)
+	if err != nil {
+		http.Error(w, "Failed to update policy", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// enrollmentUser authenticates enrollment requests by an access token or a pending enrollment token.
+// On failure the response is already written.
+func enrollmentUser(w http.ResponseWriter, r *http.Request) (userID int, email string, pending *ActionClaims, ok bool) {
+	tokenString, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, "", nil, false
+	}
+
+	if claims, err := parseAccessToken(tokenString); err == nil {
+		active, err := isSessionActive(claims.SessionID)
+		if err == nil && active {
+			return claims.UserID, claims.Email, nil, true
+		}
+	}
+
+	claims, userID, err := parseActionToken(tokenString, purposeMFAEnrollment)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, "", nil, false
+	}
+
+	return userID, claims.Email, claims, true
+}
+
+// isMFAEnabled reports whether the user has confirmed a TOTP authenticator
+func isMFAEnabled(userID int) (bool, error) {
+	var enabled bool
+	err := db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM mfa_totp WHERE user_id = ? AND enabled_at IS NOT NULL)", userID,
+	).Scan(&enabled)
+	return enabled, err
+}
+
+// isMFARequired reports whether an admin requires 2FA for the role
+func isMFARequired(role string) (bool, error) {
+	var required bool
+	err := db.QueryRow("SELECT required FROM mfa_policy WHERE role = ?", role).Scan(&required)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return required, err
+}
+
+// confirmTOTP enables a pending TOTP secret and replaces the recovery codes
+func confirmTOTP(userID int, code string) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var secret string
+	var enabledAt sql.NullTime
+	err = tx.QueryRow("SELECT secret, enabled_at FROM mfa_totp WHERE user_id = ? FOR UPDATE", userID).Scan(&secret, &enabledAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMFANotEnabled
+		}
+		return nil, fmt.Errorf("failed to load TOTP secret: %w", err)
+	}
+	if enabledAt.Valid {
+		return nil, ErrMFAAlreadyExist
+	}
+
+	step, ok := validateTOTP(secret, code, time.Now(), 0)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	if _, err := tx.Exec("UPDATE mfa_totp SET enabled_at = NOW(), last_used_step = ? WHERE user_id = ?", step, userID); err != nil {
+		return nil, fmt.Errorf("failed to enable TOTP: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		codes[i], err = newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := tx.Exec("INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES (?, ?)", userID, hashToken(codes[i])); err != nil {
+			return nil, fmt.Errorf("failed to store recovery code: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit TOTP: %w", err)
+	}
+
+	return codes, nil
+}
+
+// verifySecondFactor checks a TOTP code, or a recovery code when no TOTP code is given.
+// Used codes cannot be replayed.
+func verifySecondFactor(userID int, code, recoveryCode string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var secret string
+	var lastStep int64
+	err = tx.QueryRow(
+		"SELECT secret, last_used_step FROM mfa_totp WHERE user_id = ? AND enabled_at IS NOT NULL FOR UPDATE", userID,
+	).Scan(&secret, &lastStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidMFACode
+		}
+		return fmt.Errorf("failed to load TOTP secret: %w", err)
+	}
+
+	if code != "" {
+		step, ok := validateTOTP(secret, code, time.Now(), lastStep)
+		if !ok {
+			return ErrInvalidMFACode
+		}
+
+		if _, err := tx.Exec("UPDATE mfa_totp SET last_used_step = ? WHERE user_id = ?", step, userID); err != nil {
+			return fmt.Errorf("failed to store TOTP step: %w", err)
+		}
+	} else {
+		res, err := tx.Exec(`
+			UPDATE mfa_recovery_codes SET used_at = NOW()
+			WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+		`, userID, hashToken(normalizeRecoveryCode(recoveryCode)))
+		if err != nil {
+			return fmt.Errorf("failed to use recovery code: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrInvalidMFACode
+		}
+	}
+
+	return tx.Commit()
+}
+
+// consumeMFAToken makes an mfa token single-use
+func consumeMFAToken(claims *ActionClaims) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := markActionTokenUsed(tx, claims); err != nil {
+		return err
+	}
+
+	mfaAttempts.Lock()
+	delete(mfaAttempts.count, claims.ID)
+	mfaAttempts.Unlock()
+
+	return tx.Commit()
+}
+
+func allowMFAAttempt(claims *ActionClaims) bool {
+	mfaAttempts.Lock()
+	defer mfaAttempts.Unlock()
+
+	return mfaAttempts.count[claims.ID] < maxMFAAttempts
+}
+
+func recordMFAFailure(claims *ActionClaims) {
+	mfaAttempts.Lock()
+	defer mfaAttempts.Unlock()
+
+	mfaAttempts.count[claims.ID]++
+
+	// Entries outlive their token by at most mfaTokenTTL
+	id := claims.ID
+	time.AfterFunc(mfaTokenTTL, func() {
+		mfaAttempts.Lock()
+		delete(mfaAttempts.count, id)
+		mfaAttempts.Unlock()
+	})
+}
+
+// validateTOTP checks an RFC 6238 code within the allowed skew, skipping periods
+// at or before lastStep so that a code cannot be used twice
+func validateTOTP(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	key, err := base32NoPadding.DecodeString(secret)
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// totpCode computes the HOTP value for the time step
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// provisioningURI builds the otpauth URI understood by authenticator apps
+func provisioningURI(email, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", totpIssuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+
+	label := url.PathEscape(totpIssuer + ":" + email)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// newRecoveryCode returns a random code formatted as XXXXX-XXXXX
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate recovery code: %w", err)
+	}
+
+	code := base32NoPadding.EncodeToString(b)[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode accepts codes typed in lower case or without the dash
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	if len(code) != 10 {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed from the RFC 6238 test vectors, base32-encoded
+var rfcSecret = base32NoPadding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		if got := totpCode([]byte("12345678901234567890"), tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode(T=%d) = %q, want %q", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / totpPeriod
+	key := []byte("12345678901234567890")
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current period", secret: rfcSecret, code: "005924", wantStep: current, wantOK: true},
+		{name: "previous period", secret: rfcSecret, code: totpCode(key, current-1), wantStep: current - 1, wantOK: true},
+		{name: "next period", secret: rfcSecret, code: totpCode(key, current+1), wantStep: current + 1, wantOK: true},
+		{name: "outside skew", secret: rfcSecret, code: totpCode(key, current-2)},
+		{name: "replayed code", secret: rfcSecret, code: "005924", lastStep: current},
+		{name: "later code after use", secret: rfcSecret, code: totpCode(key, current+1), lastStep: current, wantStep: current + 1, wantOK: true},
+		{name: "wrong code", secret: rfcSecret, code: "000000"},
+		{name: "short code", secret: rfcSecret, code: "05924"},
+		{name: "invalid secret", secret: "not base32!", code: "005924"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := validateTOTP(tt.secret, tt.code, now, tt.lastStep)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("validateTOTP() = %d, %v, want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: "ABCDE-FGHIJ", want: "ABCDE-FGHIJ"},
+		{code: "abcde-fghij", want: "ABCDE-FGHIJ"},
+		{code: " abcdefghij ", want: "ABCDE-FGHIJ"},
+		{code: "ab-cde-fgh-ij", want: "ABCDE-FGHIJ"},
+		{code: "abc", want: "ABC"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeRecoveryCode(tt.code); got != tt.want {
+			t.Errorf("normalizeRecoveryCode(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
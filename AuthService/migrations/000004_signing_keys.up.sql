@@ -0,0 +1,6 @@
+CREATE TABLE signing_keys (
+    kid VARCHAR(64) PRIMARY KEY,
+    private_key TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    retired_at TIMESTAMP NULL
+);
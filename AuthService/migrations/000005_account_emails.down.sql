@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS used_tokens;
+
+ALTER TABLE users DROP COLUMN email_verified;
@@ -0,0 +1,13 @@
+CREATE TABLE login_attempts (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    user_id BIGINT UNSIGNED NULL,
+    ip VARCHAR(45) NOT NULL,
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    success BOOLEAN NOT NULL,
+    reason VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_login_attempts_email (email, created_at),
+    INDEX idx_login_attempts_ip (ip, created_at),
+    INDEX idx_login_attempts_user (user_id)
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS outbox;
+
+ALTER TABLE users
+    DROP COLUMN first_name,
+    DROP COLUMN last_name;
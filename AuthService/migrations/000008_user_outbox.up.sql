@@ -0,0 +1,20 @@
+ALTER TABLE users
+    ADD COLUMN first_name VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN last_name VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE TABLE outbox (
+    id SERIAL PRIMARY KEY,
+    event_key VARCHAR(64) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSON NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP NULL,
+    INDEX idx_outbox_pending (published_at, id)
+);
+
+-- Announce existing accounts so the Backend adopts their ids. Their names are only known
+-- to the Backend, so the event carries none and the Backend sends them back via BackfillProfile.
+INSERT INTO outbox (event_key, event_type, payload)
+SELECT CAST(id AS CHAR), 'user.linked', JSON_OBJECT('id', id, 'email', email)
+FROM users
+ORDER BY id;
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "github.com/TkMaxim9/EventOrganizationApp/proto/notifications"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// emailTimeout bounds how long a request waits for EmailSenderService
+const emailTimeout = 5 * time.Second
+
+var emailClient pb.NotificationServiceClient
+
+// initEmailClient connects to EmailSenderService's NotificationService
+func initEmailClient(addr string) error {
+	cc, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("failed to connect to email sender: %w", err)
+	}
+
+	emailClient = pb.NewNotificationServiceClient(cc)
+	return nil
+}
+
+// sendEmail delivers an email through EmailSenderService
+func sendEmail(to, subject, body string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), emailTimeout)
+	defer cancel()
+
+	_, err := emailClient.SendEmail(ctx, &pb.SendEmailRequest{
+		To:      to,
+		Subject: subject,
+		Body:    body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
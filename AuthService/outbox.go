@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Types of user events published to the users topic
+const (
+	eventUserCreated = "user.created"
+	eventUserUpdated = "user.updated"
+	eventUserDeleted = "user.deleted"
+	// eventUserLinked announces an account that existed before the outbox. It carries only the id
+	// and email, so the Backend adopts the id and keeps the names it already has.
+	eventUserLinked = "user.linked"
+)
+
+const (
+	outboxPollInterval = time.Second
+	outboxBatchSize    = 100
+	// outboxRetention is how long published events are kept for troubleshooting
+	outboxRetention = 7 * 24 * time.Hour
+)
+
+// UserEventData is the part of the account the Backend keeps a copy of
+type UserEventData struct {
+	ID        int    `json:"id"`
+	Email     string `json:"email"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
+
+// OutboxMessage is the envelope published to Kafka. ID grows with every change,
+// so consumers use it as the version of the user and ignore stale messages.
+type OutboxMessage struct {
+	ID         int64           `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt string          `json:"occurredAt"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// enqueueUserEvent records a user event in the same transaction as the change itself,
+// so the event is published if and only if the change is committed
+func enqueueUserEvent(tx *sql.Tx, eventType string, user UserEventData) error {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return fmt.Errorf("failed to encode user event: %w", err)
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO outbox (event_key, event_type, payload) VALUES (?, ?, ?)",
+		strconv.Itoa(user.ID), eventType, string(data),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue user event: %w", err)
+	}
+
+	return nil
+}
+
+// runOutboxRelay publishes pending outbox events to Kafka in order
+func runOutboxRelay(brokers []string, topic string) {
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}
+	defer writer.Close()
+
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Now()
+
+	for range ticker.C {
+		for {
+			published, err := publishOutboxBatch(writer)
+			if err != nil {
+				log.Printf("Failed to publish outbox events: %v", err)
+				break
+			}
+			if published < outboxBatchSize {
+				break
+			}
+		}
+
+		if time.Since(lastCleanup) > time.Hour {
+			if _, err := db.Exec("DELETE FROM outbox WHERE published_at < ?", time.Now().Add(-outboxRetention)); err != nil {
+				log.Printf("Failed to clean up outbox: %v", err)
+			}
+			lastCleanup = time.Now()
+		}
+	}
+}
+
+// publishOutboxBatch sends the oldest pending events. Rows stay locked until they are
+// marked published, so several instances never publish the same event concurrently.
+// A crash between sending and marking leads to a redelivery, which consumers tolerate.
+func publishOutboxBatch(writer *kafka.Writer) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT id, event_key, event_type, payload, created_at
+		FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED
+	`, outboxBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query outbox: %w", err)
+	}
+
+	var messages []kafka.Message
+	var ids []any
+	for rows.Next() {
+		var msg OutboxMessage
+		var key string
+		var payload []byte
+		var createdAt time.Time
+
+		if err := rows.Scan(&msg.ID, &key, &msg.Type, &payload, &createdAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		msg.OccurredAt = createdAt.Format(time.RFC3339)
+		msg.Data = payload
+
+		value, err := json.Marshal(msg)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to encode outbox event %d: %w", msg.ID, err)
+		}
+
+		// Messages of one user share a key and therefore a partition, which keeps them ordered
+		messages = append(messages, kafka.Message{Key: []byte(key), Value: value})
+		ids = append(ids, msg.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := writer.WriteMessages(ctx, messages...); err != nil {
+		return 0, fmt.Errorf("failed to write to kafka: %w", err)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	if _, err := tx.Exec("UPDATE outbox SET published_at = NOW() WHERE id IN ("+placeholders+")", ids...); err != nil {
+		return 0, fmt.Errorf("failed to mark outbox events published: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit outbox: %w", err)
+	}
+
+	return len(messages), nil
+}
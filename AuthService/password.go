@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptCost is the work factor for new password hashes. Hashes with a lower cost are
+// upgraded on the next successful login.
+const bcryptCost = 12
+
+// legacyHashLength is the length of the hex-encoded unsalted SHA-256 hashes
+// stored before the switch to bcrypt
+const legacyHashLength = sha256.Size * 2
+
+// hashPassword creates a bcrypt hash of the password
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// verifyPassword checks the password against a stored hash. needsRehash reports whether
+// the stored hash is a legacy SHA-256 hash or uses an outdated bcrypt cost.
+func verifyPassword(storedHash, password string) (ok bool, needsRehash bool) {
+	if isLegacyHash(storedHash) {
+		ok = subtle.ConstantTimeCompare([]byte(legacyHashPassword(password)), []byte(storedHash)) == 1
+		return ok, true
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(password)); err != nil {
+		return false, false
+	}
+
+	cost, err := bcrypt.Cost([]byte(storedHash))
+	return true, err == nil && cost < bcryptCost
+}
+
+// isLegacyHash reports whether the stored hash was produced by legacyHashPassword
+func isLegacyHash(storedHash string) bool {
+	if len(storedHash) != legacyHashLength {
+		return false
+	}
+
+	_, err := hex.DecodeString(storedHash)
+	return err == nil
+}
+
+// legacyHashPassword creates an unsalted SHA-256 hash of the password.
+// It is only used to verify hashes created before the switch to bcrypt.
+func legacyHashPassword(password string) string {
+	hash := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(hash[:])
+}
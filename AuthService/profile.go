@@ -0,0 +1,226 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxNameLength matches the name columns of the users table
+const maxNameLength = 64
+
+// UpdateProfileRequest represents the profile update payload
+type UpdateProfileRequest struct {
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
+
+// DeleteAccountRequest asks for the password before the account is deleted
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// validateNames checks the length of the profile names
+func validateNames(firstName, lastName string) error {
+	if utf8.RuneCountInString(firstName) > maxNameLength || utf8.RuneCountInString(lastName) > maxNameLength {
+		return fmt.Errorf("Имя и фамилия должны содержать не более %d символов", maxNameLength)
+	}
+	return nil
+}
+
+// handleGetMe returns the authenticated user
+func handleGetMe(w http.ResponseWriter, r *http.Request) {
+	claims, _ := claimsFromContext(r.Context())
+
+	user, err := getUserByID(claims.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, user.response())
+}
+
+// handleUpdateProfile changes the name of the authenticated user and propagates it to the Backend
+func handleUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	claims, _ := claimsFromContext(r.Context())
+
+	var req UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateNames(req.FirstName, req.LastName); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := updateProfile(claims.UserID, req.FirstName, req.LastName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to update profile of user %d: %v", claims.UserID, err)
+		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, user.response())
+}
+
+// handleDeleteAccount deletes the authenticated user after checking the password
+func handleDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	claims, _ := claimsFromContext(r.Context())
+
+	var req DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	var passwordHash string
+	if err := db.QueryRow("SELECT password_hash FROM users WHERE id = ?", claims.UserID).Scan(&passwordHash); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if ok, _ := verifyPassword(passwordHash, req.Password); !ok {
+		http.Error(w, "Неверный пароль", http.StatusForbidden)
+		return
+	}
+
+	if err := deleteUser(claims.UserID); err != nil {
+		log.Printf("Failed to delete user %d: %v", claims.UserID, err)
+		http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteUser deletes any user on behalf of an admin
+func handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := deleteUser(userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to delete user %d: %v", userID, err)
+		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// updateProfile stores the new names and enqueues a user.updated event
+func updateProfile(userID int, firstName, lastName string) (*User, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var user User
+	err = tx.QueryRow(
+		"SELECT id, email, role, email_verified FROM users WHERE id = ? FOR UPDATE", userID,
+	).Scan(&user.ID, &user.Email, &user.Role, &user.EmailVerified)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("UPDATE users SET first_name = ?, last_name = ? WHERE id = ?", firstName, lastName, userID); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	user.FirstName, user.LastName = firstName, lastName
+
+	if err := enqueueUserEvent(tx, eventUserUpdated, user.eventData()); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit profile: %w", err)
+	}
+
+	return &user, nil
+}
+
+// backfillProfile stores the names of a user that has none yet and enqueues a user.updated event.
+// A user who already set a name keeps it.
+func backfillProfile(userID int, firstName, lastName string) (*User, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var user User
+	err = tx.QueryRow(
+		"SELECT id, email, role, email_verified, first_name, last_name FROM users WHERE id = ? FOR UPDATE", userID,
+	).Scan(&user.ID, &user.Email, &user.Role, &user.EmailVerified, &user.FirstName, &user.LastName)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.FirstName != "" || user.LastName != "" || (firstName == "" && lastName == "") {
+		return &user, nil
+	}
+
+	if _, err := tx.Exec("UPDATE users SET first_name = ?, last_name = ? WHERE id = ?", firstName, lastName, userID); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	user.FirstName, user.LastName = firstName, lastName
+
+	if err := enqueueUserEvent(tx, eventUserUpdated, user.eventData()); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit profile: %w", err)
+	}
+
+	return &user, nil
+}
+
+// deleteUser removes the user with their sessions and enqueues a user.deleted event
+func deleteUser(userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var user User
+	err = tx.QueryRow(
+		"SELECT id, email, first_name, last_name FROM users WHERE id = ? FOR UPDATE", userID,
+	).Scan(&user.ID, &user.Email, &user.FirstName, &user.LastName)
+	if err != nil {
+		return err
+	}
+
+	// Sessions, refresh tokens and 2FA settings are removed by cascade
+	if _, err := tx.Exec("DELETE FROM users WHERE id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	if err := enqueueUserEvent(tx, eventUserDeleted, user.eventData()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// accessTokenTTL is the lifetime of access tokens. It is kept short because access
+	// tokens are verified locally by other services.
+	accessTokenTTL = 15 * time.Minute
+	// refreshTokenTTL is the lifetime of a session; refresh tokens never outlive their session
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+)
+
+// RefreshRequest represents the refresh and logout request payload
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// IntrospectRequest represents the token introspection request payload
+type IntrospectRequest struct {
+	Token string `json:"token"`
+}
+
+// IntrospectResponse describes whether an access token is still usable
+type IntrospectResponse struct {
+	Active    bool   `json:"active"`
+	UserID    int    `json:"userId,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Role      string `json:"role,omitempty"`
+	SessionID string `json:"sessionId,omitempty"`
+	Expires   int64  `json:"exp,omitempty"`
+}
+
+// createSession starts a new session for the user and returns its first refresh token
+func createSession(userID int) (sessionID, refreshToken string, expiresAt time.Time, err error) {
+	sessionID, err = randomToken(16)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	refreshToken, err = randomToken(32)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	expiresAt = time.Now().Add(refreshTokenTTL)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("INSERT INTO sessions (id, user_id, expires_at) VALUES (?, ?, ?)", sessionID, userID, expiresAt)
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	_, err = tx.Exec("INSERT INTO refresh_tokens (token_hash, session_id) VALUES (?, ?)", hashToken(refreshToken), sessionID)
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", "", time.Time{}, fmt.Errorf("failed to commit session: %w", err)
+	}
+
+	return sessionID, refreshToken, expiresAt, nil
+}
+
+// rotateRefreshToken exchanges a refresh token for a new one. Presenting an already
+// rotated token revokes the whole session, since either the client or an attacker
+// holds a stolen copy.
+func rotateRefreshToken(refreshToken string) (userID int, sessionID, newToken string, expiresAt time.Time, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, "", "", time.Time{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var usedAt, revokedAt sql.NullTime
+	err = tx.QueryRow(`
+		SELECT rt.session_id, rt.used_at, s.user_id, s.expires_at, s.revoked_at
+		FROM refresh_tokens rt
+		JOIN sessions s ON s.id = rt.session_id
+		WHERE rt.token_hash = ?
+		FOR UPDATE
+	`, hashToken(refreshToken)).Scan(&sessionID, &usedAt, &userID, &expiresAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, "", "", time.Time{}, ErrInvalidRefreshToken
+		}
+		return 0, "", "", time.Time{}, fmt.Errorf("failed to look up refresh token: %w", err)
+	}
+
+	if revokedAt.Valid || time.Now().After(expiresAt) {
+		return 0, "", "", time.Time{}, ErrInvalidRefreshToken
+	}
+
+	if usedAt.Valid {
+		if _, err := tx.Exec("UPDATE sessions SET revoked_at = NOW() WHERE id = ?", sessionID); err != nil {
+			return 0, "", "", time.Time{}, fmt.Errorf("failed to revoke session: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, "", "", time.Time{}, fmt.Errorf("failed to commit revocation: %w", err)
+		}
+		return 0, sessionID, "", time.Time{}, ErrRefreshTokenReused
+	}
+
+	newToken, err = randomToken(32)
+	if err != nil {
+		return 0, "", "", time.Time{}, err
+	}
+
+	if _, err := tx.Exec("UPDATE refresh_tokens SET used_at = NOW() WHERE token_hash = ?", hashToken(refreshToken)); err != nil {
+		return 0, "", "", time.Time{}, fmt.Errorf("failed to mark refresh token as used: %w", err)
+	}
+
+	_, err = tx.Exec("INSERT INTO refresh_tokens (token_hash, session_id) VALUES (?, ?)", hashToken(newToken), sessionID)
+	if err != nil {
+		return 0, "", "", time.Time{}, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, "", "", time.Time{}, fmt.Errorf("failed to commit refresh: %w", err)
+	}
+
+	return userID, sessionID, newToken, expiresAt, nil
+}
+
+// revokeSessionByRefreshToken revokes the session the refresh token belongs to
+func revokeSessionByRefreshToken(refreshToken string) error {
+	_, err := db.Exec(`
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE revoked_at IS NULL
+		  AND id = (SELECT session_id FROM refresh_tokens WHERE token_hash = ?)
+	`, hashToken(refreshToken))
+	return err
+}
+
+// revokeUserSessions revokes every active session of the user
+func revokeUserSessions(userID int) error {
+	_, err := db.Exec("UPDATE sessions SET revoked_at = NOW() WHERE user_id = ? AND revoked_at IS NULL", userID)
+	return err
+}
+
+// isSessionActive reports whether the session exists, is not revoked and has not expired
+func isSessionActive(sessionID string) (bool, error) {
+	var active bool
+	err := db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM sessions
+			WHERE id = ? AND revoked_at IS NULL AND expires_at > NOW()
+		)
+	`, sessionID).Scan(&active)
+	return active, err
+}
+
+// issueTokens starts a session for the user and writes access and refresh tokens
+func issueTokens(w http.ResponseWriter, user *User, status int) {
+	resp, err := startSession(user)
+	if err != nil {
+		log.Printf("Failed to start session for user %d: %v", user.ID, err)
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, status, resp)
+}
+
+// startSession creates a session for the user and builds the token response
+func startSession(user *User) (*JWTResponse, error) {
+	sessionID, refreshToken, refreshExpires, err := createSession(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return sessionResponse(user, sessionID, refreshToken, refreshExpires)
+}
+
+// sessionResponse generates an access token for the session and builds the token response
+func sessionResponse(user *User, sessionID, refreshToken string, refreshExpires time.Time) (*JWTResponse, error) {
+	token, expiresAt, err := generateJWT(user, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return &JWTResponse{
+		Token:          token,
+		Expires:        expiresAt.Format(time.RFC3339),
+		RefreshToken:   refreshToken,
+		RefreshExpires: refreshExpires.Format(time.RFC3339),
+		User:           user.response(),
+	}, nil
+}
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// handleRefresh rotates the refresh token and issues a new access token
+func handleRefresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	userID, sessionID, refreshToken, refreshExpires, err := rotateRefreshToken(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			log.Printf("Refresh token reuse detected, session %s revoked", sessionID)
+		} else if !errors.Is(err, ErrInvalidRefreshToken) {
+			log.Printf("Failed to rotate refresh token: %v", err)
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := getUserByID(userID)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := sessionResponse(user, sessionID, refreshToken, refreshExpires)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleLogout revokes the session of the given refresh token
+func handleLogout(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := revokeSessionByRefreshToken(req.RefreshToken); err != nil {
+		log.Printf("Failed to revoke session: %v", err)
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleLogoutAll revokes every session of the authenticated user
+func handleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	claims, _ := claimsFromContext(r.Context())
+
+	if err := revokeUserSessions(claims.UserID); err != nil {
+		log.Printf("Failed to revoke sessions of user %d: %v", claims.UserID, err)
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleIntrospect tells other services whether an access token is valid and its session is active
+func handleIntrospect(w http.ResponseWriter, r *http.Request) {
+	var req IntrospectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	var resp IntrospectResponse
+
+	claims, err := parseAccessToken(req.Token)
+	if err == nil {
+		active, err := isSessionActive(claims.SessionID)
+		if err != nil {
+			log.Printf("Failed to check session %s: %v", claims.SessionID, err)
+			http.Error(w, "Failed to check session", http.StatusInternalServerError)
+			return
+		}
+
+		if active {
+			resp = IntrospectResponse{
+				Active:    true,
+				UserID:    claims.UserID,
+				Email:     claims.Email,
+				Role:      claims.Role,
+				SessionID: claims.SessionID,
+				Expires:   claims.ExpiresAt.Unix(),
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// randomToken returns n random bytes encoded as URL-safe base64
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the SHA-256 hash of an opaque token for storage
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
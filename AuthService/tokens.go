@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// AccessClaims represents the claims of an access token
+type AccessClaims struct {
+	UserID        int    `json:"user_id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Role          string `json:"role"`
+	SessionID     string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+type claimsCtxKey struct{}
+
+// generateJWT creates a new access token for the authenticated user within a session
+func generateJWT(user *User, sessionID string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(accessTokenTTL)
+
+	claims := AccessClaims{
+		UserID:        user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Role:          user.Role,
+		SessionID:     sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	tokenString, err := signToken(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expiresAt, nil
+}
+
+// signToken signs claims with the active signing key and sets its kid header
+func signToken(claims jwt.Claims) (string, error) {
+	key, err := keys.current()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.PrivateKey)
+}
+
+// verificationKey resolves the public key a token was signed with by its kid header
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	return keys.publicKey(kid)
+}
+
+// parseAccessToken validates an access token and returns its claims
+func parseAccessToken(tokenString string) (*AccessClaims, error) {
+	var claims AccessClaims
+
+	_, err := jwt.ParseWithClaims(tokenString, &claims, verificationKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+// requireAuth rejects requests without a valid access token of an active session
+// and stores its claims in the context
+func requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := parseAccessToken(tokenString)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		active, err := isSessionActive(claims.SessionID)
+		if err != nil {
+			log.Printf("Failed to check session %s: %v", claims.SessionID, err)
+			http.Error(w, "Failed to check session", http.StatusInternalServerError)
+			return
+		}
+		if !active {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), claimsCtxKey{}, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireRole rejects requests whose access token does not carry the given role
+func requireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := claimsFromContext(r.Context())
+			if !ok || claims.Role != role {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// claimsFromContext returns the access token claims stored by requireAuth
+func claimsFromContext(ctx context.Context) (*AccessClaims, bool) {
+	claims, ok := ctx.Value(claimsCtxKey{}).(*AccessClaims)
+	return claims, ok
+}
@@ -1,16 +1,30 @@
 package main
 
 import (
+	authgrpc "Backend/internal/clients/auth/grpc"
+	authrest "Backend/internal/clients/auth/rest"
 	emailsendergrpc "Backend/internal/clients/emailsender/grpc"
+	"Backend/internal/clients/geocoder/fixture"
+	"Backend/internal/clients/geocoder/nominatim"
 	"Backend/internal/config"
+	"Backend/internal/consumers/users"
 	"Backend/internal/handlers/events"
+	"Backend/internal/lib/invite"
+	"Backend/internal/lib/jwks"
 	"Backend/internal/lib/logger/sl"
+	"Backend/internal/lib/ticket"
 	"Backend/internal/lib/validator"
+	"Backend/internal/lifecycle"
+	"Backend/internal/middleware/auth"
+	"Backend/internal/search"
 	"Backend/internal/storage/mysql"
+	"context"
 	"log/slog"
 	_ "log/slog"
 	"net/http"
 	"os"
+	// Часовые пояса событий не должны зависеть от tzdata в системе
+	_ "time/tzdata"
 
 	"github.com/go-chi/cors"
 
@@ -46,14 +60,58 @@ func main() {
 	// Регистрируем кастомные валидаторы
 	err = validator.RegisterCustomValidators(validate)
 	if err != nil {
-		log.Error("Ошибка при регистрации валидаторов: %v", err)
+		log.Error("Ошибка при регистрации валидаторов", sl.Err(err))
 	}
 
 	emailsenderclient, err := emailsendergrpc.New(log, "localhost:2282")
 	if err != nil {
-		log.Error("Ошибка при подключении к сервису отправки уведомлений: %v", err)
+		log.Error("Ошибка при подключении к сервису отправки уведомлений", sl.Err(err))
 	}
 
+	authgrpcclient, err := authgrpc.New(log, cfg.Auth.GRPCAddress, cfg.Auth.ServiceToken, cfg.Auth.SessionCacheTTL)
+	if err != nil {
+		log.Error("Ошибка при подключении к сервису авторизации", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// Пользователи создаются в AuthService и приходят сюда через Kafka с тем же идентификатором.
+	// Имена пользователей, созданных до AuthService, возвращаются туда через gRPC.
+	usersConsumer := users.New(log, cfg.Kafka.Brokers, cfg.Kafka.UsersTopic, cfg.Kafka.UsersGroupID, storage, authgrpcclient)
+	go func() {
+		if err := usersConsumer.Run(context.Background()); err != nil {
+			log.Error("users consumer stopped", sl.Err(err))
+		}
+	}()
+
+	// Прошедшие события завершаются автоматически
+	go lifecycle.New(log, storage, cfg.Lifecycle.CompletionInterval).Run(context.Background())
+
+	// Поисковый индекс строится в памяти из базы и догоняет изменения событий
+	searchIndex, err := search.New(log, storage, cfg.Search.SyncInterval)
+	if err != nil {
+		log.Error("failed to create search index", sl.Err(err))
+		os.Exit(1)
+	}
+	go searchIndex.Run(context.Background())
+
+	var geocoderclient events.Geocoder
+	switch cfg.Geocoder.Provider {
+	case "nominatim":
+		geocoderclient = nominatim.New(cfg.Geocoder.NominatimURL, cfg.Geocoder.UserAgent, cfg.Geocoder.Timeout)
+	default:
+		geocoderclient, err = fixture.Load(cfg.Geocoder.FixturePath)
+		if err != nil {
+			log.Error("failed to load geocoder fixture", sl.Err(err))
+			os.Exit(1)
+		}
+	}
+
+	var authclient auth.SessionChecker = authrest.New(log, cfg.Auth.ServiceURL, cfg.Auth.SessionCacheTTL)
+	if cfg.Auth.Transport == "grpc" {
+		authclient = authgrpcclient
+	}
+	authkeys := jwks.New(cfg.Auth.JWKSURL, cfg.Auth.JWKSCacheTTL)
+
 	router := chi.NewRouter()
 
 	corsMiddleware := cors.New(cors.Options{
@@ -71,8 +129,24 @@ func main() {
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.URLFormat)
+	router.Use(auth.New(log, authkeys, authclient))
+
+	invites := events.Invites{
+		Signer:      invite.New(cfg.Invites.Secret),
+		TTL:         cfg.Invites.LinkTTL,
+		MaxTTL:      cfg.Invites.MaxLinkTTL,
+		FrontendURL: cfg.Invites.FrontendURL,
+	}
+
+	tickets := events.Tickets{
+		Signer: ticket.New(cfg.Tickets.Secret),
+		QRSize: cfg.Tickets.QRSize,
+	}
+
+	events.Init(router, log, storage, validate, emailsenderclient, searchIndex, geocoderclient, invites, tickets)
 
-	events.Init(router, log, storage, validate, emailsenderclient)
+	// Ключ закрепляется на устройствах для отметки без сети
+	log.Info("check-in snapshot public key", slog.String("key", tickets.Signer.SnapshotPublicKey()))
 
 	log.Info("starting server", slog.String("address", cfg.Address))
 
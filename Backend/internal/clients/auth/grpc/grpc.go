@@ -0,0 +1,227 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	authpb "github.com/TkMaxim9/EventOrganizationApp/proto/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// User - учетная запись пользователя в AuthService
+type User struct {
+	ID            int64
+	Email         string
+	Role          string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+}
+
+// TokenInfo - результат проверки токена
+type TokenInfo struct {
+	Active    bool
+	User      User
+	SessionID string
+	ExpiresAt time.Time
+}
+
+// ErrUserNotFound возвращается GetUser для неизвестного идентификатора
+var ErrUserNotFound = errors.New("user not found")
+
+// Client обращается к gRPC API AuthService. Проверки сессий кэшируются так же,
+// как в HTTP-клиенте, поэтому клиенты взаимозаменяемы в middleware аутентификации.
+type Client struct {
+	api      authpb.AuthServiceClient
+	log      *slog.Logger
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// serviceToken передает токен сервиса в каждом вызове AuthService
+type serviceToken string
+
+func (t serviceToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity разрешает токен без TLS: AuthService слушает только внутренний адрес
+func (t serviceToken) RequireTransportSecurity() bool {
+	return false
+}
+
+type cacheEntry struct {
+	active    bool
+	expiresAt time.Time
+}
+
+func New(
+	log *slog.Logger,
+	addr string,
+	token string,
+	cacheTTL time.Duration,
+) (*Client, error) {
+	const op = "grpc.New"
+
+	cc, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(serviceToken(token)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Client{
+		api:      authpb.NewAuthServiceClient(cc),
+		log:      log,
+		cacheTTL: cacheTTL,
+		cache:    make(map[string]cacheEntry),
+	}, nil
+}
+
+// ValidateToken проверяет подпись, срок действия и сессию токена
+func (c *Client) ValidateToken(ctx context.Context, token string) (TokenInfo, error) {
+	const op = "grpc.ValidateToken"
+
+	resp, err := c.api.ValidateToken(ctx, &authpb.ValidateTokenRequest{Token: token})
+	if err != nil {
+		return TokenInfo{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !resp.Active {
+		return TokenInfo{}, nil
+	}
+
+	return TokenInfo{
+		Active:    true,
+		User:      userFromProto(resp.User),
+		SessionID: resp.SessionId,
+		ExpiresAt: time.Unix(resp.ExpiresAt, 0),
+	}, nil
+}
+
+// SessionActive сообщает, активна ли сессия, к которой относится токен
+func (c *Client) SessionActive(ctx context.Context, sessionID, token string) (bool, error) {
+	const op = "grpc.SessionActive"
+
+	if active, ok := c.cached(sessionID); ok {
+		return active, nil
+	}
+
+	info, err := c.ValidateToken(ctx, token)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Подпись и срок действия уже проверены локально, поэтому неактивный ответ
+	// означает отозванную сессию и тоже кэшируется
+	c.store(sessionID, info.Active)
+
+	return info.Active, nil
+}
+
+// GetUser возвращает пользователя по идентификатору
+func (c *Client) GetUser(ctx context.Context, id int64) (User, error) {
+	const op = "grpc.GetUser"
+
+	resp, err := c.api.GetUser(ctx, &authpb.GetUserRequest{Id: id})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return User{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		return User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return userFromProto(resp.User), nil
+}
+
+// ListUsersByIds возвращает известных пользователей из списка, неизвестные пропускаются
+func (c *Client) ListUsersByIds(ctx context.Context, ids []int64) ([]User, error) {
+	const op = "grpc.ListUsersByIds"
+
+	resp, err := c.api.ListUsersByIds(ctx, &authpb.ListUsersByIdsRequest{Ids: ids})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	users := make([]User, 0, len(resp.Users))
+	for _, u := range resp.Users {
+		users = append(users, userFromProto(u))
+	}
+
+	return users, nil
+}
+
+// BackfillProfile передает в AuthService имена пользователя, созданного до него.
+// AuthService не меняет уже заданные имена.
+func (c *Client) BackfillProfile(ctx context.Context, id int64, firstName, lastName string) error {
+	const op = "grpc.BackfillProfile"
+
+	_, err := c.api.BackfillProfile(ctx, &authpb.BackfillProfileRequest{
+		Id:        id,
+		FirstName: firstName,
+		LastName:  lastName,
+	})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func userFromProto(u *authpb.User) User {
+	if u == nil {
+		return User{}
+	}
+
+	return User{
+		ID:            u.Id,
+		Email:         u.Email,
+		Role:          u.Role,
+		EmailVerified: u.EmailVerified,
+		FirstName:     u.FirstName,
+		LastName:      u.LastName,
+	}
+}
+
+func (c *Client) cached(sessionID string) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.cache[sessionID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return false, false
+	}
+
+	return entry.active, true
+}
+
+func (c *Client) store(sessionID string, active bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	// Периодически вычищаем устаревшие записи, чтобы кэш не рос бесконечно
+	if len(c.cache) >= 10000 {
+		for id, entry := range c.cache {
+			if now.After(entry.expiresAt) {
+				delete(c.cache, id)
+			}
+		}
+	}
+
+	c.cache[sessionID] = cacheEntry{active: active, expiresAt: now.Add(c.cacheTTL)}
+}
@@ -0,0 +1,124 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client обращается к HTTP API AuthService, чтобы узнать, не отозвана ли сессия токена.
+// Ответы кэшируются по идентификатору сессии, поэтому отзыв вступает в силу
+// не позднее чем через cacheTTL.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	cacheTTL   time.Duration
+	log        *slog.Logger
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	active    bool
+	expiresAt time.Time
+}
+
+type introspectRequest struct {
+	Token string `json:"token"`
+}
+
+type introspectResponse struct {
+	Active bool `json:"active"`
+}
+
+func New(
+	log *slog.Logger,
+	baseURL string,
+	cacheTTL time.Duration,
+) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		cacheTTL:   cacheTTL,
+		log:        log,
+		cache:      make(map[string]cacheEntry),
+	}
+}
+
+// SessionActive сообщает, активна ли сессия, к которой относится токен
+func (c *Client) SessionActive(ctx context.Context, sessionID, token string) (bool, error) {
+	const op = "rest.SessionActive"
+
+	if active, ok := c.cached(sessionID); ok {
+		return active, nil
+	}
+
+	body, err := json.Marshal(introspectRequest{Token: token})
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/introspect", bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("%s: unexpected status %d", op, resp.StatusCode)
+	}
+
+	var res introspectResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Подпись и срок действия уже проверены локально, поэтому неактивный ответ
+	// означает отозванную сессию и тоже кэшируется
+	c.store(sessionID, res.Active)
+
+	return res.Active, nil
+}
+
+func (c *Client) cached(sessionID string) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.cache[sessionID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return false, false
+	}
+
+	return entry.active, true
+}
+
+func (c *Client) store(sessionID string, active bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	// Периодически вычищаем устаревшие записи, чтобы кэш не рос бесконечно
+	if len(c.cache) >= 10000 {
+		for id, entry := range c.cache {
+			if now.After(entry.expiresAt) {
+				delete(c.cache, id)
+			}
+		}
+	}
+
+	c.cache[sessionID] = cacheEntry{active: active, expiresAt: now.Add(c.cacheTTL)}
+}
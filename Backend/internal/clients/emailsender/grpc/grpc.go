@@ -10,6 +10,13 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+// Attachment - файл, прикладываемый к письму
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
 type Client struct {
 	api emailsender.NotificationServiceClient
 	log *slog.Logger
@@ -58,3 +65,28 @@ func (c *Client) DeleteNotifications(ctx context.Context, notificationIds []int6
 	}
 	return resp.Success, nil
 }
+
+// SendEmail немедленно отправляет письмо с необязательными вложениями
+func (c *Client) SendEmail(ctx context.Context, to, subject, body string, attachments ...Attachment) error {
+	const op = "grpc.SendEmail"
+
+	req := &emailsender.SendEmailRequest{
+		To:      to,
+		Subject: subject,
+		Body:    body,
+	}
+	for _, a := range attachments {
+		req.Attachments = append(req.Attachments, &emailsender.Attachment{
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Content:     a.Content,
+		})
+	}
+
+	_, err := c.api.SendEmail(ctx, req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package fixture
+
+import (
+	"Backend/internal/clients/geocoder"
+	"Backend/internal/lib/geo"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Geocoder находит координаты по заранее заданной таблице адресов без обращения к сети.
+// Используется для локальной разработки и тестов.
+type Geocoder struct {
+	points map[string]geo.Point
+}
+
+func New(points map[string]geo.Point) *Geocoder {
+	normalized := make(map[string]geo.Point, len(points))
+	for address, point := range points {
+		normalized[geocoder.Normalize(address)] = point
+	}
+
+	return &Geocoder{points: normalized}
+}
+
+// Load читает таблицу адресов из JSON-файла вида {"адрес": {"lat": 55.75, "lng": 37.61}}
+func Load(path string) (*Geocoder, error) {
+	const op = "fixture.Load"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var points map[string]geo.Point
+	if err := json.Unmarshal(data, &points); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return New(points), nil
+}
+
+func (g *Geocoder) Geocode(ctx context.Context, address string) (geo.Point, error) {
+	point, ok := g.points[geocoder.Normalize(address)]
+	if !ok {
+		return geo.Point{}, geocoder.ErrNotFound
+	}
+
+	return point, nil
+}
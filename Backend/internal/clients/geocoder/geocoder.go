@@ -0,0 +1,15 @@
+// Package geocoder содержит общие части клиентов геокодирования адресов
+package geocoder
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNotFound возвращается, если адрес не удалось найти
+var ErrNotFound = errors.New("address not found")
+
+// Normalize приводит адрес к виду для сравнения: нижний регистр и одиночные пробелы
+func Normalize(address string) string {
+	return strings.Join(strings.Fields(strings.ToLower(address)), " ")
+}
@@ -0,0 +1,111 @@
+package nominatim
+
+import (
+	"Backend/internal/clients/geocoder"
+	"Backend/internal/lib/geo"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minInterval - минимальный промежуток между запросами по правилам публичного сервера Nominatim
+const minInterval = time.Second
+
+// Client геокодирует адреса через API Nominatim (OpenStreetMap)
+type Client struct {
+	baseURL    string
+	userAgent  string
+	httpClient *http.Client
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+type place struct {
+	Lat string `json:"lat"`
+	Lon string `json:"lon"`
+}
+
+// New создает клиента. userAgent обязателен: публичный сервер отклоняет запросы без него.
+func New(baseURL, userAgent string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		userAgent:  userAgent,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *Client) Geocode(ctx context.Context, address string) (geo.Point, error) {
+	const op = "nominatim.Geocode"
+
+	if err := c.wait(ctx); err != nil {
+		return geo.Point{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := url.Values{
+		"q":      {address},
+		"format": {"jsonv2"},
+		"limit":  {"1"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/search?"+query.Encode(), nil)
+	if err != nil {
+		return geo.Point{}, fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept-Language", "ru")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return geo.Point{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return geo.Point{}, fmt.Errorf("%s: unexpected status %d", op, resp.StatusCode)
+	}
+
+	var places []place
+	if err := json.NewDecoder(resp.Body).Decode(&places); err != nil {
+		return geo.Point{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(places) == 0 {
+		return geo.Point{}, geocoder.ErrNotFound
+	}
+
+	lat, err := strconv.ParseFloat(places[0].Lat, 64)
+	if err != nil {
+		return geo.Point{}, fmt.Errorf("%s: invalid latitude %q", op, places[0].Lat)
+	}
+	lng, err := strconv.ParseFloat(places[0].Lon, 64)
+	if err != nil {
+		return geo.Point{}, fmt.Errorf("%s: invalid longitude %q", op, places[0].Lon)
+	}
+
+	return geo.Point{Lat: lat, Lng: lng}, nil
+}
+
+// wait выдерживает интервал между запросами
+func (c *Client) wait(ctx context.Context) error {
+	c.mu.Lock()
+	next := c.last.Add(minInterval)
+	now := time.Now()
+	if next.Before(now) {
+		next = now
+	}
+	c.last = next
+	c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(next)):
+		return nil
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/ilyakaznacheev/cleanenv"
 	"log"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -11,6 +12,13 @@ type Config struct {
 	Env         string `yaml:"env" env-default:"local"`
 	StoragePath string `yaml:"storage_path"`
 	HTTPServer  `yaml:"http_server" env-required:"true"`
+	Auth        `yaml:"auth"`
+	Kafka       `yaml:"kafka"`
+	Lifecycle   `yaml:"lifecycle"`
+	Search      `yaml:"search"`
+	Geocoder    `yaml:"geocoder"`
+	Invites     `yaml:"invites"`
+	Tickets     `yaml:"tickets"`
 }
 
 type HTTPServer struct {
@@ -19,12 +27,67 @@ type HTTPServer struct {
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"5s"`
 }
 
-func MustLoad() *Config {
-	os.Setenv("CONFIG_PATH", "./config/local.yaml")
+type Auth struct {
+	ServiceURL      string        `yaml:"service_url" env:"AUTH_SERVICE_URL" env-default:"http://localhost:8080"`
+	JWKSURL         string        `yaml:"jwks_url" env:"AUTH_JWKS_URL" env-default:"http://localhost:8080/.well-known/jwks.json"`
+	JWKSCacheTTL    time.Duration `yaml:"jwks_cache_ttl" env-default:"10m"`
+	SessionCacheTTL time.Duration `yaml:"session_cache_ttl" env-default:"30s"`
+	GRPCAddress     string        `yaml:"grpc_address" env:"AUTH_GRPC_ADDRESS" env-default:"localhost:2283"`
+	// ServiceToken предъявляется gRPC API AuthService, совпадает с его GRPC_SERVICE_TOKEN.
+	// Читается только из AUTH_SERVICE_TOKEN или файла из AUTH_SERVICE_TOKEN_FILE.
+	ServiceToken string `yaml:"-" env:"AUTH_SERVICE_TOKEN"`
+	// Transport задает способ проверки сессий: "http" или "grpc"
+	Transport string `yaml:"transport" env-default:"grpc"`
+}
+
+type Kafka struct {
+	Brokers      []string `yaml:"brokers" env:"KAFKA_BROKERS" env-default:"localhost:9092"`
+	UsersTopic   string   `yaml:"users_topic" env-default:"users"`
+	UsersGroupID string   `yaml:"users_group_id" env-default:"backend-users"`
+}
+
+type Lifecycle struct {
+	// CompletionInterval - период проверки прошедших событий
+	CompletionInterval time.Duration `yaml:"completion_interval" env-default:"1m"`
+}
 
+type Search struct {
+	// SyncInterval - период синхронизации поискового индекса с базой
+	SyncInterval time.Duration `yaml:"sync_interval" env-default:"30s"`
+}
+
+type Geocoder struct {
+	// Provider задает источник координат: "nominatim" или "fixture" для работы без сети
+	Provider     string        `yaml:"provider" env-default:"fixture"`
+	FixturePath  string        `yaml:"fixture_path" env-default:"./config/geocoder_fixture.json"`
+	NominatimURL string        `yaml:"nominatim_url" env-default:"https://nominatim.openstreetmap.org"`
+	UserAgent    string        `yaml:"user_agent" env-default:"EventsOrg/1.0"`
+	Timeout      time.Duration `yaml:"timeout" env-default:"3s"`
+}
+
+type Invites struct {
+	// Secret подписывает ссылки-приглашения, при его смене все выданные ссылки перестают действовать.
+	// Читается только из INVITE_SECRET или файла из INVITE_SECRET_FILE.
+	Secret string `yaml:"-" env:"INVITE_SECRET"`
+	// LinkTTL - срок действия ссылки по умолчанию, MaxLinkTTL - наибольший срок, который можно запросить
+	LinkTTL    time.Duration `yaml:"link_ttl" env-default:"168h"`
+	MaxLinkTTL time.Duration `yaml:"max_link_ttl" env-default:"720h"`
+	// FrontendURL - адрес фронтенда, на страницу события которого ведут ссылки
+	FrontendURL string `yaml:"frontend_url" env:"FRONTEND_URL" env-default:"http://localhost:5173"`
+}
+
+type Tickets struct {
+	// Secret подписывает коды билетов, при его смене все выданные билеты перестают действовать.
+	// Читается только из TICKET_SECRET или файла из TICKET_SECRET_FILE.
+	Secret string `yaml:"-" env:"TICKET_SECRET"`
+	// QRSize - сторона QR-кода билета в PNG в пикселях
+	QRSize int `yaml:"qr_size" env-default:"256"`
+}
+
+func MustLoad() *Config {
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
-		log.Fatal("CONFIG_PATH environment variable not set")
+		configPath = "./config/local.yaml"
 	}
 
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
@@ -37,5 +100,27 @@ func MustLoad() *Config {
 		log.Fatalf("cannot read config: %s", err)
 	}
 
+	mustSecret(&cfg.Auth.ServiceToken, "AUTH_SERVICE_TOKEN")
+	mustSecret(&cfg.Invites.Secret, "INVITE_SECRET")
+	mustSecret(&cfg.Tickets.Secret, "TICKET_SECRET")
+
 	return &cfg
 }
+
+// mustSecret дополняет секрет содержимым файла из переменной name_FILE и останавливает запуск,
+// если секрет не задан. Секреты не хранятся в YAML, чтобы не попасть в репозиторий.
+func mustSecret(value *string, name string) {
+	if *value == "" {
+		if path := os.Getenv(name + "_FILE"); path != "" {
+			secret, err := os.ReadFile(path)
+			if err != nil {
+				log.Fatalf("cannot read %s_FILE: %s", name, err)
+			}
+			*value = strings.TrimSpace(string(secret))
+		}
+	}
+
+	if *value == "" {
+		log.Fatalf("%s or %s_FILE must be set", name, name)
+	}
+}
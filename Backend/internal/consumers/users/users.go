@@ -0,0 +1,179 @@
+package users
+
+import (
+	authgrpc "Backend/internal/clients/auth/grpc"
+	"Backend/internal/lib/logger/sl"
+	"Backend/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Типы событий, которые AuthService публикует в топик пользователей
+const (
+	eventUserCreated = "user.created"
+	eventUserUpdated = "user.updated"
+	eventUserDeleted = "user.deleted"
+	// eventUserLinked - учетная запись, созданная до outbox. Событие содержит только идентификатор и email.
+	eventUserLinked = "user.linked"
+)
+
+const (
+	retryBackoff = time.Second
+	// maxRetryBackoff ограничивает паузу между попытками, пока хранилище недоступно
+	maxRetryBackoff = time.Minute
+)
+
+var errMalformed = errors.New("malformed message")
+
+type UserStorage interface {
+	UpsertUser(user storage.SyncedUser, version int64) error
+	LinkUser(user storage.SyncedUser, version int64) (storage.SyncedUser, error)
+	DeleteUser(userID int64, version int64) error
+}
+
+// ProfileBackfiller возвращает в AuthService имена пользователей, которые до него хранились только здесь
+type ProfileBackfiller interface {
+	BackfillProfile(ctx context.Context, id int64, firstName, lastName string) error
+}
+
+// message - конверт события из outbox AuthService. ID растет с каждым изменением и служит версией пользователя.
+type message struct {
+	ID   int64              `json:"id"`
+	Type string             `json:"type"`
+	Data storage.SyncedUser `json:"data"`
+}
+
+// Consumer поддерживает таблицу User в соответствии с учетными записями AuthService
+type Consumer struct {
+	log      *slog.Logger
+	reader   *kafka.Reader
+	storage  UserStorage
+	profiles ProfileBackfiller
+}
+
+func New(log *slog.Logger, brokers []string, topic, groupID string, userStorage UserStorage, profiles ProfileBackfiller) *Consumer {
+	return &Consumer{
+		log: log,
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers: brokers,
+			Topic:   topic,
+			GroupID: groupID,
+		}),
+		storage:  userStorage,
+		profiles: profiles,
+	}
+}
+
+// Run читает события до отмены контекста. Смещение фиксируется только после обработки,
+// поэтому при перезапуске события доставляются повторно, что безопасно благодаря версиям.
+func (c *Consumer) Run(ctx context.Context) error {
+	const op = "consumers.users.Run"
+
+	defer c.reader.Close()
+
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := c.handleWithRetry(ctx, msg); err != nil {
+			// Контекст отменен до применения события: смещение не фиксируется,
+			// и событие будет доставлено снова после перезапуска
+			return nil
+		}
+
+		if err := c.reader.CommitMessages(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("%s: failed to commit: %w", op, err)
+		}
+	}
+}
+
+// handleWithRetry применяет событие, повторяя попытки, пока оно не будет применено. Пропуск события
+// означал бы, что пользователь может войти, но не существует здесь, поэтому при временных ошибках
+// синхронизация останавливается до восстановления хранилища. Пропускаются только некорректные события,
+// которые не применятся никогда. Ошибка возвращается только при отмене контекста.
+func (c *Consumer) handleWithRetry(ctx context.Context, msg kafka.Message) error {
+	const op = "consumers.users.handle"
+
+	log := c.log.With(slog.String("op", op), slog.Int64("offset", msg.Offset), slog.String("key", string(msg.Key)))
+
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := c.handle(ctx, msg)
+		if err == nil {
+			return nil
+		}
+
+		if errors.Is(err, errMalformed) {
+			log.Error("malformed user event, skipping", sl.Err(err))
+			return nil
+		}
+
+		log.Warn("failed to apply user event, retrying", sl.Err(err), slog.Int("attempt", attempt), slog.Duration("backoff", backoff))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+func (c *Consumer) handle(ctx context.Context, msg kafka.Message) error {
+	var m message
+	if err := json.Unmarshal(msg.Value, &m); err != nil {
+		return fmt.Errorf("%w: %v", errMalformed, err)
+	}
+
+	if m.ID <= 0 || m.Data.ID <= 0 {
+		return fmt.Errorf("%w: missing id", errMalformed)
+	}
+
+	switch m.Type {
+	case eventUserCreated, eventUserUpdated:
+		return c.storage.UpsertUser(m.Data, m.ID)
+	case eventUserLinked:
+		return c.link(ctx, m)
+	case eventUserDeleted:
+		return c.storage.DeleteUser(m.Data.ID, m.ID)
+	default:
+		// Неизвестные типы событий могут появиться в новых версиях AuthService
+		c.log.Debug("skipping unknown user event", slog.String("type", m.Type))
+		return nil
+	}
+}
+
+// link присваивает записи пользователя идентификатор AuthService и возвращает туда ее имена
+func (c *Consumer) link(ctx context.Context, m message) error {
+	linked, err := c.storage.LinkUser(m.Data, m.ID)
+	if err != nil {
+		return err
+	}
+
+	if linked.FirstName == "" && linked.LastName == "" {
+		return nil
+	}
+
+	err = c.profiles.BackfillProfile(ctx, linked.ID, linked.FirstName, linked.LastName)
+	if errors.Is(err, authgrpc.ErrUserNotFound) {
+		// Учетная запись удалена после события, ее удаление придет следующим событием
+		return nil
+	}
+
+	return err
+}
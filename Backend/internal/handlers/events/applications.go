@@ -0,0 +1,131 @@
+package events
+
+import (
+	emailsendergrpc "Backend/internal/clients/emailsender/grpc"
+	"Backend/internal/lib/permissions"
+	"Backend/internal/lib/response"
+	"Backend/internal/storage"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// Решения по заявкам в URL
+const (
+	decisionApprove = "approve"
+	decisionReject  = "reject"
+)
+
+// ReviewApplicationsHandler одобряет или отклоняет заявки нескольких пользователей на событие с одобрением.
+// Решение передается в URL, сообщение организатора добавляется в письма всем пользователям.
+func ReviewApplicationsHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, emailClient *emailsendergrpc.Client, tickets Tickets) http.HandlerFunc {
+	type request struct {
+		UserIDs []int64 `json:"userIds" validate:"required,min=1,max=500,dive,min=1"`
+		Message string  `json:"message" validate:"max=1000"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.ReviewApplications"
+
+		var req request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error(op, "failed to decode request body", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("некорректные данные запроса"))
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error(op, "invalid request", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("ошибка валидации"))
+			return
+		}
+
+		reviewApplications(w, r, log, eventStorage, emailClient, tickets, req.UserIDs, req.Message)
+	}
+}
+
+// ReviewApplicationHandler одобряет или отклоняет заявку одного пользователя на событие с одобрением
+func ReviewApplicationHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, emailClient *emailsendergrpc.Client, tickets Tickets) http.HandlerFunc {
+	type request struct {
+		Message string `json:"message" validate:"max=1000"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.ReviewApplication"
+
+		userID, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		// Сообщение необязательно, поэтому пустое тело допустимо
+		var req request
+		if err := render.DecodeJSON(r.Body, &req); err != nil && !errors.Is(err, io.EOF) {
+			log.Error(op, "failed to decode request body", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("некорректные данные запроса"))
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error(op, "invalid request", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("ошибка валидации"))
+			return
+		}
+
+		reviewApplications(w, r, log, eventStorage, emailClient, tickets, []int64{userID}, req.Message)
+	}
+}
+
+// reviewApplications проверяет права на рассмотрение заявок события из URL, сохраняет решение
+// и рассылает письма о нем
+func reviewApplications(w http.ResponseWriter, r *http.Request, log *slog.Logger, eventStorage EventStorage, emailClient *emailsendergrpc.Client, tickets Tickets, userIDs []int64, message string) {
+	const op = "handlers.events.reviewApplications"
+
+	idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	decision := chi.URLParam(r, "decision")
+	if decision != decisionApprove && decision != decisionReject {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, response.Error("решение должно быть approve или reject"))
+		return
+	}
+
+	if _, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionReviewApplications); !ok {
+		return
+	}
+
+	decisions, err := eventStorage.ReviewApplications(idInt, userIDs, decision == decisionApprove, message)
+	if err != nil {
+		log.Error(op, "failed to review applications", err)
+		switch {
+		case errors.Is(err, storage.ErrApplicationNotFound):
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("заявки на рассмотрении не найдены"))
+		case errors.Is(err, storage.ErrEventNotOpen):
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("заявки рассматриваются только у опубликованного события"))
+		default:
+			render.JSON(w, r, response.Error("не удалось сохранить решение по заявкам"))
+		}
+		return
+	}
+
+	go notifyApplicationDecisions(log, eventStorage, emailClient, tickets, idInt, decisions, message)
+
+	render.JSON(w, r, Response{Response: response.OK(), Decisions: decisions})
+}
@@ -2,26 +2,31 @@ package events
 
 import (
 	emailsendergrpc "Backend/internal/clients/emailsender/grpc"
+	"Backend/internal/lib/permissions"
 	"Backend/internal/lib/response"
+	"Backend/internal/lib/rrule"
+	"Backend/internal/middleware/auth"
+	"Backend/internal/search"
 	"Backend/internal/storage"
-	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
-	"github.com/segmentio/kafka-go"
 )
 
 const (
@@ -30,22 +35,51 @@ const (
 	uploadAvatarDir = "./uploads/avatars"
 	// Максимальный размер файла (10 МБ)
 	maxUploadSize = 10 * 1024 * 1024
+	// Максимальное число результатов полнотекстового поиска
+	maxSearchHits = 1000
 )
 
 type Response struct {
 	response.Response
-	Events      []storage.Event          `json:"events,omitempty"`
-	Event       storage.Event            `json:"event,omitempty"`
-	EventCards  []storage.EventCardProps `json:"eventCards,omitempty"`
-	EventId     int64                    `json:"eventId,omitempty"`
-	UserId      int64                    `json:"userId,omitempty"`
-	ProfileInfo storage.ProfileInfo      `json:"profileInfo,omitempty"`
-	Users       []storage.UserInfo       `json:"users,omitempty"`
+	Events       []storage.Event               `json:"events,omitempty"`
+	Event        storage.Event                 `json:"event,omitempty"`
+	EventCards   []storage.EventCardProps      `json:"eventCards,omitempty"`
+	EventId      int64                         `json:"eventId,omitempty"`
+	UserId       int64                         `json:"userId,omitempty"`
+	ProfileInfo  storage.ProfileInfo           `json:"profileInfo,omitempty"`
+	Users        []storage.UserInfo            `json:"users,omitempty"`
+	Facets       *storage.Facets               `json:"facets,omitempty"`
+	Categories   []storage.Category            `json:"categories,omitempty"`
+	Page         *storage.PageInfo             `json:"page,omitempty"`
+	Venue        *storage.Venue                `json:"venue,omitempty"`
+	Venues       []storage.Venue               `json:"venues,omitempty"`
+	VenueId      int64                         `json:"venueId,omitempty"`
+	Organizers   []storage.Organizer           `json:"organizers,omitempty"`
+	Attendees    []storage.Attendee            `json:"attendees,omitempty"`
+	Invitations  []storage.Invitation          `json:"invitations,omitempty"`
+	InviteLink   *InviteLink                   `json:"inviteLink,omitempty"`
+	Questions    []storage.Question            `json:"questions,omitempty"`
+	Decisions    []storage.ApplicationDecision `json:"decisions,omitempty"`
+	Ticket       *TicketResponse               `json:"ticket,omitempty"`
+	CheckIn      *storage.CheckIn              `json:"checkIn,omitempty"`
+	CheckInStats *storage.CheckInStats         `json:"checkInStats,omitempty"`
+	Snapshot     *SignedSnapshot               `json:"snapshot,omitempty"`
+	Sync         *SyncResponse                 `json:"sync,omitempty"`
+	// SnapshotPublicKey - открытый ключ для проверки выгрузок билетов
+	SnapshotPublicKey string `json:"snapshotPublicKey,omitempty"`
+	// Conflicts предупреждает о пересечении сохраненного события с другими событиями на той же площадке
+	Conflicts []storage.VenueConflict `json:"conflicts,omitempty"`
+	// RegistrationStatus и WaitlistPosition возвращаются при записи на событие
+	RegistrationStatus string `json:"registrationStatus,omitempty"`
+	WaitlistPosition   int    `json:"waitlistPosition,omitempty"`
 }
 
 type RegisterRequest struct {
-	UserID  int `json:"userId"`
 	EventID int `json:"eventId"`
+	// InviteToken - токен из ссылки-приглашения, нужен для записи на событие только по приглашениям
+	InviteToken string `json:"inviteToken"`
+	// Answers - ответы на анкету регистрации события
+	Answers []storage.Answer `json:"answers"`
 }
 
 type CreateRequest struct {
@@ -54,60 +88,37 @@ type CreateRequest struct {
 
 type EventStorage interface {
 	AddEvent(dto storage.EventCreateDto) (int64, error)
-	AddUser(dto storage.CrateUserDto) (int64, error)
-	GetEventsByUser(userId int) ([]storage.Event, error)
+	UpdateUserAvatar(userID int64, imageURL string) error
+	GetEventsByUser(userId int, includeHidden bool, page storage.PageRequest) (storage.EventPage, error)
 	GetUserInfo(userId int) (storage.UserInfo, error)
-	EditEvent(eventId int64, dto storage.EventCreateDto) error
+	EditEvent(eventId int64, dto storage.EventCreateDto, scope string) (storage.EditResult, error)
 	GetEvent(eventId int) (storage.Event, error)
-	DeleteEvent(eventID int) error
-	RegisterUserForEvent(userId int, eventId int) (userEmail string, eventName string, err error)
-	GetFilteredEvents(title, date, address string) ([]storage.Event, error)
+	DeleteEvent(eventID int) ([]int64, error)
+	PublishEvent(eventID int, scope string) error
+	CancelEvent(eventID int, reason string) (storage.EventCancellation, error)
+	RegisterUserForEvent(userId int, eventId int, answers []storage.Answer) (storage.RegistrationResult, error)
+	GetFilteredEvents(filter storage.EventFilter, page storage.PageRequest) (storage.EventPage, error)
+	GetEventFacets(filter storage.EventFilter) (storage.Facets, error)
+	GetCategories() ([]storage.Category, error)
 	GetEventRegisteredUsers(eventId, creatoriId int) ([]storage.UserInfo, error)
-	GetRegisteredEventsByUser(userId int) ([]storage.Event, error)
-	CancelRegistration(eventId, userId int) error
+	GetRegisteredEventsByUser(userId int, includeHidden bool, page storage.PageRequest) (storage.EventPage, error)
+	CancelRegistration(eventId, userId int) (storage.CancelResult, error)
+	GetVenueConflicts(eventID int64) ([]storage.VenueConflict, error)
+	ReminderStorage
+	VenueStorage
+	StaffStorage
+	InvitationStorage
+	QuestionStorage
+	TicketStorage
+	ReviewApplications(eventID int, userIDs []int64, approve bool, message string) ([]storage.ApplicationDecision, error)
 }
 
-type UserCreatedEvent struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-}
-
-var (
-	kafkaTopic  = "user-created"
-	kafkaBroker = "localhost:9092"
-)
-
-func publishUserCreatedEvent(email, password string) error {
-	// Создаем объект события
-	event := UserCreatedEvent{
-		Email:    email,
-		Password: password,
-	}
-
-	// Сериализуем событие в JSON
-	eventData, err := json.Marshal(event)
-	if err != nil {
-		return err
-	}
-
-	// Создаем Kafka writer с фиксированными значениями
-	writer := kafka.NewWriter(kafka.WriterConfig{
-		Brokers:  []string{kafkaBroker},
-		Topic:    kafkaTopic,
-		Balancer: &kafka.LeastBytes{},
-	})
-	defer writer.Close()
-
-	// Отправляем сообщение в Kafka
-	err = writer.WriteMessages(context.Background(), kafka.Message{
-		Value: eventData,
-	})
-	if err != nil {
-		return err
-	}
-
-	log.Printf("UserCreatedEvent отправлено в Kafka: %s", email)
-	return nil
+// Searcher - полнотекстовый поиск по событиям
+type Searcher interface {
+	Search(text string, limit int) ([]search.Hit, error)
+	// Notify сообщает об изменении событий, чтобы индекс обновился без ожидания
+	Notify()
+	Remove(eventID int64) error
 }
 
 func eventValidator(fl validator.FieldLevel) bool {
@@ -132,8 +143,35 @@ func generateRandomFilename(originalFilename string) string {
 	return randomStr + ext
 }
 
+// authorizeEvent загружает событие и проверяет, что автор запроса может выполнить над ним действие.
+// При отказе ответ уже записан и возвращается false.
+func authorizeEvent(w http.ResponseWriter, r *http.Request, log *slog.Logger, eventStorage EventStorage, eventID int, action permissions.Action) (storage.Event, bool) {
+	const op = "handlers.events.authorizeEvent"
+
+	event, err := eventStorage.GetEvent(eventID)
+	if err != nil {
+		log.Error(op, "failed to get event", err)
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, response.Error("событие не найдено"))
+		return storage.Event{}, false
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	if !permissions.CanOnEvent(user, event, action) {
+		forbidden(w, r)
+		return storage.Event{}, false
+	}
+
+	return event, true
+}
+
+func forbidden(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, http.StatusForbidden)
+	render.JSON(w, r, response.ErrorWithCode(response.CodeForbidden, "недостаточно прав"))
+}
+
 // Обработчик для создания события с загрузкой изображения
-func CreateEventHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+func CreateEventHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, searcher Searcher, geocoderClient Geocoder) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.events.CreateEvent"
 
@@ -163,6 +201,10 @@ func CreateEventHandler(log *slog.Logger, eventStorage EventStorage, validate *v
 			return
 		}
 
+		// Создателем события всегда является автор запроса
+		user, _ := auth.UserFromContext(r.Context())
+		eventDto.CreatorUserID = user.ID
+
 		// Проверяем валидность данных события
 		err := validate.Struct(eventDto)
 		if err != nil {
@@ -210,21 +252,44 @@ func CreateEventHandler(log *slog.Logger, eventStorage EventStorage, validate *v
 			eventDto.ImageURL = imageURL
 		}
 
+		locateEvent(r.Context(), log, geocoderClient, &eventDto)
+
 		// Добавляем событие в базу данных
 		id, err := eventStorage.AddEvent(eventDto)
 		if err != nil {
 			log.Error(op, "failed to add event", err)
+			if errors.Is(err, rrule.ErrInvalidRule) || errors.Is(err, storage.ErrNoOccurrences) {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error("некорректное правило повторения"))
+				return
+			}
+			if errors.Is(err, storage.ErrCategoryNotFound) {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error("неизвестная категория"))
+				return
+			}
+			if errors.Is(err, storage.ErrVenueNotFound) {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error("неизвестная площадка"))
+				return
+			}
 			w.WriteHeader(http.StatusBadRequest)
 			render.JSON(w, r, response.Error("Такое событие уже существует"))
 			return
 		}
 
+		searcher.Notify()
+
 		// Возвращаем успешный ответ с ID созданного события
-		render.JSON(w, r, Response{Response: response.OK(), EventId: id})
+		render.JSON(w, r, Response{
+			Response:  response.OK(),
+			EventId:   id,
+			Conflicts: venueConflicts(log, eventStorage, eventDto, id),
+		})
 	}
 }
 
-func UpdateEventHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+func UpdateEventHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, emailClient *emailsendergrpc.Client, tickets Tickets, searcher Searcher, geocoderClient Geocoder) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.events.UpdateEvent"
 
@@ -236,6 +301,10 @@ func UpdateEventHandler(log *slog.Logger, eventStorage EventStorage, validate *v
 			return
 		}
 
+		if _, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionEditEvent); !ok {
+			return
+		}
+
 		// Ограничиваем размер запроса
 		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
 
@@ -309,22 +378,57 @@ func UpdateEventHandler(log *slog.Logger, eventStorage EventStorage, validate *v
 			eventDto.ImageURL = imageURL
 		}
 
+		locateEvent(r.Context(), log, geocoderClient, &eventDto)
+
 		// Добавляем событие в базу данных
-		err = eventStorage.EditEvent(int64(idInt), eventDto)
+		// Для повторения серии scope задает, меняется ли оно одно (this), вместе со следующими (following) или вся серия (all)
+		result, err := eventStorage.EditEvent(int64(idInt), eventDto, r.URL.Query().Get("scope"))
 		if err != nil {
 			log.Error(op, "failed to edit event", err)
-			render.JSON(w, r, response.Error("Ошибка при добавлении события"))
+			switch {
+			case errors.Is(err, storage.ErrEventClosed):
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, response.Error("отмененное или завершенное событие нельзя изменить"))
+			case errors.Is(err, storage.ErrNotRecurring), errors.Is(err, storage.ErrInvalidScope):
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error("некорректная область изменения серии"))
+			case errors.Is(err, rrule.ErrInvalidRule), errors.Is(err, storage.ErrNoOccurrences):
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error("некорректное правило повторения"))
+			case errors.Is(err, storage.ErrCategoryNotFound):
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error("неизвестная категория"))
+			case errors.Is(err, storage.ErrVenueNotFound):
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error("неизвестная площадка"))
+			default:
+				render.JSON(w, r, response.Error("Ошибка при добавлении события"))
+			}
 			return
 		}
 
+		searcher.Notify()
+
+		// При увеличении вместимости места получают пользователи из листа ожидания,
+		// а участники повторений, исключенных из расписания, получают уведомление об отмене
+		go func() {
+			notifyPromoted(log, eventStorage, emailClient, tickets, result.Promoted)
+			for _, cancellation := range result.Cancelled {
+				notifyCancelled(log, emailClient, cancellation)
+			}
+		}()
+
 		// Возвращаем успешный ответ с ID созданного события
-		render.JSON(w, r, Response{Response: response.OK()})
+		render.JSON(w, r, Response{
+			Response:  response.OK(),
+			Conflicts: venueConflicts(log, eventStorage, eventDto, int64(idInt)),
+		})
 	}
 }
 
-func DeleteEventHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+func DeleteEventHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, emailClient *emailsendergrpc.Client, searcher Searcher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "handlers.events.UpdateEvent"
+		const op = "handlers.events.DeleteEvent"
 
 		idStr := chi.URLParam(r, "id")
 
@@ -334,23 +438,122 @@ func DeleteEventHandler(log *slog.Logger, eventStorage EventStorage, validate *v
 			return
 		}
 
-		err = eventStorage.DeleteEvent(idInt)
+		if _, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionDeleteEvent); !ok {
+			return
+		}
+
+		reminderIDs, err := eventStorage.DeleteEvent(idInt)
 		if err != nil {
 			log.Error(op, "failed to delete", err)
 			render.JSON(w, r, response.Error("не удалось удалить событие"))
 			return
 		}
 
+		// Напоминания удаленного события больше не должны отправляться
+		go deleteReminders(log, emailClient, reminderIDs)
+
+		if err := searcher.Remove(int64(idInt)); err != nil {
+			log.Error(op, "failed to remove event from search index", err)
+		}
+
 		render.JSON(w, r, Response{
 			Response: response.OK(),
 		})
 	}
 }
 
-func CancelRegistrationHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+// PublishEventHandler публикует черновик события
+func PublishEventHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.PublishEvent"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionPublishEvent); !ok {
+			return
+		}
+
+		// Повторения серии можно опубликовать вместе со следующими или все сразу
+		if err := eventStorage.PublishEvent(idInt, r.URL.Query().Get("scope")); err != nil {
+			log.Error(op, "failed to publish event", err)
+			switch {
+			case errors.Is(err, storage.ErrInvalidTransition):
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, response.Error("опубликовать можно только черновик"))
+				return
+			case errors.Is(err, storage.ErrNotRecurring), errors.Is(err, storage.ErrInvalidScope):
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error("некорректная область изменения серии"))
+				return
+			}
+			render.JSON(w, r, response.Error("не удалось опубликовать событие"))
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK()})
+	}
+}
+
+// CancelEventHandler отменяет опубликованное событие и уведомляет всех зарегистрированных
+func CancelEventHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, emailClient *emailsendergrpc.Client) http.HandlerFunc {
+	type request struct {
+		Reason string `json:"reason" validate:"max=1000"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.CancelEvent"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		// Причина необязательна, поэтому пустое тело допустимо
+		var req request
+		if err := render.DecodeJSON(r.Body, &req); err != nil && !errors.Is(err, io.EOF) {
+			log.Error(op, "failed to decode request body", err)
+			render.JSON(w, r, response.Error("некорректные данные запроса"))
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error(op, "invalid request", err)
+			render.JSON(w, r, response.Error("ошибка валидации"))
+			return
+		}
+
+		if _, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionCancelEvent); !ok {
+			return
+		}
+
+		cancellation, err := eventStorage.CancelEvent(idInt, req.Reason)
+		if err != nil {
+			log.Error(op, "failed to cancel event", err)
+			if errors.Is(err, storage.ErrInvalidTransition) {
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, response.Error("отменить можно только опубликованное событие"))
+				return
+			}
+			render.JSON(w, r, response.Error("не удалось отменить событие"))
+			return
+		}
+
+		go notifyCancelled(log, emailClient, cancellation)
+
+		render.JSON(w, r, Response{Response: response.OK()})
+	}
+}
+
+func CancelRegistrationHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, emailClient *emailsendergrpc.Client, tickets Tickets) http.HandlerFunc {
 	type request struct {
 		EventID int `json:"event_id" validate:"required,min=1"`
-		UserID  int `json:"user_id" validate:"required,min=1"`
+		// UserID позволяет организатору отменить чужую регистрацию, по умолчанию - автор запроса
+		UserID int `json:"user_id" validate:"omitempty,min=1"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -370,41 +573,144 @@ func CancelRegistrationHandler(log *slog.Logger, eventStorage EventStorage, vali
 			return
 		}
 
-		err := eventStorage.CancelRegistration(req.EventID, req.UserID)
+		user, _ := auth.UserFromContext(r.Context())
+
+		if req.UserID == 0 {
+			req.UserID = int(user.ID)
+		}
+
+		// Отменить чужую регистрацию может только организатор события
+		if req.UserID != int(user.ID) {
+			if _, ok := authorizeEvent(w, r, log, eventStorage, req.EventID, permissions.ActionCancelRegistration); !ok {
+				return
+			}
+		}
+
+		result, err := eventStorage.CancelRegistration(req.EventID, req.UserID)
 		if err != nil {
 			log.Error(op, "failed to cancel registration", err)
+			if errors.Is(err, storage.ErrRegistrationNotFound) || errors.Is(err, storage.ErrEventNotFound) {
+				render.Status(r, http.StatusNotFound)
+			}
 			render.JSON(w, r, response.Error("не удалось отменить регистрацию"))
 			return
 		}
 
+		go func() {
+			deleteReminders(log, emailClient, result.ReminderIDs)
+			notifyPromoted(log, eventStorage, emailClient, tickets, result.Promoted)
+		}()
+
 		render.JSON(w, r, Response{
 			Response: response.OK(),
 		})
 	}
 }
 
-func GetEventsHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+func GetEventsHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, searcher Searcher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.events.GetEvents"
 
 		// Получаем параметры запроса для фильтрации
-		title := r.URL.Query().Get("search")
-		date := r.URL.Query().Get("date")
-		address := r.URL.Query().Get("address")
+		query := r.URL.Query()
+		filter := storage.EventFilter{
+			Address:    query.Get("address"),
+			Categories: listParam(query, "category"),
+			Tags:       listParam(query, "tags"),
+		}
+
+		switch query.Get("tagMatch") {
+		case "", "any":
+		case "all":
+			filter.MatchAllTags = true
+		default:
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("tagMatch должен быть any или all"))
+			return
+		}
+
+		if err := parseTimeFilter(query, &filter, time.Now()); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		if err := parseGeoFilter(query, &filter); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		if venue := query.Get("venue"); venue != "" {
+			venueID, err := strconv.ParseInt(venue, 10, 64)
+			if err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error("venue должен быть идентификатором площадки"))
+				return
+			}
+			filter.VenueID = &venueID
+		}
+
+		// По умолчанию при поиске по тексту сначала показываются самые релевантные события,
+		// при поиске рядом - ближайшие, иначе - ближайшие по дате
+		text := strings.TrimSpace(query.Get("search"))
+		sorts := []string{storage.SortDate, storage.SortPopularity, storage.SortCreated}
+		if filter.Near != nil {
+			sorts = append([]string{storage.SortDistance}, sorts...)
+		}
+		if text != "" {
+			sorts = append([]string{storage.SortRelevance}, sorts...)
+		}
+
+		page, err := pageParams(query, sorts...)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		var hits map[int64]search.Hit
+		if text != "" {
+			found, err := searcher.Search(text, maxSearchHits)
+			switch {
+			case errors.Is(err, search.ErrNotReady):
+				// Пока индекс загружается, ищем по вхождению в название
+				filter.Title = text
+			case err != nil:
+				log.Error(op, "failed to search events", err)
+				render.JSON(w, r, response.Error("failed to get events"))
+				return
+			default:
+				// Порядок IDs задает сортировку по релевантности
+				hits = make(map[int64]search.Hit, len(found))
+				filter.IDs = make([]int64, 0, len(found))
+				for _, hit := range found {
+					hits[hit.EventID] = hit
+					filter.IDs = append(filter.IDs, hit.EventID)
+				}
+			}
+		}
 
-		// Получаем отфильтрованные мероприятия
-		events, err := eventStorage.GetFilteredEvents(title, date, address)
+		// Получаем страницу отфильтрованных мероприятий
+		result, err := eventStorage.GetFilteredEvents(filter, page)
 		if err != nil {
 			log.Error(op, "failed to get filtered events", err)
+			renderPageError(w, r, err, "failed to get events")
+			return
+		}
+
+		facets, err := eventStorage.GetEventFacets(filter)
+		if err != nil {
+			log.Error(op, "failed to get event facets", err)
 			render.JSON(w, r, response.Error("failed to get events"))
 			return
 		}
 
 		// Преобразуем события в формат EventCardProps
-		eventCards := make([]storage.EventCardProps, 0, len(events))
-		for _, event := range events {
-			// Преобразуем дату в нужный формат
-			dateStr := event.EventDate.Format("2006-01-02")
+		eventCards := make([]storage.EventCardProps, 0, len(result.Events))
+		for _, event := range result.Events {
+			// Дата отдается в RFC 3339 в часовом поясе события
+			dateStr := event.EventDate.Format(time.RFC3339)
 
 			// Здесь предполагается, что адрес находится в поле Description
 			// Если у вас есть отдельное поле для адреса, используйте его
@@ -414,16 +720,89 @@ func GetEventsHandler(log *slog.Logger, eventStorage EventStorage, validate *val
 				ID:         event.EventID,
 				Name:       event.Title,
 				Date:       dateStr,
+				TimeZone:   event.TimeZone,
 				Address:    address,
 				UsersCount: event.UsersCount,
+				Capacity:   event.Capacity,
+				Status:     event.Status,
+				Category:   event.Category,
+				Tags:       event.Tags,
+				Highlights: hits[event.EventID].Highlights,
+				Location:   event.Location,
+				DistanceKm: event.DistanceKm,
+				Venue:      event.Venue,
 			})
 		}
 
-		render.JSON(w, r, Response{Response: response.OK(), EventCards: eventCards})
+		render.JSON(w, r, Response{Response: response.OK(), EventCards: eventCards, Facets: &facets, Page: &result.PageInfo})
+	}
+}
+
+// GetCategoriesHandler возвращает справочник категорий для форм и фильтров
+func GetCategoriesHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.GetCategories"
+
+		categories, err := eventStorage.GetCategories()
+		if err != nil {
+			log.Error(op, "failed to get categories", err)
+			render.JSON(w, r, response.Error("не удалось получить категории"))
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK(), Categories: categories})
+	}
+}
+
+// pageParams читает параметры постраничной выдачи sort, order, limit и cursor.
+// sorts - допустимые сортировки, первая из них используется по умолчанию.
+func pageParams(query url.Values, sorts ...string) (storage.PageRequest, error) {
+	page := storage.PageRequest{
+		Sort:   query.Get("sort"),
+		Order:  query.Get("order"),
+		Limit:  storage.DefaultPageLimit,
+		Cursor: query.Get("cursor"),
+	}
+
+	if page.Sort == "" {
+		page.Sort = sorts[0]
+	} else if !slices.Contains(sorts, page.Sort) {
+		return storage.PageRequest{}, fmt.Errorf("sort должен быть одним из: %s", strings.Join(sorts, ", "))
+	}
+
+	switch page.Order {
+	case "":
+		page.Order = storage.DefaultOrder(page.Sort)
+	case storage.OrderAsc, storage.OrderDesc:
+	default:
+		return storage.PageRequest{}, errors.New("order должен быть asc или desc")
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > storage.MaxPageLimit {
+			return storage.PageRequest{}, fmt.Errorf("limit должен быть от 1 до %d", storage.MaxPageLimit)
+		}
+		page.Limit = n
+	}
+
+	return page, nil
+}
+
+// listParam читает параметр со списком значений, переданных через запятую или повторением параметра
+func listParam(query url.Values, name string) []string {
+	var values []string
+	for _, param := range query[name] {
+		for _, value := range strings.Split(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
 	}
+	return values
 }
 
-func RegisterUserForEventHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, emailClient *emailsendergrpc.Client) http.HandlerFunc {
+func RegisterUserForEventHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, emailClient *emailsendergrpc.Client, invites Invites, tickets Tickets) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.events.RegisterUserForEvent"
 
@@ -438,72 +817,96 @@ func RegisterUserForEventHandler(log *slog.Logger, eventStorage EventStorage, va
 			return
 		}
 
-		// Проверяем, что userId и eventId присутствуют
-		if req.UserID == 0 || req.EventID == 0 {
-			log.Error(op, "userId or eventId is missing in the request", nil)
-			render.JSON(w, r, response.Error("userId и eventId должны быть указаны"))
+		// Проверяем, что eventId присутствует
+		if req.EventID == 0 {
+			log.Error(op, "eventId is missing in the request", nil)
+			render.JSON(w, r, response.Error("eventId должен быть указан"))
 			return
 		}
 
-		// Вызываем метод RegisterUserForEvent для регистрации пользователя
-		userEmail, eventName, err := eventStorage.RegisterUserForEvent(req.UserID, req.EventID)
-		if err != nil {
-			log.Error(op, "failed to register user for event", err)
-			render.JSON(w, r, response.Error("не удалось зарегистрировать пользователя на мероприятие"))
+		registerForEvent(w, r, log, eventStorage, emailClient, invites, tickets, req.EventID, req.InviteToken, req.Answers)
+	}
+}
+
+// registerForEvent записывает автора запроса на событие. На событие только по приглашениям
+// можно записаться по действующей ссылке-приглашению или приглашению на email пользователя.
+// Ответы на анкету регистрации проверяются по вопросам события.
+func registerForEvent(w http.ResponseWriter, r *http.Request, log *slog.Logger, eventStorage EventStorage, emailClient *emailsendergrpc.Client, invites Invites, tickets Tickets, eventID int, inviteToken string, answers []storage.Answer) {
+	const op = "handlers.events.registerForEvent"
+
+	event, err := eventStorage.GetEvent(eventID)
+	if err != nil {
+		log.Error(op, "failed to get event", err)
+		if errors.Is(err, storage.ErrEventNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("событие не найдено"))
 			return
 		}
+		render.JSON(w, r, response.Error("не удалось зарегистрировать пользователя на мероприятие"))
+		return
+	}
 
-		if emailClient != nil {
-			// Создаем контекст с таймаутом для запроса
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
+	allowed, err := canViewEvent(r, eventStorage, invites, event, inviteToken)
+	if err != nil {
+		log.Error(op, "failed to check event access", err)
+		render.JSON(w, r, response.Error("не удалось зарегистрировать пользователя на мероприятие"))
+		return
+	}
+	if !allowed {
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, response.ErrorWithCode(response.CodeForbidden, "запись на событие только по приглашениям"))
+		return
+	}
 
-			eventTime := time.Now().Add(2*time.Hour + 3*time.Minute).Unix()
+	answers, err = checkAnswers(event.Questions, answers)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
 
-			notificationIDs, err := emailClient.CreateNotification(ctx, userEmail, eventName, eventTime)
-			if err != nil {
-				log.Error("Ошибка при создании уведомления", slog.Any("error", err))
-				os.Exit(1)
-			}
-			log.Info("Уведомления успешно созданы", slog.Any("notification_ids", notificationIDs))
+	// Регистрируем на мероприятие автора запроса
+	user, _ := auth.UserFromContext(r.Context())
 
+	result, err := eventStorage.RegisterUserForEvent(int(user.ID), eventID, answers)
+	if err != nil {
+		log.Error(op, "failed to register user for event", err)
+		switch {
+		case errors.Is(err, storage.ErrAlreadyRegistered):
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("пользователь уже зарегистрирован на мероприятие"))
+		case errors.Is(err, storage.ErrEventNotFound):
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("событие не найдено"))
+		case errors.Is(err, storage.ErrEventNotOpen):
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("регистрация на событие закрыта"))
+		default:
+			render.JSON(w, r, response.Error("не удалось зарегистрировать пользователя на мероприятие"))
 		}
+		return
+	}
 
-		// Возвращаем успешный ответ с ID регистрации
-		render.JSON(w, r, Response{Response: response.OK()})
+	// Билет и напоминания получают только участники с подтвержденным местом
+	if result.Status == storage.RegistrationConfirmed {
+		go func() {
+			notifyRegistered(log, emailClient, tickets, int64(eventID), user.ID, result)
+			scheduleReminders(log, eventStorage, emailClient, user.ID, eventID, result.UserEmail, result.EventName, result.EventDate)
+		}()
 	}
+
+	render.JSON(w, r, Response{
+		Response:           response.OK(),
+		RegistrationStatus: result.Status,
+		WaitlistPosition:   result.Position,
+	})
 }
 
-// func AddUserHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
-// 	return func(w http.ResponseWriter, r *http.Request) {
-// 		const op = "handlers.events.AddUser"
-
-// 		// Ограничиваем размер тела запроса
-// 		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
-
-// 		// Декодируем JSON из тела запроса
-// 		var req storage.CrateUserDto
-// 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-// 			log.Error(op, "failed to decode request body", err)
-// 			render.JSON(w, r, response.Error("Некорректный формат данных"))
-// 			return
-// 		}
-
-// 		// Вызываем метод RegisterUserForEvent для регистрации пользователя
-// 		id, err := eventStorage.AddUser(req)
-// 		if err != nil {
-// 			log.Error(op, "failed to register user for event", err)
-// 			render.JSON(w, r, response.Error("не удалось зарегистрировать пользователя на мероприятие"))
-// 			return
-// 		}
-
-// 		render.JSON(w, r, Response{Response: response.OK(), UserId: id})
-// 	}
-// }
-
-func AddUserHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+// UpdateAvatarHandler сохраняет аватар автора запроса. Остальные данные пользователя
+// принадлежат AuthService и приходят через события.
+func UpdateAvatarHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "handlers.events.AddUser"
+		const op = "handlers.events.UpdateAvatar"
 
 		// Ограничиваем размер тела запроса
 		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
@@ -515,63 +918,54 @@ func AddUserHandler(log *slog.Logger, eventStorage EventStorage, validate *valid
 			return
 		}
 
-		// Получаем JSON-данные пользователя
-		var req storage.CrateUserDto
-		if err := json.Unmarshal([]byte(r.FormValue("user")), &req); err != nil {
-			log.Error(op, "failed to decode user JSON", err)
-			render.JSON(w, r, response.Error("Некорректный формат данных"))
+		file, fileHeader, err := r.FormFile("image")
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("Изображение не найдено"))
 			return
 		}
+		defer file.Close()
 
-		// Проверяем, есть ли файл изображения
-		file, fileHeader, err := r.FormFile("image")
-		if err == nil { // Если файл есть, обрабатываем его
-			defer file.Close()
-
-			// Создаем директорию для загрузки, если она не существует
-			if err := os.MkdirAll(uploadAvatarDir, os.ModePerm); err != nil {
-				log.Error(op, "failed to create upload directory", err)
-				render.JSON(w, r, response.Error("Ошибка сервера при сохранении файла"))
-				return
-			}
+		// Создаем директорию для загрузки, если она не существует
+		if err := os.MkdirAll(uploadAvatarDir, os.ModePerm); err != nil {
+			log.Error(op, "failed to create upload directory", err)
+			render.JSON(w, r, response.Error("Ошибка сервера при сохранении файла"))
+			return
+		}
 
-			// Генерируем уникальное имя файла
-			filename := generateRandomFilename(fileHeader.Filename)
+		// Генерируем уникальное имя файла
+		filename := generateRandomFilename(fileHeader.Filename)
 
-			// Полный путь к файлу
-			filePath := filepath.Join(uploadAvatarDir, filename)
+		// Полный путь к файлу
+		filePath := filepath.Join(uploadAvatarDir, filename)
 
-			// Создаем файл
-			dst, err := os.Create(filePath)
-			if err != nil {
-				log.Error(op, "failed to create destination file", err)
-				render.JSON(w, r, response.Error("Ошибка при создании файла"))
-				return
-			}
-			defer dst.Close()
-
-			// Копируем содержимое загруженного файла
-			if _, err := io.Copy(dst, file); err != nil {
-				log.Error(op, "failed to copy file content", err)
-				render.JSON(w, r, response.Error("Ошибка при сохранении файла"))
-				return
-			}
+		// Создаем файл
+		dst, err := os.Create(filePath)
+		if err != nil {
+			log.Error(op, "failed to create destination file", err)
+			render.JSON(w, r, response.Error("Ошибка при создании файла"))
+			return
+		}
+		defer dst.Close()
 
-			// Формируем URL для доступа к изображению и сохраняем его в DTO
-			imageURL := fmt.Sprintf("/uploads/avatars/%s", filename)
-			req.ImageURL = imageURL
+		// Копируем содержимое загруженного файла
+		if _, err := io.Copy(dst, file); err != nil {
+			log.Error(op, "failed to copy file content", err)
+			render.JSON(w, r, response.Error("Ошибка при сохранении файла"))
+			return
 		}
 
-		// Регистрируем пользователя
-		id, err := eventStorage.AddUser(req)
-		if err != nil {
-			log.Error(op, "failed to register user", err)
-			w.WriteHeader(http.StatusBadRequest)
-			render.JSON(w, r, response.Error("Пользователь с такой почтой уже зарегистрирован"))
+		user, _ := auth.UserFromContext(r.Context())
+
+		// Профиль появляется после события из AuthService, поэтому сразу после регистрации его может еще не быть
+		if err := eventStorage.UpdateUserAvatar(user.ID, fmt.Sprintf("/uploads/avatars/%s", filename)); err != nil {
+			log.Error(op, "failed to update avatar", err)
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("профиль пользователя еще не создан, повторите позже"))
 			return
 		}
 
-		render.JSON(w, r, Response{Response: response.OK(), UserId: id})
+		render.JSON(w, r, Response{Response: response.OK(), UserId: user.ID})
 	}
 }
 
@@ -594,14 +988,17 @@ func GetProfileInfoHandler(log *slog.Logger, eventStorage EventStorage, validate
 			return
 		}
 
-		events, err := eventStorage.GetEventsByUser(idInt)
+		// Профиль содержит первые страницы списков, следующие запрашиваются через /profile/{id}/events и /profile/{id}/registrations
+		// Черновики и непубличные события видны только самому пользователю
+		viewer, _ := auth.UserFromContext(r.Context())
+		events, err := eventStorage.GetEventsByUser(idInt, viewer.ID == int64(idInt), storage.PageRequest{})
 		if err != nil {
 			log.Error(op, "failed to get user events", err)
 			render.JSON(w, r, response.Error("не удалось события пользователя"))
 			return
 		}
 
-		registeredEvents, err := eventStorage.GetRegisteredEventsByUser(idInt)
+		registeredEvents, err := eventStorage.GetRegisteredEventsByUser(idInt, viewer.ID == int64(idInt), storage.PageRequest{})
 		if err != nil {
 			log.Error(op, "failed to get user registered events", err)
 			render.JSON(w, r, response.Error("не удалось события на которые зарегестрирован пользователь"))
@@ -609,14 +1006,92 @@ func GetProfileInfoHandler(log *slog.Logger, eventStorage EventStorage, validate
 		}
 
 		render.JSON(w, r, Response{
-			Response:    response.OK(),
-			ProfileInfo: storage.ProfileInfo{User: userInfo, Events: events, RegisteredEvents: registeredEvents},
+			Response: response.OK(),
+			ProfileInfo: storage.ProfileInfo{
+				User:                 userInfo,
+				Events:               events.Events,
+				EventsPage:           events.PageInfo,
+				RegisteredEvents:     registeredEvents.Events,
+				RegisteredEventsPage: registeredEvents.PageInfo,
+			},
 		})
 
 	}
 }
 
-func GetEventPageHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+// GetUserEventsHandler возвращает страницу событий, созданных пользователем
+func GetUserEventsHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.GetUserEvents"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		page, err := pageParams(r.URL.Query(), storage.SortDate, storage.SortPopularity, storage.SortCreated)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		// Черновики и непубличные события видны только их автору
+		viewer, _ := auth.UserFromContext(r.Context())
+		result, err := eventStorage.GetEventsByUser(idInt, viewer.ID == int64(idInt), page)
+		if err != nil {
+			log.Error(op, "failed to get user events", err)
+			renderPageError(w, r, err, "не удалось получить события пользователя")
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK(), Events: result.Events, Page: &result.PageInfo})
+	}
+}
+
+// GetUserRegistrationsHandler возвращает страницу событий, на которые записан пользователь
+func GetUserRegistrationsHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.GetUserRegistrations"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		page, err := pageParams(r.URL.Query(), storage.SortDate, storage.SortPopularity, storage.SortCreated)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		// Непубличные события видны только самому пользователю
+		viewer, _ := auth.UserFromContext(r.Context())
+		result, err := eventStorage.GetRegisteredEventsByUser(idInt, viewer.ID == int64(idInt), page)
+		if err != nil {
+			log.Error(op, "failed to get user registered events", err)
+			renderPageError(w, r, err, "не удалось получить события, на которые записан пользователь")
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK(), Events: result.Events, Page: &result.PageInfo})
+	}
+}
+
+// renderPageError отвечает 400 на курсор, не подходящий к запросу, и message на остальные ошибки
+func renderPageError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("некорректный курсор страницы"))
+		return
+	}
+	render.JSON(w, r, response.Error(message))
+}
+
+func GetEventPageHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, invites Invites) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.events.GetEventPageHandler"
 
@@ -635,6 +1110,27 @@ func GetEventPageHandler(log *slog.Logger, eventStorage EventStorage, validate *
 			return
 		}
 
+		// Черновик для всех, кроме организаторов, выглядит как несуществующее событие
+		user, _ := auth.UserFromContext(r.Context())
+		if event.Status == storage.EventDraft && !permissions.CanOnEvent(user, event, permissions.ActionEditEvent) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("событие не найдено"))
+			return
+		}
+
+		// Событие только по приглашениям без доступа к нему тоже скрыто
+		allowed, err := canViewEvent(r, eventStorage, invites, event, r.URL.Query().Get("invite"))
+		if err != nil {
+			log.Error(op, "failed to check event access", err)
+			render.JSON(w, r, response.Error("не удалось получить информацию о событии"))
+			return
+		}
+		if !allowed {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("событие не найдено"))
+			return
+		}
+
 		users, err := eventStorage.GetEventRegisteredUsers(idInt, (int)(event.CreatorUserID))
 		if err != nil {
 			log.Error(op, "failed to get reg users", err)
@@ -642,6 +1138,13 @@ func GetEventPageHandler(log *slog.Logger, eventStorage EventStorage, validate *
 			return
 		}
 
+		// Почты участников видны только тем, кому доступен список участников
+		if !permissions.CanOnEvent(user, event, permissions.ActionViewAttendees) {
+			for i := range users {
+				users[i].Email = ""
+			}
+		}
+
 		render.JSON(w, r, Response{
 			Response: response.OK(),
 			Event:    event,
@@ -651,17 +1154,55 @@ func GetEventPageHandler(log *slog.Logger, eventStorage EventStorage, validate *
 	}
 }
 
-func Init(router *chi.Mux, log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, emailClient *emailsendergrpc.Client) {
-	router.Post("/event", CreateEventHandler(log, eventStorage, validate))
-	router.Get("/events", GetEventsHandler(log, eventStorage, validate))
-	router.Get("/event/{id}", GetEventPageHandler(log, eventStorage, validate))
-	router.Put("/event/{id}", UpdateEventHandler(log, eventStorage, validate))
-	router.Delete("/event/{id}", DeleteEventHandler(log, eventStorage, validate))
-	router.Delete("/registration", CancelRegistrationHandler(log, eventStorage, validate))
-	router.Post("/participate", RegisterUserForEventHandler(log, eventStorage, validate, emailClient))
-	router.Post("/register", AddUserHandler(log, eventStorage, validate))
+func Init(router *chi.Mux, log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, emailClient *emailsendergrpc.Client, searcher Searcher, geocoderClient Geocoder, invites Invites, tickets Tickets) {
+	router.Get("/events", GetEventsHandler(log, eventStorage, validate, searcher))
+	router.Get("/venues", GetVenuesHandler(log, eventStorage, validate))
+	router.Get("/venue/{id}", GetVenueHandler(log, eventStorage, validate))
+	router.Get("/categories", GetCategoriesHandler(log, eventStorage, validate))
+	router.Get("/checkin/public-key", GetSnapshotPublicKeyHandler(log, tickets))
+	router.Get("/event/{id}", GetEventPageHandler(log, eventStorage, validate, invites))
 	router.Get("/profile/{id}", GetProfileInfoHandler(log, eventStorage, validate))
+	router.Get("/profile/{id}/events", GetUserEventsHandler(log, eventStorage, validate))
+	router.Get("/profile/{id}/registrations", GetUserRegistrationsHandler(log, eventStorage, validate))
+
+	// Изменяющие запросы доступны только аутентифицированным пользователям
+	router.Group(func(r chi.Router) {
+		r.Use(auth.Required)
+
+		r.With(auth.VerifiedEmail).Post("/event", CreateEventHandler(log, eventStorage, validate, searcher, geocoderClient))
+		r.Put("/event/{id}", UpdateEventHandler(log, eventStorage, validate, emailClient, tickets, searcher, geocoderClient))
+		r.Delete("/event/{id}", DeleteEventHandler(log, eventStorage, validate, emailClient, searcher))
+		r.Post("/event/{id}/publish", PublishEventHandler(log, eventStorage, validate))
+		r.Post("/event/{id}/cancel", CancelEventHandler(log, eventStorage, validate, emailClient))
+		r.Put("/event/{id}/questions", SetEventQuestionsHandler(log, eventStorage, validate))
+		r.Post("/event/{id}/applications/{decision}", ReviewApplicationsHandler(log, eventStorage, validate, emailClient, tickets))
+		r.Post("/event/{id}/applications/{userId}/{decision}", ReviewApplicationHandler(log, eventStorage, validate, emailClient, tickets))
+		r.Get("/event/{id}/attendees", GetEventAttendeesHandler(log, eventStorage, validate))
+		r.Get("/event/{id}/ticket", GetTicketHandler(log, eventStorage, validate, tickets))
+		r.Get("/event/{id}/ticket/qr", GetTicketQRHandler(log, eventStorage, validate, tickets))
+		r.Post("/event/{id}/checkin", CheckInHandler(log, eventStorage, validate, tickets))
+		r.Get("/event/{id}/checkin/stats", GetCheckInStatsHandler(log, eventStorage, validate))
+		r.Get("/event/{id}/checkin/snapshot", GetCheckInSnapshotHandler(log, eventStorage, validate, tickets))
+		r.Post("/event/{id}/checkin/sync", SyncCheckInsHandler(log, eventStorage, validate, tickets))
+		r.Post("/event/{id}/staff", AddEventStaffHandler(log, eventStorage, validate, emailClient))
+		r.Post("/event/{id}/staff/accept", AcceptEventStaffHandler(log, eventStorage, validate))
+		r.Delete("/event/{id}/staff/{userId}", RemoveEventStaffHandler(log, eventStorage, validate))
+		r.Post("/event/{id}/transfer", TransferOwnershipHandler(log, eventStorage, validate, emailClient))
+		r.Post("/event/{id}/invite-link", CreateInviteLinkHandler(log, eventStorage, validate, invites))
+		r.Delete("/event/{id}/invite-link", RevokeInviteLinksHandler(log, eventStorage, validate))
+		r.Get("/event/{id}/invitations", GetInvitationsHandler(log, eventStorage, validate))
+		r.Post("/event/{id}/invitations", InviteByEmailHandler(log, eventStorage, validate, emailClient, invites))
+		r.Delete("/event/{id}/invitations", RemoveInvitationHandler(log, eventStorage, validate))
+		r.Post("/event/{id}/rsvp", RSVPHandler(log, eventStorage, validate, emailClient, invites, tickets))
+		r.Delete("/registration", CancelRegistrationHandler(log, eventStorage, validate, emailClient, tickets))
+		r.Post("/participate", RegisterUserForEventHandler(log, eventStorage, validate, emailClient, invites, tickets))
+		r.Put("/profile/avatar", UpdateAvatarHandler(log, eventStorage, validate))
+		r.With(auth.VerifiedEmail).Post("/venue", CreateVenueHandler(log, eventStorage, validate, geocoderClient))
+		r.Put("/venue/{id}", UpdateVenueHandler(log, eventStorage, validate, geocoderClient))
+		r.Delete("/venue/{id}", DeleteVenueHandler(log, eventStorage, validate))
+	})
 
 	router.Handle("/uploads/images/*", http.StripPrefix("/uploads/images/", http.FileServer(http.Dir("./uploads/images"))))
 	router.Handle("/uploads/avatars/*", http.StripPrefix("/uploads/avatars/", http.FileServer(http.Dir("./uploads/avatars"))))
+	router.Handle("/uploads/venues/*", http.StripPrefix("/uploads/venues/", http.FileServer(http.Dir("./uploads/venues"))))
 }
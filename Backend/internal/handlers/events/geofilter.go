@@ -0,0 +1,92 @@
+package events
+
+import (
+	"Backend/internal/clients/geocoder"
+	"Backend/internal/lib/geo"
+	"Backend/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultRadiusKm - радиус поиска рядом, если radius не указан
+	defaultRadiusKm = 10
+	maxRadiusKm     = 500
+	// geocodeTimeout ограничивает ожидание геокодера при сохранении события
+	geocodeTimeout = 5 * time.Second
+)
+
+// Geocoder определяет координаты по адресу
+type Geocoder interface {
+	Geocode(ctx context.Context, address string) (geo.Point, error)
+}
+
+// parseGeoFilter заполняет фильтр по параметрам near=lat,lng, radius (км) и bbox=west,south,east,north
+func parseGeoFilter(query url.Values, filter *storage.EventFilter) error {
+	if near := query.Get("near"); near != "" {
+		point, err := geo.ParsePoint(near)
+		if err != nil {
+			return errors.New("near должен быть в формате lat,lng")
+		}
+		filter.Near = &point
+		filter.RadiusKm = defaultRadiusKm
+
+		if radius := query.Get("radius"); radius != "" {
+			km, err := strconv.ParseFloat(radius, 64)
+			if err != nil || km <= 0 || km > maxRadiusKm {
+				return fmt.Errorf("radius должен быть от 0 до %d км", maxRadiusKm)
+			}
+			filter.RadiusKm = km
+		}
+	} else if query.Get("radius") != "" {
+		return errors.New("radius используется только вместе с near")
+	}
+
+	if bbox := query.Get("bbox"); bbox != "" {
+		box, err := geo.ParseBBox(bbox)
+		if err != nil {
+			return errors.New("bbox должен быть в формате west,south,east,north")
+		}
+		filter.BBox = &box
+	}
+
+	return nil
+}
+
+// locateEvent определяет координаты события по адресу, если они не переданы явно и событие
+// не привязано к площадке, из которой они берутся при сохранении.
+// Ненайденный адрес не мешает сохранить событие, оно просто не попадет в поиск рядом и на карту.
+func locateEvent(ctx context.Context, log *slog.Logger, geocoderClient Geocoder, dto *storage.EventCreateDto) {
+	if dto.Location != nil || dto.VenueID != nil {
+		return
+	}
+
+	dto.Location = geocode(ctx, log, geocoderClient, dto.EventAddress)
+}
+
+// geocode возвращает координаты адреса или nil, если их не удалось определить
+func geocode(ctx context.Context, log *slog.Logger, geocoderClient Geocoder, address string) *geo.Point {
+	const op = "handlers.events.geocode"
+
+	if address == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, geocodeTimeout)
+	defer cancel()
+
+	point, err := geocoderClient.Geocode(ctx, address)
+	if err != nil {
+		if !errors.Is(err, geocoder.ErrNotFound) {
+			log.Error(op, "failed to geocode address", err)
+		}
+		return nil
+	}
+
+	return &point
+}
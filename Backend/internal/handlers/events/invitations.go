@@ -0,0 +1,367 @@
+package events
+
+import (
+	emailsendergrpc "Backend/internal/clients/emailsender/grpc"
+	"Backend/internal/lib/invite"
+	"Backend/internal/lib/permissions"
+	"Backend/internal/lib/response"
+	"Backend/internal/middleware/auth"
+	"Backend/internal/storage"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// maxInvitationsPerRequest ограничивает число адресов в одном запросе приглашений
+const maxInvitationsPerRequest = 100
+
+// Invites - настройки ссылок-приглашений
+type Invites struct {
+	Signer *invite.Signer
+	// TTL - срок действия ссылки по умолчанию, MaxTTL - наибольший допустимый
+	TTL    time.Duration
+	MaxTTL time.Duration
+	// FrontendURL - адрес фронтенда, ссылка ведет на страницу события в нем
+	FrontendURL string
+}
+
+// link возвращает ссылку на страницу события с токеном приглашения
+func (i Invites) link(eventID int64, token string) string {
+	return fmt.Sprintf("%s/event/%d?invite=%s", strings.TrimSuffix(i.FrontendURL, "/"), eventID, url.QueryEscape(token))
+}
+
+// InviteLink - выданная ссылка-приглашение
+type InviteLink struct {
+	URL       string    `json:"url"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// InvitationStorage хранит приглашения на события
+type InvitationStorage interface {
+	AddInvitations(eventID int, invitedBy int64, emails []string) ([]string, error)
+	GetInvitations(eventID int) ([]storage.Invitation, error)
+	RemoveInvitation(eventID int, email string) error
+	DeclineInvitation(eventID int, userID int64) error
+	HasEventAccess(eventID int, userID int64) (bool, error)
+	RotateInviteLinks(eventID int) error
+}
+
+// canViewEvent проверяет, может ли автор запроса видеть событие и записаться на него.
+// Событие только по приглашениям доступно команде события, владельцу действующей ссылки-приглашения,
+// приглашенным по email и уже записавшимся. Черновики проверяются отдельно.
+func canViewEvent(r *http.Request, eventStorage EventStorage, invites Invites, event storage.Event, token string) (bool, error) {
+	if event.Visibility != storage.VisibilityInviteOnly {
+		return true, nil
+	}
+
+	user, authenticated := auth.UserFromContext(r.Context())
+	if permissions.CanOnEvent(user, event, permissions.ActionViewAttendees) {
+		return true, nil
+	}
+
+	if token != "" && invites.Signer.Verify(token, event.EventID, event.InviteVersion, time.Now()) == nil {
+		return true, nil
+	}
+
+	if !authenticated {
+		return false, nil
+	}
+
+	return eventStorage.HasEventAccess(int(event.EventID), user.ID)
+}
+
+// CreateInviteLinkHandler выдает подписанную ссылку-приглашение на событие с ограниченным сроком действия
+func CreateInviteLinkHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, invites Invites) http.HandlerFunc {
+	type request struct {
+		// TTLHours - срок действия ссылки в часах, по умолчанию из настроек
+		TTLHours int `json:"ttlHours" validate:"omitempty,min=1"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.CreateInviteLink"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		// Срок действия необязателен, поэтому пустое тело допустимо
+		var req request
+		if err := render.DecodeJSON(r.Body, &req); err != nil && !errors.Is(err, io.EOF) {
+			log.Error(op, "failed to decode request body", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("некорректные данные запроса"))
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error(op, "invalid request", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("ошибка валидации"))
+			return
+		}
+
+		// Часы сравниваются до перевода в Duration, иначе большое значение переполнит его
+		tooLong := float64(req.TTLHours) > invites.MaxTTL.Hours()
+		ttl := invites.TTL
+		if req.TTLHours > 0 && !tooLong {
+			ttl = time.Duration(req.TTLHours) * time.Hour
+		}
+		if tooLong || ttl > invites.MaxTTL {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(fmt.Sprintf("ссылка может действовать не дольше %d ч", int(invites.MaxTTL.Hours()))))
+			return
+		}
+
+		event, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionInvite)
+		if !ok {
+			return
+		}
+
+		expires := time.Now().Add(ttl).Truncate(time.Second)
+		token := invites.Signer.Sign(event.EventID, event.InviteVersion, expires)
+
+		render.JSON(w, r, Response{
+			Response:   response.OK(),
+			InviteLink: &InviteLink{URL: invites.link(event.EventID, token), Token: token, ExpiresAt: expires},
+		})
+	}
+}
+
+// RevokeInviteLinksHandler отзывает все выданные ссылки-приглашения на событие.
+// Приглашения по email продолжают действовать.
+func RevokeInviteLinksHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.RevokeInviteLinks"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionInvite); !ok {
+			return
+		}
+
+		if err := eventStorage.RotateInviteLinks(idInt); err != nil {
+			log.Error(op, "failed to revoke invite links", err)
+			render.JSON(w, r, response.Error("не удалось отозвать ссылки-приглашения"))
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK()})
+	}
+}
+
+// InviteByEmailHandler приглашает пользователей по email и отправляет им письма со ссылкой на событие
+func InviteByEmailHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, emailClient *emailsendergrpc.Client, invites Invites) http.HandlerFunc {
+	type request struct {
+		Emails []string `json:"emails" validate:"required,min=1,max=100,dive,required,email"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.InviteByEmail"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		var req request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error(op, "failed to decode request body", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("некорректные данные запроса"))
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error(op, "invalid request", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(fmt.Sprintf("укажите от 1 до %d корректных email", maxInvitationsPerRequest)))
+			return
+		}
+
+		event, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionInvite)
+		if !ok {
+			return
+		}
+
+		// Адреса сравниваются без учета регистра, как и при входе
+		emails := make([]string, 0, len(req.Emails))
+		for _, email := range req.Emails {
+			emails = append(emails, strings.ToLower(strings.TrimSpace(email)))
+		}
+
+		user, _ := auth.UserFromContext(r.Context())
+		added, err := eventStorage.AddInvitations(idInt, user.ID, emails)
+		if err != nil {
+			log.Error(op, "failed to add invitations", err)
+			render.JSON(w, r, response.Error("не удалось отправить приглашения"))
+			return
+		}
+
+		// Ссылка в письме позволяет открыть событие еще до входа под приглашенным адресом
+		token := invites.Signer.Sign(event.EventID, event.InviteVersion, time.Now().Add(invites.MaxTTL))
+		go notifyInvited(log, emailClient, added, event, invites.link(event.EventID, token))
+
+		invitations, err := eventStorage.GetInvitations(idInt)
+		if err != nil {
+			log.Error(op, "failed to get invitations", err)
+			render.JSON(w, r, response.Error("не удалось получить приглашения"))
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK(), Invitations: invitations})
+	}
+}
+
+// GetInvitationsHandler возвращает приглашения на событие с ответами приглашенных
+func GetInvitationsHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.GetInvitations"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionViewAttendees); !ok {
+			return
+		}
+
+		invitations, err := eventStorage.GetInvitations(idInt)
+		if err != nil {
+			log.Error(op, "failed to get invitations", err)
+			render.JSON(w, r, response.Error("не удалось получить приглашения"))
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK(), Invitations: invitations})
+	}
+}
+
+// RemoveInvitationHandler отзывает приглашение по email из параметра email
+func RemoveInvitationHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.RemoveInvitation"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		email := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("email")))
+		if email == "" {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("email должен быть указан"))
+			return
+		}
+
+		if _, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionInvite); !ok {
+			return
+		}
+
+		if err := eventStorage.RemoveInvitation(idInt, email); err != nil {
+			log.Error(op, "failed to remove invitation", err)
+			if errors.Is(err, storage.ErrInvitationNotFound) {
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, response.Error("приглашение не найдено"))
+				return
+			}
+			render.JSON(w, r, response.Error("не удалось отозвать приглашение"))
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK()})
+	}
+}
+
+// RSVPHandler принимает ответ на приглашение: accepted записывает на событие, declined отменяет запись,
+// если она была, и отмечает отказ
+func RSVPHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, emailClient *emailsendergrpc.Client, invites Invites, tickets Tickets) http.HandlerFunc {
+	type request struct {
+		Status      string `json:"status" validate:"required,oneof=accepted declined"`
+		InviteToken string `json:"inviteToken"`
+		// Answers - ответы на анкету регистрации при принятии приглашения
+		Answers []storage.Answer `json:"answers"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.RSVP"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		var req request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error(op, "failed to decode request body", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("некорректные данные запроса"))
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error(op, "invalid request", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("status должен быть accepted или declined"))
+			return
+		}
+
+		if req.Status == storage.InvitationAccepted {
+			registerForEvent(w, r, log, eventStorage, emailClient, invites, tickets, idInt, req.InviteToken, req.Answers)
+			return
+		}
+
+		user, _ := auth.UserFromContext(r.Context())
+
+		// Отмена записи сама отмечает отказ от приглашения
+		result, err := eventStorage.CancelRegistration(idInt, int(user.ID))
+		switch {
+		case err == nil:
+			go func() {
+				deleteReminders(log, emailClient, result.ReminderIDs)
+				notifyPromoted(log, eventStorage, emailClient, tickets, result.Promoted)
+			}()
+		case errors.Is(err, storage.ErrRegistrationNotFound):
+			if err := eventStorage.DeclineInvitation(idInt, user.ID); err != nil {
+				log.Error(op, "failed to decline invitation", err)
+				if errors.Is(err, storage.ErrInvitationNotFound) {
+					render.Status(r, http.StatusNotFound)
+					render.JSON(w, r, response.Error("приглашение не найдено"))
+					return
+				}
+				render.JSON(w, r, response.Error("не удалось отклонить приглашение"))
+				return
+			}
+		default:
+			log.Error(op, "failed to cancel registration", err)
+			if errors.Is(err, storage.ErrEventNotFound) {
+				render.Status(r, http.StatusNotFound)
+			}
+			render.JSON(w, r, response.Error("не удалось отклонить приглашение"))
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK()})
+	}
+}
@@ -0,0 +1,272 @@
+package events
+
+import (
+	emailsendergrpc "Backend/internal/clients/emailsender/grpc"
+	"Backend/internal/lib/logger/sl"
+	"Backend/internal/storage"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// notificationTimeout ограничивает время обращения к EmailSenderService
+const notificationTimeout = 5 * time.Second
+
+// ReminderStorage сохраняет идентификаторы напоминаний регистрации
+type ReminderStorage interface {
+	AddReminders(userId, eventId int, notificationIDs []int64) error
+}
+
+// scheduleReminders создает напоминания о событии для участника и сохраняет их,
+// чтобы удалить при отмене регистрации
+func scheduleReminders(log *slog.Logger, reminderStorage ReminderStorage, emailClient *emailsendergrpc.Client, userID int64, eventID int, email, eventName string, eventDate time.Time) {
+	const op = "handlers.events.scheduleReminders"
+
+	if emailClient == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+
+	notificationIDs, err := emailClient.CreateNotification(ctx, email, eventName, eventDate.Unix())
+	if err != nil {
+		log.Error("Ошибка при создании уведомления", slog.String("op", op), sl.Err(err))
+		return
+	}
+
+	if err := reminderStorage.AddReminders(int(userID), eventID, notificationIDs); err != nil {
+		log.Error("Ошибка при сохранении уведомлений", slog.String("op", op), sl.Err(err))
+		return
+	}
+
+	log.Info("Уведомления успешно созданы", slog.Any("notification_ids", notificationIDs))
+}
+
+// deleteReminders удаляет напоминания отмененной регистрации
+func deleteReminders(log *slog.Logger, emailClient *emailsendergrpc.Client, notificationIDs []int64) {
+	const op = "handlers.events.deleteReminders"
+
+	if emailClient == nil || len(notificationIDs) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+
+	if _, err := emailClient.DeleteNotifications(ctx, notificationIDs); err != nil {
+		log.Error("Ошибка при удалении уведомлений", slog.String("op", op), sl.Err(err))
+	}
+}
+
+// ticketNote дописывается к письму, к которому приложен билет
+const ticketNote = "\r\n\r\nВо вложении ваш билет: покажите QR-код на входе."
+
+// ticketAttachments возвращает QR-код билета для вложения в письмо. Если билет сформировать не удалось,
+// письмо уходит без него: билет всегда можно открыть на странице события.
+func ticketAttachments(log *slog.Logger, tickets Tickets, eventID, userID int64, nonce string) []emailsendergrpc.Attachment {
+	const op = "handlers.events.ticketAttachments"
+
+	if tickets.Signer == nil || nonce == "" {
+		return nil
+	}
+
+	attachment, err := tickets.attachment(eventID, userID, nonce)
+	if err != nil {
+		log.Error("Ошибка при формировании билета", slog.String("op", op), sl.Err(err))
+		return nil
+	}
+
+	return []emailsendergrpc.Attachment{attachment}
+}
+
+// notifyRegistered подтверждает запись на событие письмом с билетом
+func notifyRegistered(log *slog.Logger, emailClient *emailsendergrpc.Client, tickets Tickets, eventID, userID int64, result storage.RegistrationResult) {
+	const op = "handlers.events.notifyRegistered"
+
+	if emailClient == nil {
+		return
+	}
+
+	body := fmt.Sprintf(
+		"Здравствуйте!\r\n\r\nВы записаны на мероприятие «%s», которое состоится %s.",
+		result.EventName, result.EventDate.Format("02.01.2006 15:04"),
+	)
+	attachments := ticketAttachments(log, tickets, eventID, userID, result.TicketNonce)
+	if len(attachments) > 0 {
+		body += ticketNote
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+
+	if err := emailClient.SendEmail(ctx, result.UserEmail, "Вы записаны на мероприятие", body, attachments...); err != nil {
+		log.Error("Ошибка при отправке письма о записи на мероприятие", slog.String("op", op), sl.Err(err))
+	}
+}
+
+// notifyPromoted сообщает пользователям из листа ожидания, что для них освободилось место,
+// и создает им напоминания о событии
+func notifyPromoted(log *slog.Logger, reminderStorage ReminderStorage, emailClient *emailsendergrpc.Client, tickets Tickets, promoted []storage.Promotion) {
+	const op = "handlers.events.notifyPromoted"
+
+	if emailClient == nil {
+		return
+	}
+
+	for _, p := range promoted {
+		body := fmt.Sprintf(
+			"Здравствуйте!\r\n\r\nОсвободилось место на мероприятии «%s», которое состоится %s. Вы переведены из листа ожидания в список участников.",
+			p.EventName, p.EventDate.Format("02.01.2006 15:04"),
+		)
+		attachments := ticketAttachments(log, tickets, p.EventID, p.UserID, p.TicketNonce)
+		if len(attachments) > 0 {
+			body += ticketNote
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+		err := emailClient.SendEmail(ctx, p.UserEmail, "Для вас освободилось место", body, attachments...)
+		cancel()
+		if err != nil {
+			log.Error("Ошибка при отправке письма о переводе из листа ожидания", slog.String("op", op), sl.Err(err))
+		}
+
+		scheduleReminders(log, reminderStorage, emailClient, p.UserID, int(p.EventID), p.UserEmail, p.EventName, p.EventDate)
+	}
+}
+
+// notifyCancelled удаляет напоминания отмененного события и сообщает об отмене всем зарегистрированным,
+// включая лист ожидания
+func notifyCancelled(log *slog.Logger, emailClient *emailsendergrpc.Client, cancellation storage.EventCancellation) {
+	const op = "handlers.events.notifyCancelled"
+
+	if emailClient == nil {
+		return
+	}
+
+	deleteReminders(log, emailClient, cancellation.ReminderIDs)
+
+	body := fmt.Sprintf(
+		"Здравствуйте!\r\n\r\nМероприятие «%s», которое должно было состояться %s, отменено организатором.",
+		cancellation.EventName, cancellation.EventDate.Format("02.01.2006 15:04"),
+	)
+	if cancellation.Reason != "" {
+		body += "\r\n\r\nПричина: " + cancellation.Reason
+	}
+
+	for _, registrant := range cancellation.Registrants {
+		ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+		err := emailClient.SendEmail(ctx, registrant.UserEmail, "Мероприятие отменено", body)
+		cancel()
+		if err != nil {
+			log.Error("Ошибка при отправке письма об отмене мероприятия", slog.String("op", op), slog.Int64("user_id", registrant.UserID), sl.Err(err))
+		}
+	}
+}
+
+// staffRoleNames - названия ролей команды события в письмах
+var staffRoleNames = map[string]string{
+	storage.StaffOwner:       "владельцем",
+	storage.StaffCoOrganizer: "соорганизатором",
+	storage.StaffCheckIn:     "сотрудником регистрации на входе",
+}
+
+// notifyStaffAdded сообщает пользователю, что его пригласили в команду события или он стал его владельцем
+func notifyStaffAdded(log *slog.Logger, emailClient *emailsendergrpc.Client, email string, event storage.Event, role string) {
+	const op = "handlers.events.notifyStaffAdded"
+
+	if emailClient == nil {
+		return
+	}
+
+	subject := "Приглашение в команду мероприятия"
+	body := fmt.Sprintf(
+		"Здравствуйте!\r\n\r\nВас пригласили стать %s мероприятия «%s», которое состоится %s.\r\n\r\n"+
+			"Принять или отклонить приглашение можно на странице мероприятия.",
+		staffRoleNames[role], event.Title, event.EventDate.Format("02.01.2006 15:04"),
+	)
+	if role == storage.StaffOwner {
+		subject = "Вы в команде мероприятия"
+		body = fmt.Sprintf(
+			"Здравствуйте!\r\n\r\nВы назначены %s мероприятия «%s», которое состоится %s.",
+			staffRoleNames[role], event.Title, event.EventDate.Format("02.01.2006 15:04"),
+		)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+
+	if err := emailClient.SendEmail(ctx, email, subject, body); err != nil {
+		log.Error("Ошибка при отправке письма о добавлении в команду", slog.String("op", op), sl.Err(err))
+	}
+}
+
+// notifyInvited рассылает приглашения на событие новым приглашенным
+func notifyInvited(log *slog.Logger, emailClient *emailsendergrpc.Client, emails []string, event storage.Event, link string) {
+	const op = "handlers.events.notifyInvited"
+
+	if emailClient == nil {
+		return
+	}
+
+	body := fmt.Sprintf(
+		"Здравствуйте!\r\n\r\nВас пригласили на мероприятие «%s», которое состоится %s.\r\n\r\nПодробности и запись: %s",
+		event.Title, event.EventDate.Format("02.01.2006 15:04"), link,
+	)
+
+	for _, email := range emails {
+		ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+		err := emailClient.SendEmail(ctx, email, "Приглашение на мероприятие", body)
+		cancel()
+		if err != nil {
+			log.Error("Ошибка при отправке приглашения на мероприятие", slog.String("op", op), sl.Err(err))
+		}
+	}
+}
+
+// notifyApplicationDecisions сообщает пользователям о решении по их заявкам на событие.
+// Напоминания о событии создаются только участникам, одобренным на свободное место.
+func notifyApplicationDecisions(log *slog.Logger, reminderStorage ReminderStorage, emailClient *emailsendergrpc.Client, tickets Tickets, eventID int, decisions []storage.ApplicationDecision, message string) {
+	const op = "handlers.events.notifyApplicationDecisions"
+
+	if emailClient == nil {
+		return
+	}
+
+	for _, d := range decisions {
+		var subject, body string
+		var attachments []emailsendergrpc.Attachment
+		date := d.EventDate.Format("02.01.2006 15:04")
+
+		switch d.Status {
+		case storage.RegistrationConfirmed:
+			subject = "Заявка одобрена"
+			body = fmt.Sprintf("Здравствуйте!\r\n\r\nВаша заявка на мероприятие «%s», которое состоится %s, одобрена. Вы в списке участников.", d.EventName, date)
+			attachments = ticketAttachments(log, tickets, int64(eventID), d.UserID, d.TicketNonce)
+			if len(attachments) > 0 {
+				body += ticketNote
+			}
+		case storage.RegistrationWaitlisted:
+			subject = "Заявка одобрена"
+			body = fmt.Sprintf("Здравствуйте!\r\n\r\nВаша заявка на мероприятие «%s», которое состоится %s, одобрена, но свободных мест пока нет. Вы в листе ожидания под номером %d.", d.EventName, date, d.Position)
+		default:
+			subject = "Заявка отклонена"
+			body = fmt.Sprintf("Здравствуйте!\r\n\r\nК сожалению, ваша заявка на мероприятие «%s», которое состоится %s, отклонена.", d.EventName, date)
+		}
+		if message != "" {
+			body += "\r\n\r\nСообщение организатора: " + message
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+		err := emailClient.SendEmail(ctx, d.UserEmail, subject, body, attachments...)
+		cancel()
+		if err != nil {
+			log.Error("Ошибка при отправке письма о решении по заявке", slog.String("op", op), slog.Int64("user_id", d.UserID), sl.Err(err))
+		}
+
+		if d.Status == storage.RegistrationConfirmed {
+			scheduleReminders(log, reminderStorage, emailClient, d.UserID, eventID, d.UserEmail, d.EventName, d.EventDate)
+		}
+	}
+}
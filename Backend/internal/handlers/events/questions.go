@@ -0,0 +1,211 @@
+package events
+
+import (
+	"Backend/internal/lib/permissions"
+	"Backend/internal/lib/response"
+	"Backend/internal/storage"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// maxQuestions ограничивает размер анкеты регистрации
+	maxQuestions = 30
+	// defaultAnswerLength - наибольшая длина текстового ответа, если у вопроса не задана своя
+	defaultAnswerLength = 1000
+)
+
+// QuestionStorage хранит анкеты регистрации на события
+type QuestionStorage interface {
+	SetEventQuestions(eventID int, questions []storage.Question) ([]storage.Question, error)
+}
+
+// SetEventQuestionsHandler заменяет анкету регистрации события. Вопросы передаются в порядке показа,
+// у оставляемых вопросов указывается id, чтобы сохранить ответы на них.
+func SetEventQuestionsHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+	type request struct {
+		Questions []storage.Question `json:"questions" validate:"max=30,dive"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.SetEventQuestions"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		var req request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error(op, "failed to decode request body", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("некорректные данные запроса"))
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error(op, "invalid request", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(fmt.Sprintf("ошибка валидации анкеты, в ней может быть до %d вопросов", maxQuestions)))
+			return
+		}
+
+		if err := checkQuestions(req.Questions); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		if _, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionEditEvent); !ok {
+			return
+		}
+
+		questions, err := eventStorage.SetEventQuestions(idInt, req.Questions)
+		if err != nil {
+			log.Error(op, "failed to set questions", err)
+			if errors.Is(err, storage.ErrQuestionNotFound) {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error("вопрос не относится к анкете события"))
+				return
+			}
+			render.JSON(w, r, response.Error("не удалось сохранить анкету"))
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK(), Questions: questions})
+	}
+}
+
+// checkQuestions проверяет согласованность вопросов анкеты, которую не выразить тегами валидации
+func checkQuestions(questions []storage.Question) error {
+	for i := range questions {
+		q := &questions[i]
+		q.Label = strings.TrimSpace(q.Label)
+		if q.Label == "" {
+			return fmt.Errorf("у вопроса %d нет текста", i+1)
+		}
+
+		switch q.Type {
+		case storage.QuestionSingle, storage.QuestionMulti:
+			if len(q.Options) < 2 {
+				return fmt.Errorf("у вопроса «%s» должно быть не меньше двух вариантов ответа", q.Label)
+			}
+			for j, option := range q.Options {
+				if slices.Contains(q.Options[:j], option) {
+					return fmt.Errorf("у вопроса «%s» повторяется вариант «%s»", q.Label, option)
+				}
+			}
+		default:
+			if len(q.Options) > 0 {
+				return fmt.Errorf("варианты ответа задаются только для вопросов с выбором")
+			}
+		}
+
+		if q.Type != storage.QuestionText && (q.MaxLength != nil || q.Pattern != "") {
+			return fmt.Errorf("длина и формат ответа задаются только для текстовых вопросов")
+		}
+		if q.Pattern != "" {
+			if _, err := regexp.Compile(q.Pattern); err != nil {
+				return fmt.Errorf("некорректный формат ответа у вопроса «%s»", q.Label)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkAnswers проверяет ответы на анкету регистрации и приводит их к виду для хранения.
+// Пустые ответы на необязательные вопросы отбрасываются.
+func checkAnswers(questions []storage.Question, answers []storage.Answer) ([]storage.Answer, error) {
+	byID := make(map[int64]storage.Answer, len(answers))
+	for _, answer := range answers {
+		if _, ok := byID[answer.QuestionID]; ok {
+			return nil, fmt.Errorf("на вопрос %d дано несколько ответов", answer.QuestionID)
+		}
+		byID[answer.QuestionID] = answer
+	}
+
+	checked := make([]storage.Answer, 0, len(questions))
+	for _, q := range questions {
+		answer, ok := byID[q.ID]
+		delete(byID, q.ID)
+		answer.QuestionID = q.ID
+
+		switch q.Type {
+		case storage.QuestionText:
+			answer.Value, answer.Values = strings.TrimSpace(answer.Value), nil
+
+			maxLength := defaultAnswerLength
+			if q.MaxLength != nil {
+				maxLength = *q.MaxLength
+			}
+			if utf8.RuneCountInString(answer.Value) > maxLength {
+				return nil, fmt.Errorf("ответ на вопрос «%s» длиннее %d символов", q.Label, maxLength)
+			}
+			if answer.Value != "" && q.Pattern != "" && !regexp.MustCompile(q.Pattern).MatchString(answer.Value) {
+				return nil, fmt.Errorf("ответ на вопрос «%s» имеет неверный формат", q.Label)
+			}
+			ok = answer.Value != ""
+
+		case storage.QuestionSingle:
+			answer.Values = nil
+			if answer.Value != "" && !slices.Contains(q.Options, answer.Value) {
+				return nil, fmt.Errorf("вариант «%s» не подходит к вопросу «%s»", answer.Value, q.Label)
+			}
+			ok = answer.Value != ""
+
+		case storage.QuestionMulti:
+			var values []string
+			for _, value := range answer.Values {
+				if !slices.Contains(q.Options, value) {
+					return nil, fmt.Errorf("вариант «%s» не подходит к вопросу «%s»", value, q.Label)
+				}
+				if !slices.Contains(values, value) {
+					values = append(values, value)
+				}
+			}
+			answer.Value, answer.Values = "", values
+			ok = len(values) > 0
+
+		case storage.QuestionCheckbox:
+			// Обязательная отметка - это согласие, без которого записаться нельзя
+			answer.Values = nil
+			if answer.Value != "" && answer.Value != "true" && answer.Value != "false" {
+				return nil, fmt.Errorf("ответ на вопрос «%s» должен быть true или false", q.Label)
+			}
+			ok = answer.Value == "true"
+			if !ok {
+				answer.Value = "false"
+			}
+		}
+
+		if !ok {
+			if q.Required {
+				return nil, fmt.Errorf("ответьте на обязательный вопрос «%s»", q.Label)
+			}
+			if q.Type != storage.QuestionCheckbox {
+				continue
+			}
+		}
+
+		checked = append(checked, answer)
+	}
+
+	for id := range byID {
+		return nil, fmt.Errorf("вопрос %d не относится к анкете события", id)
+	}
+
+	return checked, nil
+}
@@ -0,0 +1,242 @@
+package events
+
+import (
+	emailsendergrpc "Backend/internal/clients/emailsender/grpc"
+	"Backend/internal/lib/permissions"
+	"Backend/internal/lib/response"
+	"Backend/internal/middleware/auth"
+	"Backend/internal/storage"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// StaffStorage хранит команду события
+type StaffStorage interface {
+	AddEventStaff(eventID int, email, role string) (storage.Organizer, error)
+	AcceptEventStaff(eventID int, userID int64) (storage.Organizer, error)
+	RemoveEventStaff(eventID int, userID int64) error
+	TransferOwnership(eventID int, email string) (storage.Organizer, error)
+	GetEventAttendees(eventID int) ([]storage.Attendee, error)
+}
+
+// AddEventStaffHandler приглашает пользователя в команду события или меняет его роль.
+// Права роли появляются, когда пользователь примет приглашение через AcceptEventStaffHandler.
+func AddEventStaffHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, emailClient *emailsendergrpc.Client) http.HandlerFunc {
+	type request struct {
+		Email string `json:"email" validate:"required,email"`
+		Role  string `json:"role" validate:"required,oneof=co-organizer checkin"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.AddEventStaff"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		var req request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error(op, "failed to decode request body", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("некорректные данные запроса"))
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error(op, "invalid request", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("ошибка валидации"))
+			return
+		}
+
+		event, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionManageStaff)
+		if !ok {
+			return
+		}
+
+		member, err := eventStorage.AddEventStaff(idInt, req.Email, req.Role)
+		if err != nil {
+			log.Error(op, "failed to add staff", err)
+			renderStaffError(w, r, err, "не удалось добавить пользователя в команду")
+			return
+		}
+
+		if member.Status == storage.StaffInvited {
+			go notifyStaffAdded(log, emailClient, req.Email, event, req.Role)
+		}
+
+		render.JSON(w, r, Response{Response: response.OK(), Organizers: []storage.Organizer{member}})
+	}
+}
+
+// AcceptEventStaffHandler принимает приглашение текущего пользователя в команду события.
+// Отклонить приглашение можно через RemoveEventStaffHandler.
+func AcceptEventStaffHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.AcceptEventStaff"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		user, _ := auth.UserFromContext(r.Context())
+		member, err := eventStorage.AcceptEventStaff(idInt, user.ID)
+		if err != nil {
+			log.Error(op, "failed to accept staff invitation", err)
+			if errors.Is(err, storage.ErrStaffNotFound) {
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, response.Error("приглашение в команду события не найдено"))
+				return
+			}
+			render.JSON(w, r, response.Error("не удалось принять приглашение в команду"))
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK(), Organizers: []storage.Organizer{member}})
+	}
+}
+
+// RemoveEventStaffHandler исключает пользователя из команды события. Участник команды может выйти из нее сам.
+func RemoveEventStaffHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.RemoveEventStaff"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		userID, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		user, _ := auth.UserFromContext(r.Context())
+		if user.ID != userID {
+			if _, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionManageStaff); !ok {
+				return
+			}
+		}
+
+		if err := eventStorage.RemoveEventStaff(idInt, userID); err != nil {
+			log.Error(op, "failed to remove staff", err)
+			renderStaffError(w, r, err, "не удалось исключить пользователя из команды")
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK()})
+	}
+}
+
+// TransferOwnershipHandler передает событие другому пользователю. Прежний владелец становится соорганизатором.
+func TransferOwnershipHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, emailClient *emailsendergrpc.Client) http.HandlerFunc {
+	type request struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.TransferOwnership"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		var req request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error(op, "failed to decode request body", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("некорректные данные запроса"))
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error(op, "invalid request", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("ошибка валидации"))
+			return
+		}
+
+		event, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionTransferOwnership)
+		if !ok {
+			return
+		}
+
+		owner, err := eventStorage.TransferOwnership(idInt, req.Email)
+		if err != nil {
+			log.Error(op, "failed to transfer ownership", err)
+			renderStaffError(w, r, err, "не удалось передать событие")
+			return
+		}
+
+		go notifyStaffAdded(log, emailClient, req.Email, event, storage.StaffOwner)
+
+		render.JSON(w, r, Response{Response: response.OK(), Organizers: []storage.Organizer{owner}})
+	}
+}
+
+// GetEventAttendeesHandler возвращает участников, лист ожидания и заявки события с контактами для команды события.
+// Параметр status оставляет записи с указанным статусом, например pending для нерассмотренных заявок.
+func GetEventAttendeesHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.GetEventAttendees"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionViewAttendees); !ok {
+			return
+		}
+
+		attendees, err := eventStorage.GetEventAttendees(idInt)
+		if err != nil {
+			log.Error(op, "failed to get attendees", err)
+			render.JSON(w, r, response.Error("не удалось получить список участников"))
+			return
+		}
+
+		if status := r.URL.Query().Get("status"); status != "" {
+			filtered := []storage.Attendee{}
+			for _, attendee := range attendees {
+				if attendee.Status == status {
+					filtered = append(filtered, attendee)
+				}
+			}
+			attendees = filtered
+		}
+
+		render.JSON(w, r, Response{Response: response.OK(), Attendees: attendees})
+	}
+}
+
+func renderStaffError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, response.Error("пользователь с таким email не найден"))
+	case errors.Is(err, storage.ErrStaffNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, response.Error("пользователь не входит в команду события"))
+	case errors.Is(err, storage.ErrAlreadyOwner):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, response.Error("пользователь уже является владельцем события"))
+	default:
+		render.JSON(w, r, response.Error(message))
+	}
+}
@@ -0,0 +1,450 @@
+package events
+
+import (
+	emailsendergrpc "Backend/internal/clients/emailsender/grpc"
+	"Backend/internal/lib/permissions"
+	"Backend/internal/lib/response"
+	"Backend/internal/lib/ticket"
+	"Backend/internal/middleware/auth"
+	"Backend/internal/storage"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// Tickets - настройки билетов
+type Tickets struct {
+	Signer *ticket.Signer
+	// QRSize - сторона QR-кода в PNG в пикселях
+	QRSize int
+}
+
+// attachment возвращает QR-код билета для вложения в письмо
+func (t Tickets) attachment(eventID, userID int64, nonce string) (emailsendergrpc.Attachment, error) {
+	png, err := ticket.PNG(t.Signer.Code(eventID, userID, nonce), t.QRSize)
+	if err != nil {
+		return emailsendergrpc.Attachment{}, err
+	}
+
+	return emailsendergrpc.Attachment{
+		Filename:    fmt.Sprintf("ticket-%d.png", eventID),
+		ContentType: "image/png",
+		Content:     png,
+	}, nil
+}
+
+// TicketStorage хранит билеты и отметки на входе
+type TicketStorage interface {
+	GetTicket(eventID int, userID int64) (storage.Ticket, error)
+	CheckIn(eventID int, userID int64, nonce string, staffID int64) (storage.CheckIn, error)
+	GetCheckInStats(eventID int) (storage.CheckInStats, error)
+	GetCheckInSnapshot(eventID int) ([]storage.SnapshotTicket, error)
+	SyncCheckIns(eventID int, staffID int64, deviceID string, scans []storage.OfflineScan) ([]storage.SyncResult, storage.CheckInStats, error)
+}
+
+// TicketResponse - билет автора запроса с кодом для QR
+type TicketResponse struct {
+	storage.Ticket
+	Code string `json:"code"`
+}
+
+// GetTicketHandler возвращает билет автора запроса на событие. Билет есть только у участника с подтвержденным местом.
+func GetTicketHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, tickets Tickets) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.GetTicket"
+
+		t, ok := ownTicket(w, r, log, eventStorage, op)
+		if !ok {
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			Ticket:   &TicketResponse{Ticket: t, Code: tickets.Signer.Code(t.EventID, t.UserID, t.Nonce)},
+		})
+	}
+}
+
+// GetTicketQRHandler возвращает QR-код билета автора запроса. format=svg отдает SVG, по умолчанию PNG.
+func GetTicketQRHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, tickets Tickets) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.GetTicketQR"
+
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "png" && format != "svg" {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("format должен быть png или svg"))
+			return
+		}
+
+		t, ok := ownTicket(w, r, log, eventStorage, op)
+		if !ok {
+			return
+		}
+
+		code := tickets.Signer.Code(t.EventID, t.UserID, t.Nonce)
+
+		var image []byte
+		var err error
+		contentType := "image/png"
+		if format == "svg" {
+			image, err = ticket.SVG(code)
+			contentType = "image/svg+xml"
+		} else {
+			image, err = ticket.PNG(code, tickets.QRSize)
+		}
+		if err != nil {
+			log.Error(op, "failed to render QR code", err)
+			render.JSON(w, r, response.Error("не удалось сформировать QR-код"))
+			return
+		}
+
+		// Билет нельзя кэшировать: при отмене регистрации он перестает действовать
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(image)
+	}
+}
+
+// CheckInHandler отмечает участника на входе по коду билета и возвращает текущие счетчики отметок.
+// Повторная отметка отклоняется с 409 и временем первой.
+func CheckInHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, tickets Tickets) http.HandlerFunc {
+	type request struct {
+		Code string `json:"code" validate:"required,max=512"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.CheckIn"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		var req request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error(op, "failed to decode request body", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("некорректные данные запроса"))
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error(op, "invalid request", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("код билета должен быть указан"))
+			return
+		}
+
+		if _, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionCheckIn); !ok {
+			return
+		}
+
+		eventID, userID, nonce, err := tickets.Signer.Parse(req.Code)
+		if err != nil || eventID != int64(idInt) {
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Error("билет недействителен для этого события"))
+			return
+		}
+
+		staff, _ := auth.UserFromContext(r.Context())
+		result, err := eventStorage.CheckIn(idInt, userID, nonce, staff.ID)
+		if err != nil {
+			log.Error(op, "failed to check in", err)
+			switch {
+			case errors.Is(err, storage.ErrAlreadyCheckedIn):
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, Response{
+					Response: response.Error(fmt.Sprintf("участник уже отмечен %s", result.CheckedInAt.Format("02.01.2006 15:04:05"))),
+					CheckIn:  &result,
+				})
+			case errors.Is(err, storage.ErrTicketNotValid):
+				render.Status(r, http.StatusUnprocessableEntity)
+				render.JSON(w, r, response.Error("билет недействителен: регистрация отменена или место не подтверждено"))
+			case errors.Is(err, storage.ErrEventNotOpen):
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, response.Error("отметка на входе доступна только для опубликованного события до его окончания"))
+			default:
+				render.JSON(w, r, response.Error("не удалось отметить участника"))
+			}
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK(), CheckIn: &result})
+	}
+}
+
+// GetCheckInStatsHandler возвращает текущее число отмеченных на входе и подтвержденных участников
+func GetCheckInStatsHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.GetCheckInStats"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionCheckIn); !ok {
+			return
+		}
+
+		stats, err := eventStorage.GetCheckInStats(idInt)
+		if err != nil {
+			log.Error(op, "failed to get check-in stats", err)
+			render.JSON(w, r, response.Error("не удалось получить счетчики отметок"))
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK(), CheckInStats: &stats})
+	}
+}
+
+const (
+	// maxScanClockSkew - насколько время сканирования может опережать часы сервера из-за расхождения часов устройства
+	maxScanClockSkew = 5 * time.Minute
+	// checkInOpensBefore - за сколько до начала события принимаются отметки без сети. Более ранние сканирования
+	// отклоняются, иначе задним числом можно было бы вытеснить настоящую отметку билета.
+	checkInOpensBefore = 12 * time.Hour
+)
+
+// SnapshotTicket - билет в выгрузке для отметки без сети. Устройство находит отсканированный билет по отпечатку кода.
+type SnapshotTicket struct {
+	Hash string `json:"hash"`
+	storage.SnapshotTicket
+}
+
+// Snapshot - содержимое выгрузки билетов события
+type Snapshot struct {
+	EventID     int64            `json:"eventId"`
+	GeneratedAt time.Time        `json:"generatedAt"`
+	Tickets     []SnapshotTicket `json:"tickets"`
+}
+
+// SignedSnapshot - выгрузка билетов с подписью. Payload - JSON выгрузки в base64url, подпись Ed25519
+// проверяется по его байтам открытым ключом, который устройство закрепляет заранее
+// (GET /checkin/public-key или ключ из журнала запуска сервера), а не получает вместе с выгрузкой.
+type SignedSnapshot struct {
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// GetSnapshotPublicKeyHandler возвращает открытый ключ для проверки выгрузок билетов. Устройства получают его
+// один раз при настройке и закрепляют.
+func GetSnapshotPublicKeyHandler(log *slog.Logger, tickets Tickets) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, Response{
+			Response:          response.OK(),
+			SnapshotPublicKey: tickets.Signer.SnapshotPublicKey(),
+		})
+	}
+}
+
+// SyncResponse - итог загрузки отметок с устройства
+type SyncResponse struct {
+	// Results - итог каждого сканирования в порядке запроса
+	Results []storage.SyncResult `json:"results"`
+	// Conflicts - сканирования, по которым билет был отмечен несколько раз
+	Conflicts []storage.SyncResult `json:"conflicts"`
+	Stats     storage.CheckInStats `json:"stats"`
+}
+
+// GetCheckInSnapshotHandler выгружает подписанный список действующих билетов события
+// для отметки на входе без сети
+func GetCheckInSnapshotHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, tickets Tickets) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.GetCheckInSnapshot"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionCheckIn); !ok {
+			return
+		}
+
+		registrations, err := eventStorage.GetCheckInSnapshot(idInt)
+		if err != nil {
+			log.Error(op, "failed to get check-in snapshot", err)
+			render.JSON(w, r, response.Error("не удалось выгрузить билеты"))
+			return
+		}
+
+		snapshot := Snapshot{
+			EventID:     int64(idInt),
+			GeneratedAt: time.Now().UTC(),
+			Tickets:     make([]SnapshotTicket, 0, len(registrations)),
+		}
+		for _, t := range registrations {
+			snapshot.Tickets = append(snapshot.Tickets, SnapshotTicket{
+				Hash:           ticket.Hash(tickets.Signer.Code(int64(idInt), t.UserID, t.Nonce)),
+				SnapshotTicket: t,
+			})
+		}
+
+		payload, err := json.Marshal(snapshot)
+		if err != nil {
+			log.Error(op, "failed to encode check-in snapshot", err)
+			render.JSON(w, r, response.Error("не удалось выгрузить билеты"))
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			Snapshot: &SignedSnapshot{
+				Payload:   base64.RawURLEncoding.EncodeToString(payload),
+				Signature: tickets.Signer.SignSnapshot(payload),
+			},
+		})
+	}
+}
+
+// SyncCheckInsHandler загружает отметки, отсканированные устройством без сети. Каждое сканирование
+// получает итог, конфликты повторных сканирований одного билета возвращаются отдельно.
+func SyncCheckInsHandler(log *slog.Logger, eventStorage EventStorage, validate *validator.Validate, tickets Tickets) http.HandlerFunc {
+	type scan struct {
+		Code      string    `json:"code" validate:"required,max=512"`
+		ScannedAt time.Time `json:"scannedAt" validate:"required"`
+	}
+	type request struct {
+		DeviceID string `json:"deviceId" validate:"required,max=64"`
+		Scans    []scan `json:"scans" validate:"required,min=1,max=1000,dive"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.SyncCheckIns"
+
+		idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+
+		var req request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error(op, "failed to decode request body", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("некорректные данные запроса"))
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error(op, "invalid request", err)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("ошибка валидации"))
+			return
+		}
+
+		event, ok := authorizeEvent(w, r, log, eventStorage, idInt, permissions.ActionCheckIn)
+		if !ok {
+			return
+		}
+
+		// Недействительные коды и сканирования из будущего, до открытия или после закрытия входа не доходят
+		// до хранилища, valid связывает остальные с их местом в запросе
+		results := make([]storage.SyncResult, len(req.Scans))
+		var scans []storage.OfflineScan
+		var valid []int
+		earliest := event.EventDate.Add(-checkInOpensBefore)
+		latest := time.Now().Add(maxScanClockSkew)
+		endsAt := event.EventDate
+		if event.DurationMinutes != nil {
+			endsAt = endsAt.Add(time.Duration(*event.DurationMinutes) * time.Minute)
+		}
+		if closes := endsAt.Add(storage.CheckInClosesAfter); closes.Before(latest) {
+			latest = closes
+		}
+		for i, s := range req.Scans {
+			eventID, userID, nonce, err := tickets.Signer.Parse(s.Code)
+			if err != nil || eventID != int64(idInt) || s.ScannedAt.Before(earliest) || s.ScannedAt.After(latest) {
+				results[i] = storage.SyncResult{Status: storage.SyncInvalid}
+				continue
+			}
+			scans = append(scans, storage.OfflineScan{UserID: userID, Nonce: nonce, ScannedAt: s.ScannedAt})
+			valid = append(valid, i)
+		}
+
+		var stats storage.CheckInStats
+		if len(scans) > 0 {
+			staff, _ := auth.UserFromContext(r.Context())
+			var synced []storage.SyncResult
+			synced, stats, err = eventStorage.SyncCheckIns(idInt, staff.ID, req.DeviceID, scans)
+			if err != nil {
+				log.Error(op, "failed to sync check-ins", err)
+				if errors.Is(err, storage.ErrEventNotOpen) {
+					render.Status(r, http.StatusConflict)
+					render.JSON(w, r, response.Error("отметка на входе доступна только для опубликованного события"))
+					return
+				}
+				render.JSON(w, r, response.Error("не удалось загрузить отметки"))
+				return
+			}
+			for j, i := range valid {
+				results[i] = synced[j]
+			}
+		} else {
+			stats, err = eventStorage.GetCheckInStats(idInt)
+			if err != nil {
+				log.Error(op, "failed to get check-in stats", err)
+				render.JSON(w, r, response.Error("не удалось загрузить отметки"))
+				return
+			}
+		}
+
+		conflicts := []storage.SyncResult{}
+		for _, result := range results {
+			if result.Status == storage.SyncDuplicate || result.ReplacedAt != nil {
+				conflicts = append(conflicts, result)
+			}
+		}
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			Sync:     &SyncResponse{Results: results, Conflicts: conflicts, Stats: stats},
+		})
+	}
+}
+
+// ownTicket загружает действующий билет автора запроса на событие из URL.
+// При ошибке ответ уже записан и возвращается false.
+func ownTicket(w http.ResponseWriter, r *http.Request, log *slog.Logger, eventStorage EventStorage, op string) (storage.Ticket, bool) {
+	idInt, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return storage.Ticket{}, false
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	t, err := eventStorage.GetTicket(idInt, user.ID)
+	if err != nil {
+		log.Error(op, "failed to get ticket", err)
+		if errors.Is(err, storage.ErrRegistrationNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("вы не записаны на событие"))
+			return storage.Ticket{}, false
+		}
+		render.JSON(w, r, response.Error("не удалось получить билет"))
+		return storage.Ticket{}, false
+	}
+
+	if t.Status != storage.RegistrationConfirmed || t.Nonce == "" {
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, response.Error("билет выдается после подтверждения места"))
+		return storage.Ticket{}, false
+	}
+
+	return t, true
+}
@@ -0,0 +1,131 @@
+package events
+
+import (
+	"Backend/internal/storage"
+	"errors"
+	"net/url"
+	"time"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	// clockLayout - формат времени суток в запросе, в базу оно передается как timeLayout
+	clockLayout = "15:04"
+	timeLayout  = "15:04:05"
+)
+
+// Предустановленные интервалы дат
+const (
+	presetWeekend   = "weekend"
+	presetNext7Days = "next7days"
+)
+
+// parseTimeFilter заполняет фильтр по параметрам date, from, to, preset, timeFrom, timeTo и tz.
+// from и to принимают момент в RFC 3339 или дату YYYY-MM-DD, даты и время суток относятся к местному
+// времени события. tz задает часовой пояс пользователя, в котором вычисляются предустановки.
+func parseTimeFilter(query url.Values, filter *storage.EventFilter, now time.Time) error {
+	if date := query.Get("date"); date != "" {
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			return errors.New("date должен быть в формате YYYY-MM-DD")
+		}
+		filter.FromDate, filter.ToDate = date, date
+	}
+
+	if from := query.Get("from"); from != "" {
+		if err := parseBound(from, &filter.From, &filter.FromDate); err != nil {
+			return errors.New("from должен быть датой YYYY-MM-DD или временем в RFC 3339")
+		}
+	}
+
+	if to := query.Get("to"); to != "" {
+		if err := parseBound(to, &filter.To, &filter.ToDate); err != nil {
+			return errors.New("to должен быть датой YYYY-MM-DD или временем в RFC 3339")
+		}
+	}
+
+	if preset := query.Get("preset"); preset != "" {
+		if query.Get("date") != "" || query.Get("from") != "" || query.Get("to") != "" {
+			return errors.New("preset нельзя сочетать с date, from и to")
+		}
+
+		tz := query.Get("tz")
+		if tz == "" {
+			tz = storage.DefaultTimeZone
+		}
+		loc, err := time.LoadLocation(tz)
+		if err != nil || tz == "Local" {
+			return errors.New("tz должен быть часовым поясом IANA, например Europe/Moscow")
+		}
+
+		first, last, err := presetDates(preset, now.In(loc))
+		if err != nil {
+			return err
+		}
+
+		// Уже начавшиеся события в подборки не попадают
+		filter.From = &now
+		filter.FromDate = first.Format(dateLayout)
+		filter.ToDate = last.Format(dateLayout)
+	}
+
+	var err error
+	if filter.TimeFrom, err = parseClock(query.Get("timeFrom")); err != nil {
+		return errors.New("timeFrom должен быть в формате HH:MM")
+	}
+	if filter.TimeTo, err = parseClock(query.Get("timeTo")); err != nil {
+		return errors.New("timeTo должен быть в формате HH:MM")
+	}
+
+	return nil
+}
+
+// parseBound разбирает границу интервала: дата записывается в date, момент времени - в instant
+func parseBound(value string, instant **time.Time, date *string) error {
+	if _, err := time.Parse(dateLayout, value); err == nil {
+		*date = value
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return err
+	}
+	*instant = &t
+
+	return nil
+}
+
+// presetDates возвращает первый и последний день предустановки относительно today
+func presetDates(preset string, today time.Time) (time.Time, time.Time, error) {
+	switch preset {
+	case presetWeekend:
+		// В выходные подборка начинается с сегодняшнего дня
+		switch today.Weekday() {
+		case time.Saturday:
+			return today, today.AddDate(0, 0, 1), nil
+		case time.Sunday:
+			return today, today, nil
+		default:
+			saturday := today.AddDate(0, 0, int(time.Saturday-today.Weekday()))
+			return saturday, saturday.AddDate(0, 0, 1), nil
+		}
+	case presetNext7Days:
+		return today, today.AddDate(0, 0, 6), nil
+	}
+
+	return time.Time{}, time.Time{}, errors.New("preset должен быть weekend или next7days")
+}
+
+// parseClock проверяет время суток HH:MM и возвращает его в формате для базы
+func parseClock(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	t, err := time.Parse(clockLayout, value)
+	if err != nil {
+		return "", err
+	}
+
+	return t.Format(timeLayout), nil
+}
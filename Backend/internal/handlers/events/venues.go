@@ -0,0 +1,342 @@
+package events
+
+import (
+	"Backend/internal/lib/permissions"
+	"Backend/internal/lib/response"
+	"Backend/internal/middleware/auth"
+	"Backend/internal/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	uploadVenueDir = "./uploads/venues"
+	// maxVenuePhotos ограничивает число фотографий площадки
+	maxVenuePhotos = 20
+)
+
+// VenueStorage хранит площадки
+type VenueStorage interface {
+	AddVenue(dto storage.VenueDto) (int64, error)
+	GetVenue(venueID int64) (storage.Venue, error)
+	GetVenues(filter storage.VenueFilter) ([]storage.Venue, error)
+	EditVenue(venueID int64, dto storage.VenueDto) error
+	DeleteVenue(venueID int64) error
+}
+
+// venueConflicts возвращает пересечения сохраненного события с другими событиями его площадки.
+// Пересечение не мешает сохранить событие, поэтому ошибка проверки только записывается в лог.
+func venueConflicts(log *slog.Logger, eventStorage EventStorage, dto storage.EventCreateDto, eventID int64) []storage.VenueConflict {
+	const op = "handlers.events.venueConflicts"
+
+	if dto.VenueID == nil {
+		return nil
+	}
+
+	conflicts, err := eventStorage.GetVenueConflicts(eventID)
+	if err != nil {
+		log.Error(op, "failed to check venue conflicts", err)
+		return nil
+	}
+
+	return conflicts
+}
+
+// GetVenuesHandler возвращает площадки по названию или адресу. owner=me оставляет площадки автора запроса.
+func GetVenuesHandler(log *slog.Logger, venueStorage VenueStorage, validate *validator.Validate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.GetVenues"
+
+		query := r.URL.Query()
+		filter := storage.VenueFilter{Search: query.Get("search")}
+
+		if limit := query.Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n < 1 || n > storage.MaxPageLimit {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error(fmt.Sprintf("limit должен быть от 1 до %d", storage.MaxPageLimit)))
+				return
+			}
+			filter.Limit = n
+		}
+
+		if query.Get("owner") == "me" {
+			user, ok := auth.UserFromContext(r.Context())
+			if !ok {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, response.ErrorWithCode(response.CodeUnauthorized, "требуется авторизация"))
+				return
+			}
+			filter.OwnerUserID = user.ID
+		}
+
+		venues, err := venueStorage.GetVenues(filter)
+		if err != nil {
+			log.Error(op, "failed to get venues", err)
+			render.JSON(w, r, response.Error("не удалось получить площадки"))
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK(), Venues: venues})
+	}
+}
+
+// GetVenueHandler возвращает площадку
+func GetVenueHandler(log *slog.Logger, venueStorage VenueStorage, validate *validator.Validate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.GetVenue"
+
+		venueID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid venue ID", http.StatusBadRequest)
+			return
+		}
+
+		venue, err := venueStorage.GetVenue(venueID)
+		if err != nil {
+			log.Error(op, "failed to get venue", err)
+			renderVenueError(w, r, err, "не удалось получить площадку")
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK(), Venue: &venue})
+	}
+}
+
+// CreateVenueHandler создает площадку. Данные передаются в поле venue multipart-формы,
+// фотографии - в полях photos.
+func CreateVenueHandler(log *slog.Logger, venueStorage VenueStorage, validate *validator.Validate, geocoderClient Geocoder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.CreateVenue"
+
+		dto, ok := decodeVenueForm(w, r, log, validate, op)
+		if !ok {
+			return
+		}
+
+		user, _ := auth.UserFromContext(r.Context())
+		dto.OwnerUserID = user.ID
+
+		// Ссылки на фотографии при создании появляются только из загруженных файлов
+		dto.Photos = nil
+		if !saveVenuePhotos(w, r, log, &dto, op) {
+			return
+		}
+
+		if dto.Location == nil {
+			dto.Location = geocode(r.Context(), log, geocoderClient, dto.Address)
+		}
+
+		id, err := venueStorage.AddVenue(dto)
+		if err != nil {
+			log.Error(op, "failed to add venue", err)
+			render.JSON(w, r, response.Error("не удалось создать площадку"))
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK(), VenueId: id})
+	}
+}
+
+// UpdateVenueHandler изменяет площадку. В photos перечисляются оставляемые фотографии,
+// новые загружаются в полях photos формы и добавляются в конец.
+func UpdateVenueHandler(log *slog.Logger, venueStorage VenueStorage, validate *validator.Validate, geocoderClient Geocoder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.UpdateVenue"
+
+		venue, ok := authorizeVenue(w, r, log, venueStorage, permissions.ActionEditVenue)
+		if !ok {
+			return
+		}
+
+		dto, ok := decodeVenueForm(w, r, log, validate, op)
+		if !ok {
+			return
+		}
+
+		// Оставить можно только фотографии самой площадки
+		kept := make([]string, 0, len(dto.Photos))
+		for _, photo := range dto.Photos {
+			if slices.Contains(venue.Photos, photo) && !slices.Contains(kept, photo) {
+				kept = append(kept, photo)
+			}
+		}
+		dto.Photos = kept
+
+		if !saveVenuePhotos(w, r, log, &dto, op) {
+			return
+		}
+
+		// Координаты сохраняются, пока не изменился адрес
+		if dto.Location == nil {
+			if dto.Address == venue.Address {
+				dto.Location = venue.Location
+			} else {
+				dto.Location = geocode(r.Context(), log, geocoderClient, dto.Address)
+			}
+		}
+
+		if err := venueStorage.EditVenue(venue.ID, dto); err != nil {
+			log.Error(op, "failed to edit venue", err)
+			renderVenueError(w, r, err, "не удалось изменить площадку")
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK()})
+	}
+}
+
+// DeleteVenueHandler удаляет площадку. Ее события остаются с прежним адресом.
+func DeleteVenueHandler(log *slog.Logger, venueStorage VenueStorage, validate *validator.Validate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.DeleteVenue"
+
+		venue, ok := authorizeVenue(w, r, log, venueStorage, permissions.ActionDeleteVenue)
+		if !ok {
+			return
+		}
+
+		if err := venueStorage.DeleteVenue(venue.ID); err != nil {
+			log.Error(op, "failed to delete venue", err)
+			renderVenueError(w, r, err, "не удалось удалить площадку")
+			return
+		}
+
+		render.JSON(w, r, Response{Response: response.OK()})
+	}
+}
+
+// authorizeVenue загружает площадку из URL и проверяет, что автор запроса может выполнить над ней действие.
+// При отказе ответ уже записан и возвращается false.
+func authorizeVenue(w http.ResponseWriter, r *http.Request, log *slog.Logger, venueStorage VenueStorage, action permissions.Action) (storage.Venue, bool) {
+	const op = "handlers.events.authorizeVenue"
+
+	venueID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid venue ID", http.StatusBadRequest)
+		return storage.Venue{}, false
+	}
+
+	venue, err := venueStorage.GetVenue(venueID)
+	if err != nil {
+		log.Error(op, "failed to get venue", err)
+		renderVenueError(w, r, err, "не удалось получить площадку")
+		return storage.Venue{}, false
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	if !permissions.CanOnVenue(user, venue, action) {
+		forbidden(w, r)
+		return storage.Venue{}, false
+	}
+
+	return venue, true
+}
+
+// decodeVenueForm разбирает и проверяет данные площадки из multipart-формы.
+// При ошибке ответ уже записан и возвращается false.
+func decodeVenueForm(w http.ResponseWriter, r *http.Request, log *slog.Logger, validate *validator.Validate, op string) (storage.VenueDto, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		log.Error(op, "failed to parse multipart form", err)
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("Ошибка при обработке формы"))
+		return storage.VenueDto{}, false
+	}
+
+	var dto storage.VenueDto
+	if err := json.Unmarshal([]byte(r.FormValue("venue")), &dto); err != nil {
+		log.Error(op, "failed to decode venue JSON", err)
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("Некорректный формат данных площадки"))
+		return storage.VenueDto{}, false
+	}
+
+	if err := validate.Struct(dto); err != nil {
+		log.Error(op, "validation failed", err)
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("Ошибка валидации данных площадки"))
+		return storage.VenueDto{}, false
+	}
+
+	return dto, true
+}
+
+// saveVenuePhotos сохраняет загруженные фотографии и добавляет ссылки на них в dto.
+// При ошибке ответ уже записан и возвращается false.
+func saveVenuePhotos(w http.ResponseWriter, r *http.Request, log *slog.Logger, dto *storage.VenueDto, op string) bool {
+	files := r.MultipartForm.File["photos"]
+	if len(dto.Photos)+len(files) > maxVenuePhotos {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error(fmt.Sprintf("у площадки может быть не больше %d фотографий", maxVenuePhotos)))
+		return false
+	}
+
+	if len(files) == 0 {
+		return true
+	}
+
+	if err := os.MkdirAll(uploadVenueDir, os.ModePerm); err != nil {
+		log.Error(op, "failed to create upload directory", err)
+		render.JSON(w, r, response.Error("Ошибка сервера при сохранении файла"))
+		return false
+	}
+
+	for _, fileHeader := range files {
+		filename, err := saveUploadedFile(fileHeader, uploadVenueDir)
+		if err != nil {
+			log.Error(op, "failed to save photo", err)
+			render.JSON(w, r, response.Error("Ошибка при сохранении файла"))
+			return false
+		}
+		dto.Photos = append(dto.Photos, "/uploads/venues/"+filename)
+	}
+
+	return true
+}
+
+// saveUploadedFile копирует загруженный файл в dir под случайным именем и возвращает это имя
+func saveUploadedFile(fileHeader *multipart.FileHeader, dir string) (string, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	filename := generateRandomFilename(fileHeader.Filename)
+
+	dst, err := os.Create(filepath.Join(dir, filename))
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, file); err != nil {
+		return "", err
+	}
+
+	return filename, nil
+}
+
+func renderVenueError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	if errors.Is(err, storage.ErrVenueNotFound) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, response.Error("площадка не найдена"))
+		return
+	}
+	render.JSON(w, r, response.Error(message))
+}
@@ -0,0 +1,120 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// kmPerDegree - длина градуса широты в километрах
+const kmPerDegree = 111.32
+
+var (
+	ErrInvalidPoint = errors.New("invalid point")
+	ErrInvalidBBox  = errors.New("invalid bounding box")
+)
+
+// Point - географические координаты в градусах
+type Point struct {
+	Lat float64 `json:"lat" validate:"min=-90,max=90"`
+	Lng float64 `json:"lng" validate:"min=-180,max=180"`
+}
+
+// BBox - прямоугольник на карте. Если West больше East, прямоугольник пересекает 180-й меридиан.
+type BBox struct {
+	South float64
+	West  float64
+	North float64
+	East  float64
+}
+
+// ParsePoint разбирает координаты в формате "lat,lng"
+func ParsePoint(value string) (Point, error) {
+	parts, err := parseFloats(value, 2)
+	if err != nil {
+		return Point{}, ErrInvalidPoint
+	}
+
+	p := Point{Lat: parts[0], Lng: parts[1]}
+	if !p.Valid() {
+		return Point{}, ErrInvalidPoint
+	}
+
+	return p, nil
+}
+
+// Valid сообщает, что координаты лежат в допустимых пределах
+func (p Point) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lng >= -180 && p.Lng <= 180
+}
+
+// ParseBBox разбирает прямоугольник в формате "west,south,east,north", как в GeoJSON
+func ParseBBox(value string) (BBox, error) {
+	parts, err := parseFloats(value, 4)
+	if err != nil {
+		return BBox{}, ErrInvalidBBox
+	}
+
+	b := BBox{West: parts[0], South: parts[1], East: parts[2], North: parts[3]}
+	if !(Point{Lat: b.South, Lng: b.West}).Valid() || !(Point{Lat: b.North, Lng: b.East}).Valid() || b.South > b.North {
+		return BBox{}, ErrInvalidBBox
+	}
+
+	return b, nil
+}
+
+// Around возвращает прямоугольник, описанный вокруг круга радиусом radiusKm с центром center.
+// Он грубо отсекает далекие точки перед точным расчетом расстояния.
+func Around(center Point, radiusKm float64) BBox {
+	dLat := radiusKm / kmPerDegree
+
+	b := BBox{
+		South: math.Max(center.Lat-dLat, -90),
+		North: math.Min(center.Lat+dLat, 90),
+		West:  -180,
+		East:  180,
+	}
+
+	// У полюсов круг покрывает все долготы
+	cos := math.Cos(center.Lat * math.Pi / 180)
+	if b.South > -90 && b.North < 90 && cos > 0 {
+		dLng := radiusKm / (kmPerDegree * cos)
+		if dLng < 180 {
+			b.West = normalizeLng(center.Lng - dLng)
+			b.East = normalizeLng(center.Lng + dLng)
+		}
+	}
+
+	return b
+}
+
+// normalizeLng приводит долготу к диапазону [-180, 180]
+func normalizeLng(lng float64) float64 {
+	switch {
+	case lng < -180:
+		return lng + 360
+	case lng > 180:
+		return lng - 360
+	default:
+		return lng
+	}
+}
+
+func parseFloats(value string, n int) ([]float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != n {
+		return nil, errors.New("unexpected number of values")
+	}
+
+	result := make([]float64, n)
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, errors.New("invalid number")
+		}
+		result[i] = f
+	}
+
+	return result, nil
+}
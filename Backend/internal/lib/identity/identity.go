@@ -0,0 +1,13 @@
+package identity
+
+// RoleAdmin - роль учетной записи администратора в AuthService. Роли внутри события определяет пакет permissions.
+const RoleAdmin = "admin"
+
+// User - аутентифицированный пользователь, извлечённый из JWT
+type User struct {
+	ID            int64
+	Email         string
+	EmailVerified bool
+	Role          string
+	SessionID     string
+}
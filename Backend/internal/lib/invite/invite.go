@@ -0,0 +1,84 @@
+package invite
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid invite token")
+	ErrExpired      = errors.New("invite token expired")
+)
+
+// Signer выпускает и проверяет ссылки-приглашения на событие. Токен содержит событие, версию
+// ссылок события и срок действия и подписан HMAC-SHA256, поэтому хранить выданные ссылки не нужно.
+// Увеличение версии у события отзывает все выданные ранее ссылки.
+type Signer struct {
+	secret []byte
+}
+
+func New(secret string) *Signer {
+	return &Signer{secret: []byte(secret)}
+}
+
+// Sign возвращает токен приглашения на событие, действительный до expires
+func (s *Signer) Sign(eventID int64, version int, expires time.Time) string {
+	payload := fmt.Sprintf("%d.%d.%d", eventID, version, expires.Unix())
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+// Verify проверяет, что токен выпущен для события с текущей версией ссылок и еще не истек
+func (s *Signer) Verify(token string, eventID int64, version int, now time.Time) error {
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	if !hmac.Equal(mac, s.mac(string(payload))) {
+		return ErrInvalidToken
+	}
+
+	parts := strings.Split(string(payload), ".")
+	if len(parts) != 3 {
+		return ErrInvalidToken
+	}
+
+	tokenEventID, err1 := strconv.ParseInt(parts[0], 10, 64)
+	tokenVersion, err2 := strconv.Atoi(parts[1])
+	expires, err3 := strconv.ParseInt(parts[2], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return ErrInvalidToken
+	}
+
+	if tokenEventID != eventID || tokenVersion != version {
+		return ErrInvalidToken
+	}
+
+	if now.Unix() >= expires {
+		return ErrExpired
+	}
+
+	return nil
+}
+
+func (s *Signer) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
@@ -0,0 +1,50 @@
+package invite
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	signer := New("secret")
+	now := time.Date(2025, time.January, 6, 19, 0, 0, 0, time.UTC)
+	expires := now.Add(time.Hour)
+	token := signer.Sign(42, 3, expires)
+	payload, mac, _ := strings.Cut(token, ".")
+
+	tests := []struct {
+		name    string
+		token   string
+		eventID int64
+		version int
+		now     time.Time
+		wantErr error
+	}{
+		{name: "valid", token: token, eventID: 42, version: 3, now: now},
+		{name: "just before expiry", token: token, eventID: 42, version: 3, now: expires.Add(-time.Second)},
+		{name: "at expiry", token: token, eventID: 42, version: 3, now: expires, wantErr: ErrExpired},
+		{name: "other event", token: token, eventID: 43, version: 3, now: now, wantErr: ErrInvalidToken},
+		{name: "revoked version", token: token, eventID: 42, version: 4, now: now, wantErr: ErrInvalidToken},
+		{name: "other secret", token: New("other").Sign(42, 3, expires), eventID: 42, version: 3, now: now, wantErr: ErrInvalidToken},
+		{
+			name:    "extended expiry",
+			token:   base64.RawURLEncoding.EncodeToString([]byte("42.3.4102444800")) + "." + mac,
+			eventID: 42, version: 3, now: now, wantErr: ErrInvalidToken,
+		},
+		{name: "without mac", token: payload, eventID: 42, version: 3, now: now, wantErr: ErrInvalidToken},
+		{name: "not base64", token: "!!!." + mac, eventID: 42, version: 3, now: now, wantErr: ErrInvalidToken},
+		{name: "empty", token: "", eventID: 42, version: 3, now: now, wantErr: ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := signer.Verify(tt.token, tt.eventID, tt.version, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
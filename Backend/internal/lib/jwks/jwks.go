@@ -0,0 +1,162 @@
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval ограничивает частоту загрузки JWKS при запросе неизвестного kid,
+// чтобы токены с произвольным kid не превращались в поток запросов к AuthService
+const minRefreshInterval = 30 * time.Second
+
+var ErrUnknownKey = errors.New("unknown key id")
+
+// Cache загружает открытые ключи AuthService из JWKS и хранит их в памяти
+type Cache struct {
+	url        string
+	ttl        time.Duration
+	httpClient *http.Client
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+type keySet struct {
+	Keys []jwk `json:"keys"`
+}
+
+func New(url string, ttl time.Duration) *Cache {
+	return &Cache{
+		url:        url,
+		ttl:        ttl,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		keys:       make(map[string]crypto.PublicKey),
+	}
+}
+
+// Key возвращает открытый ключ по kid. Набор ключей перечитывается по истечении ttl
+// или когда встречается неизвестный kid (например, после ротации ключей).
+func (c *Cache) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	const op = "jwks.Key"
+
+	c.mu.RLock()
+	key, ok := c.keys[kid]
+	age := time.Since(c.fetchedAt)
+	c.mu.RUnlock()
+
+	if ok && age < c.ttl {
+		return key, nil
+	}
+
+	if ok || age >= minRefreshInterval {
+		if err := c.refresh(ctx); err != nil {
+			// Если AuthService недоступен, продолжаем доверять уже известному ключу
+			if ok {
+				return key, nil
+			}
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		c.mu.RLock()
+		key, ok = c.keys[kid]
+		c.mu.RUnlock()
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrUnknownKey, kid)
+	}
+
+	return key, nil
+}
+
+func (c *Cache) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var set keySet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			// Неподдерживаемые ключи пропускаем, остальные остаются пригодными
+			continue
+		}
+		keys[k.KeyID] = key
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+
+		return ed25519.PublicKey(x), nil
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
@@ -0,0 +1,130 @@
+package permissions
+
+import (
+	"Backend/internal/lib/identity"
+	"Backend/internal/storage"
+)
+
+// Role - роль пользователя по отношению к конкретному событию
+type Role string
+
+const (
+	RoleAttendee    Role = "attendee"
+	RoleOrganizer   Role = "organizer"
+	RoleCoOrganizer Role = "co-organizer"
+	RoleCheckIn     Role = "checkin"
+	RoleAdmin       Role = "admin"
+)
+
+// Action - действие над событием, требующее проверки прав
+type Action string
+
+const (
+	ActionEditEvent          Action = "event:edit"
+	ActionDeleteEvent        Action = "event:delete"
+	ActionPublishEvent       Action = "event:publish"
+	ActionCancelEvent        Action = "event:cancel"
+	ActionViewAttendees      Action = "event:view_attendees"
+	ActionCancelRegistration Action = "registration:cancel"
+	ActionCheckIn            Action = "event:check_in"
+	ActionManageStaff        Action = "event:manage_staff"
+	ActionTransferOwnership  Action = "event:transfer"
+	ActionInvite             Action = "event:invite"
+	ActionReviewApplications Action = "registration:review"
+	ActionEditVenue          Action = "venue:edit"
+	ActionDeleteVenue        Action = "venue:delete"
+)
+
+var rolePermissions = map[Role]map[Action]bool{
+	RoleAdmin: {
+		ActionEditEvent:          true,
+		ActionDeleteEvent:        true,
+		ActionPublishEvent:       true,
+		ActionCancelEvent:        true,
+		ActionViewAttendees:      true,
+		ActionCancelRegistration: true,
+		ActionCheckIn:            true,
+		ActionManageStaff:        true,
+		ActionTransferOwnership:  true,
+		ActionInvite:             true,
+		ActionReviewApplications: true,
+		ActionEditVenue:          true,
+		ActionDeleteVenue:        true,
+	},
+	RoleOrganizer: {
+		ActionEditEvent:          true,
+		ActionDeleteEvent:        true,
+		ActionPublishEvent:       true,
+		ActionCancelEvent:        true,
+		ActionViewAttendees:      true,
+		ActionCancelRegistration: true,
+		ActionCheckIn:            true,
+		ActionManageStaff:        true,
+		ActionTransferOwnership:  true,
+		ActionInvite:             true,
+		ActionReviewApplications: true,
+		ActionEditVenue:          true,
+		ActionDeleteVenue:        true,
+	},
+	RoleCoOrganizer: {
+		ActionEditEvent:          true,
+		ActionViewAttendees:      true,
+		ActionCheckIn:            true,
+		ActionInvite:             true,
+		ActionReviewApplications: true,
+	},
+	RoleCheckIn: {
+		ActionViewAttendees: true,
+		ActionCheckIn:       true,
+	},
+	RoleAttendee: {},
+}
+
+// EventRole определяет роль пользователя по отношению к событию.
+// Администратор из токена имеет приоритет над ролью внутри события.
+func EventRole(user identity.User, event storage.Event) Role {
+	if user.Role == identity.RoleAdmin {
+		return RoleAdmin
+	}
+
+	if event.CreatorUserID == user.ID {
+		return RoleOrganizer
+	}
+
+	// Роли команды загружаются вместе со страницей события и действуют после принятия приглашения
+	for _, organizer := range event.Organizers {
+		if organizer.UserID == user.ID && organizer.Role != storage.StaffOwner && organizer.Status == storage.StaffAccepted {
+			return Role(organizer.Role)
+		}
+	}
+
+	return RoleAttendee
+}
+
+// Can сообщает, разрешено ли роли выполнять действие
+func Can(role Role, action Action) bool {
+	return rolePermissions[role][action]
+}
+
+// CanOnEvent сообщает, может ли пользователь выполнить действие над событием
+func CanOnEvent(user identity.User, event storage.Event, action Action) bool {
+	return Can(EventRole(user, event), action)
+}
+
+// VenueRole определяет роль пользователя по отношению к площадке: ее владелец распоряжается ей как организатор
+func VenueRole(user identity.User, venue storage.Venue) Role {
+	if user.Role == identity.RoleAdmin {
+		return RoleAdmin
+	}
+
+	if venue.OwnerUserID == user.ID {
+		return RoleOrganizer
+	}
+
+	return RoleAttendee
+}
+
+// CanOnVenue сообщает, может ли пользователь выполнить действие над площадкой
+func CanOnVenue(user identity.User, venue storage.Venue, action Action) bool {
+	return Can(VenueRole(user, venue), action)
+}
@@ -3,6 +3,7 @@ package response
 type Response struct {
 	Status string `json:"status"`
 	Error  string `json:"error,omitempty"`
+	Code   string `json:"code,omitempty"`
 }
 
 const (
@@ -10,10 +11,21 @@ const (
 	StatusError = "Error"
 )
 
+// Стабильные коды ошибок, на которые может опираться клиент
+const (
+	CodeUnauthorized     = "UNAUTHORIZED"
+	CodeForbidden        = "FORBIDDEN"
+	CodeEmailNotVerified = "EMAIL_NOT_VERIFIED"
+)
+
 func OK() Response {
 	return Response{Status: StatusOK}
 }
 
 func Error(msg string) Response {
-	return Response{StatusError, msg}
+	return Response{Status: StatusError, Error: msg}
+}
+
+func ErrorWithCode(code, msg string) Response {
+	return Response{Status: StatusError, Error: msg, Code: code}
 }
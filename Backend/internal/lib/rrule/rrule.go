@@ -0,0 +1,286 @@
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency - частота повторения правила
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// untilLayout - формат UTC-даты в UNTIL и EXDATE по RFC 5545
+const untilLayout = "20060102T150405Z"
+
+// maxIterations защищает от бесконечного перебора правил, которые почти не дают повторений
+const maxIterations = 100000
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Rule - подмножество RRULE из RFC 5545: FREQ (DAILY, WEEKLY, MONTHLY), INTERVAL, COUNT, UNTIL,
+// BYDAY для еженедельных и BYMONTHDAY для ежемесячных правил. Неделя начинается с понедельника.
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	// Count и Until ограничивают правило, нулевые значения означают бесконечное повторение
+	Count      int
+	Until      time.Time
+	ByDay      []time.Weekday
+	ByMonthDay []int
+}
+
+// Parse разбирает значение RRULE, например "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10".
+// Префикс "RRULE:" допускается.
+func Parse(value string) (Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return Rule{}, fmt.Errorf("%w: empty rule", ErrInvalidRule)
+	}
+
+	rule := Rule{Interval: 1}
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return Rule{}, fmt.Errorf("%w: malformed part %q", ErrInvalidRule, part)
+		}
+		key = strings.ToUpper(key)
+		if seen[key] {
+			return Rule{}, fmt.Errorf("%w: duplicate %s", ErrInvalidRule, key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			switch Frequency(strings.ToUpper(val)) {
+			case Daily, Weekly, Monthly:
+				rule.Freq = Frequency(strings.ToUpper(val))
+			default:
+				return Rule{}, fmt.Errorf("%w: unsupported frequency %q", ErrInvalidRule, val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return Rule{}, fmt.Errorf("%w: invalid interval %q", ErrInvalidRule, val)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return Rule{}, fmt.Errorf("%w: invalid count %q", ErrInvalidRule, val)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := ParseDate(val)
+			if err != nil {
+				return Rule{}, fmt.Errorf("%w: invalid until %q", ErrInvalidRule, val)
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, day := range strings.Split(strings.ToUpper(val), ",") {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return Rule{}, fmt.Errorf("%w: unsupported weekday %q", ErrInvalidRule, day)
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return Rule{}, fmt.Errorf("%w: invalid month day %q", ErrInvalidRule, day)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		default:
+			return Rule{}, fmt.Errorf("%w: unsupported part %s", ErrInvalidRule, key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return Rule{}, fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return Rule{}, fmt.Errorf("%w: COUNT and UNTIL are mutually exclusive", ErrInvalidRule)
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly {
+		return Rule{}, fmt.Errorf("%w: BYDAY is supported only for weekly rules", ErrInvalidRule)
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != Monthly {
+		return Rule{}, fmt.Errorf("%w: BYMONTHDAY is supported only for monthly rules", ErrInvalidRule)
+	}
+
+	return rule, nil
+}
+
+// ParseDate разбирает дату в формате EXDATE/UNTIL, например "20250107T190000Z"
+func ParseDate(value string) (time.Time, error) {
+	return time.Parse(untilLayout, value)
+}
+
+// FormatDate форматирует дату в формате EXDATE/UNTIL
+func FormatDate(t time.Time) string {
+	return t.UTC().Format(untilLayout)
+}
+
+// Bounded сообщает, ограничено ли правило через COUNT или UNTIL
+func (r Rule) Bounded() bool {
+	return r.Count > 0 || !r.Until.IsZero()
+}
+
+// String возвращает правило в каноническом виде RRULE
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+FormatDate(r.Until))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, weekday := range r.ByDay {
+			for name, day := range weekdays {
+				if day == weekday {
+					days = append(days, name)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, 0, len(r.ByMonthDay))
+		for _, day := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Occurrences возвращает повторения правила начиная с dtstart и не позже before,
+// но не больше limit, если он положителен. Повторения вычисляются в часовом поясе dtstart, поэтому
+// время события сохраняется при переходе на летнее время.
+// Второе значение сообщает, что правило исчерпано и повторений после before не будет.
+func (r Rule) Occurrences(dtstart, before time.Time, limit int) ([]time.Time, bool) {
+	var result []time.Time
+	generated := 0
+
+	for period := 0; period < maxIterations; period++ {
+		for _, t := range r.candidates(dtstart, period) {
+			if t.Before(dtstart) {
+				continue
+			}
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return result, true
+			}
+			if t.After(before) {
+				return result, false
+			}
+			if limit > 0 && len(result) == limit {
+				return result, false
+			}
+
+			result = append(result, t)
+			generated++
+
+			if r.Count > 0 && generated == r.Count {
+				return result, true
+			}
+		}
+	}
+
+	return result, true
+}
+
+// candidates возвращает возможные даты повторения в периоде с номером n в порядке возрастания
+func (r Rule) candidates(dtstart time.Time, n int) []time.Time {
+	hour, minute, sec := dtstart.Clock()
+	loc := dtstart.Location()
+	step := n * r.Interval
+
+	switch r.Freq {
+	case Daily:
+		return []time.Time{dtstart.AddDate(0, 0, step)}
+
+	case Weekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{dtstart.Weekday()}
+		}
+
+		// Понедельник недели dtstart
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		monday := time.Date(dtstart.Year(), dtstart.Month(), dtstart.Day()-offset+7*step, hour, minute, sec, 0, loc)
+
+		result := make([]time.Time, 0, len(days))
+		for _, day := range days {
+			result = append(result, monday.AddDate(0, 0, (int(day)+6)%7))
+		}
+		return sortUnique(result)
+
+	case Monthly:
+		year, month := dtstart.Year(), dtstart.Month()+time.Month(step)
+		// Нормализуем месяц через первое число, чтобы не переполнить короткие месяцы
+		first := time.Date(year, month, 1, hour, minute, sec, 0, loc)
+		length := first.AddDate(0, 1, -1).Day()
+
+		days := r.ByMonthDay
+		if len(days) == 0 {
+			days = []int{dtstart.Day()}
+		}
+
+		result := make([]time.Time, 0, len(days))
+		for _, day := range days {
+			if day < 0 {
+				day = length + day + 1
+			}
+			// Несуществующие дни месяца пропускаются, как требует RFC 5545
+			if day < 1 || day > length {
+				continue
+			}
+			result = append(result, first.AddDate(0, 0, day-1))
+		}
+		return sortUnique(result)
+	}
+
+	return nil
+}
+
+// sortUnique упорядочивает даты и убирает повторы, возникающие из дублей в BYDAY и BYMONTHDAY
+func sortUnique(times []time.Time) []time.Time {
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	result := times[:0]
+	for i, t := range times {
+		if i == 0 || !t.Equal(times[i-1]) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
@@ -0,0 +1,248 @@
+package rrule
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "weekly with days", value: "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10", want: "FREQ=WEEKLY;COUNT=10;BYDAY=MO,WE"},
+		{name: "prefix and lower case", value: " RRULE:freq=daily;interval=2 ", want: "FREQ=DAILY;INTERVAL=2"},
+		{name: "monthly with negative day", value: "FREQ=MONTHLY;BYMONTHDAY=1,-1", want: "FREQ=MONTHLY;BYMONTHDAY=1,-1"},
+		{name: "until", value: "FREQ=DAILY;UNTIL=20250107T190000Z", want: "FREQ=DAILY;UNTIL=20250107T190000Z"},
+		{name: "interval one is omitted", value: "FREQ=DAILY;INTERVAL=1", want: "FREQ=DAILY"},
+		{name: "empty", value: "", wantErr: true},
+		{name: "without freq", value: "COUNT=3", wantErr: true},
+		{name: "unsupported freq", value: "FREQ=YEARLY", wantErr: true},
+		{name: "malformed part", value: "FREQ=DAILY;COUNT", wantErr: true},
+		{name: "duplicate part", value: "FREQ=DAILY;FREQ=WEEKLY", wantErr: true},
+		{name: "zero interval", value: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{name: "zero count", value: "FREQ=DAILY;COUNT=0", wantErr: true},
+		{name: "count with until", value: "FREQ=DAILY;COUNT=3;UNTIL=20250107T190000Z", wantErr: true},
+		{name: "until without zone", value: "FREQ=DAILY;UNTIL=20250107", wantErr: true},
+		{name: "unknown weekday", value: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		{name: "byday for daily", value: "FREQ=DAILY;BYDAY=MO", wantErr: true},
+		{name: "bymonthday for weekly", value: "FREQ=WEEKLY;BYMONTHDAY=1", wantErr: true},
+		{name: "month day out of range", value: "FREQ=MONTHLY;BYMONTHDAY=32", wantErr: true},
+		{name: "zero month day", value: "FREQ=MONTHLY;BYMONTHDAY=0", wantErr: true},
+		{name: "unsupported part", value: "FREQ=DAILY;BYHOUR=10", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.value)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRule) {
+					t.Fatalf("Parse(%q) error = %v, want ErrInvalidRule", tt.value, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) unexpected error: %v", tt.value, err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Errorf("Parse(%q).String() = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	utc := func(value string) time.Time {
+		d, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	local := func(value string) time.Time {
+		d, err := time.ParseInLocation("2006-01-02T15:04", value, berlin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	far := utc("2030-01-01T00:00:00Z")
+
+	tests := []struct {
+		name          string
+		rule          string
+		dtstart       time.Time
+		before        time.Time
+		limit         int
+		want          []time.Time
+		wantExhausted bool
+	}{
+		{
+			name:    "daily count",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: utc("2025-01-06T10:00:00Z"),
+			before:  far,
+			want: []time.Time{
+				utc("2025-01-06T10:00:00Z"), utc("2025-01-07T10:00:00Z"), utc("2025-01-08T10:00:00Z"),
+			},
+			wantExhausted: true,
+		},
+		{
+			name:    "daily interval",
+			rule:    "FREQ=DAILY;INTERVAL=2;COUNT=3",
+			dtstart: utc("2025-01-06T10:00:00Z"),
+			before:  far,
+			want: []time.Time{
+				utc("2025-01-06T10:00:00Z"), utc("2025-01-08T10:00:00Z"), utc("2025-01-10T10:00:00Z"),
+			},
+			wantExhausted: true,
+		},
+		{
+			name:    "until is inclusive",
+			rule:    "FREQ=DAILY;UNTIL=20250108T100000Z",
+			dtstart: utc("2025-01-06T10:00:00Z"),
+			before:  far,
+			want: []time.Time{
+				utc("2025-01-06T10:00:00Z"), utc("2025-01-07T10:00:00Z"), utc("2025-01-08T10:00:00Z"),
+			},
+			wantExhausted: true,
+		},
+		{
+			name:    "unbounded rule stops at before",
+			rule:    "FREQ=DAILY",
+			dtstart: utc("2025-01-06T10:00:00Z"),
+			before:  utc("2025-01-07T10:00:00Z"),
+			want:    []time.Time{utc("2025-01-06T10:00:00Z"), utc("2025-01-07T10:00:00Z")},
+		},
+		{
+			name:    "limit",
+			rule:    "FREQ=DAILY",
+			dtstart: utc("2025-01-06T10:00:00Z"),
+			before:  far,
+			limit:   2,
+			want:    []time.Time{utc("2025-01-06T10:00:00Z"), utc("2025-01-07T10:00:00Z")},
+		},
+		{
+			name:    "weekly by day",
+			rule:    "FREQ=WEEKLY;BYDAY=WE,MO;COUNT=4",
+			dtstart: utc("2025-01-06T10:00:00Z"),
+			before:  far,
+			want: []time.Time{
+				utc("2025-01-06T10:00:00Z"), utc("2025-01-08T10:00:00Z"),
+				utc("2025-01-13T10:00:00Z"), utc("2025-01-15T10:00:00Z"),
+			},
+			wantExhausted: true,
+		},
+		{
+			name:          "weekly days before dtstart are skipped",
+			rule:          "FREQ=WEEKLY;BYDAY=MO;COUNT=2",
+			dtstart:       utc("2025-01-08T10:00:00Z"),
+			before:        far,
+			want:          []time.Time{utc("2025-01-13T10:00:00Z"), utc("2025-01-20T10:00:00Z")},
+			wantExhausted: true,
+		},
+		{
+			name:    "biweekly defaults to dtstart weekday",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=3",
+			dtstart: utc("2025-01-08T10:00:00Z"),
+			before:  far,
+			want: []time.Time{
+				utc("2025-01-08T10:00:00Z"), utc("2025-01-22T10:00:00Z"), utc("2025-02-05T10:00:00Z"),
+			},
+			wantExhausted: true,
+		},
+		{
+			name:    "monthly skips missing days",
+			rule:    "FREQ=MONTHLY;COUNT=3",
+			dtstart: utc("2025-01-31T10:00:00Z"),
+			before:  far,
+			want: []time.Time{
+				utc("2025-01-31T10:00:00Z"), utc("2025-03-31T10:00:00Z"), utc("2025-05-31T10:00:00Z"),
+			},
+			wantExhausted: true,
+		},
+		{
+			name:    "monthly last day",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3",
+			dtstart: utc("2025-01-31T10:00:00Z"),
+			before:  far,
+			want: []time.Time{
+				utc("2025-01-31T10:00:00Z"), utc("2025-02-28T10:00:00Z"), utc("2025-03-31T10:00:00Z"),
+			},
+			wantExhausted: true,
+		},
+		{
+			name:    "duplicate month days",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=28,-1;COUNT=3",
+			dtstart: utc("2025-02-01T10:00:00Z"),
+			before:  far,
+			want: []time.Time{
+				utc("2025-02-28T10:00:00Z"), utc("2025-03-28T10:00:00Z"), utc("2025-03-31T10:00:00Z"),
+			},
+			wantExhausted: true,
+		},
+		{
+			name:          "local time survives daylight saving",
+			rule:          "FREQ=WEEKLY;COUNT=2",
+			dtstart:       local("2025-03-24T19:00"),
+			before:        far,
+			want:          []time.Time{local("2025-03-24T19:00"), local("2025-03-31T19:00")},
+			wantExhausted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q) unexpected error: %v", tt.rule, err)
+			}
+
+			got, exhausted := rule.Occurrences(tt.dtstart, tt.before, tt.limit)
+			if !equalTimes(got, tt.want) {
+				t.Errorf("Occurrences() = %v, want %v", got, tt.want)
+			}
+			if exhausted != tt.wantExhausted {
+				t.Errorf("Occurrences() exhausted = %v, want %v", exhausted, tt.wantExhausted)
+			}
+		})
+	}
+}
+
+func TestDateRoundTrip(t *testing.T) {
+	date := time.Date(2025, time.January, 7, 22, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+
+	formatted := FormatDate(date)
+	if formatted != "20250107T190000Z" {
+		t.Fatalf("FormatDate() = %q, want %q", formatted, "20250107T190000Z")
+	}
+
+	parsed, err := ParseDate(formatted)
+	if err != nil {
+		t.Fatalf("ParseDate(%q) unexpected error: %v", formatted, err)
+	}
+	if !parsed.Equal(date) {
+		t.Errorf("ParseDate(%q) = %v, want %v", formatted, parsed, date)
+	}
+}
+
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
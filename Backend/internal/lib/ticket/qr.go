@@ -0,0 +1,45 @@
+package ticket
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// PNG возвращает QR-код билета в PNG со стороной size пикселей
+func PNG(code string, size int) ([]byte, error) {
+	png, err := qrcode.Encode(code, qrcode.Medium, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	return png, nil
+}
+
+// SVG возвращает QR-код билета в SVG. Модули кода рисуются одним путем, поэтому изображение масштабируется без потерь.
+func SVG(code string) ([]byte, error) {
+	qr, err := qrcode.New(code, qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+
+	bitmap := qr.Bitmap()
+	n := len(bitmap)
+
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+
+	svg := fmt.Sprintf(
+		`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+			`<rect width="%d" height="%d" fill="#fff"/><path d="%s" fill="#000"/></svg>`,
+		n, n, n, n, path.String(),
+	)
+
+	return []byte(svg), nil
+}
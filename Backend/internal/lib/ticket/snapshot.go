@@ -0,0 +1,25 @@
+package ticket
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// Hash возвращает отпечаток кода билета для выгрузки на устройства сотрудников.
+// По отпечатку устройство находит отсканированный билет без сети, а сам код из выгрузки не восстановить.
+func Hash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// SignSnapshot подписывает выгрузку билетов Ed25519. Подпись проверяется открытым ключом,
+// поэтому устройству не нужен секрет билетов.
+func (s *Signer) SignSnapshot(payload []byte) string {
+	return base64.RawURLEncoding.EncodeToString(ed25519.Sign(s.snapshotKey, payload))
+}
+
+// SnapshotPublicKey возвращает открытый ключ для проверки подписи выгрузок
+func (s *Signer) SnapshotPublicKey() string {
+	return base64.RawURLEncoding.EncodeToString(s.snapshotKey.Public().(ed25519.PublicKey))
+}
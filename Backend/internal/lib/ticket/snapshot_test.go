@@ -0,0 +1,55 @@
+package ticket
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestSignSnapshot(t *testing.T) {
+	signer := New("secret")
+	payload := []byte(`{"eventId":42}`)
+
+	key := mustDecode(t, signer.SnapshotPublicKey())
+	signature := mustDecode(t, signer.SignSnapshot(payload))
+
+	if !ed25519.Verify(key, payload, signature) {
+		t.Fatal("SignSnapshot() signature does not verify with SnapshotPublicKey()")
+	}
+	if ed25519.Verify(key, []byte(`{"eventId":43}`), signature) {
+		t.Error("SignSnapshot() signature verifies a changed payload")
+	}
+	if ed25519.Verify(mustDecode(t, New("other").SnapshotPublicKey()), payload, signature) {
+		t.Error("SignSnapshot() signature verifies with the key of another secret")
+	}
+
+	// Ключ выводится из секрета, поэтому закрепленный на устройствах ключ переживает перезапуск
+	if New("secret").SnapshotPublicKey() != signer.SnapshotPublicKey() {
+		t.Error("SnapshotPublicKey() differs for the same secret")
+	}
+}
+
+func TestHash(t *testing.T) {
+	signer := New("secret")
+	code := signer.Code(42, 7, "nonce-1")
+
+	if Hash(code) != Hash(code) {
+		t.Error("Hash() is not deterministic")
+	}
+	if Hash(code) == Hash(signer.Code(42, 7, "nonce-2")) {
+		t.Error("Hash() is the same for different codes")
+	}
+	if strings.Contains(Hash(code), "nonce-1") {
+		t.Error("Hash() reveals the ticket nonce")
+	}
+}
+
+func mustDecode(t *testing.T, value string) []byte {
+	t.Helper()
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,93 @@
+package ticket
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidCode = errors.New("invalid ticket code")
+
+// macSize - длина подписи в коде билета. 128 бит достаточно, чтобы код нельзя было подобрать,
+// и QR-код остается небольшим.
+const macSize = 16
+
+// Signer выпускает и проверяет коды билетов. Код содержит событие, участника и случайный nonce регистрации
+// и подписан HMAC-SHA256, поэтому подделать его без секрета нельзя. Смена nonce в регистрации
+// аннулирует выданный билет.
+type Signer struct {
+	secret []byte
+	// snapshotKey подписывает выгрузки билетов для отметки без сети
+	snapshotKey ed25519.PrivateKey
+}
+
+func New(secret string) *Signer {
+	seed := sha256.Sum256([]byte("snapshot:" + secret))
+	return &Signer{
+		secret:      []byte(secret),
+		snapshotKey: ed25519.NewKeyFromSeed(seed[:]),
+	}
+}
+
+// NewNonce возвращает случайный nonce для новой регистрации
+func NewNonce() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate ticket nonce: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Code возвращает код билета участника на событие
+func (s *Signer) Code(eventID, userID int64, nonce string) string {
+	payload := fmt.Sprintf("%d.%d.%s", eventID, userID, nonce)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+// Parse проверяет подпись кода и возвращает событие, участника и nonce из него.
+// Совпадение nonce с регистрацией проверяет вызывающий.
+func (s *Signer) Parse(code string) (eventID, userID int64, nonce string, err error) {
+	encodedPayload, encodedMAC, ok := strings.Cut(strings.TrimSpace(code), ".")
+	if !ok {
+		return 0, 0, "", ErrInvalidCode
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return 0, 0, "", ErrInvalidCode
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil {
+		return 0, 0, "", ErrInvalidCode
+	}
+
+	if !hmac.Equal(mac, s.mac(string(payload))) {
+		return 0, 0, "", ErrInvalidCode
+	}
+
+	parts := strings.SplitN(string(payload), ".", 3)
+	if len(parts) != 3 {
+		return 0, 0, "", ErrInvalidCode
+	}
+
+	eventID, err1 := strconv.ParseInt(parts[0], 10, 64)
+	userID, err2 := strconv.ParseInt(parts[1], 10, 64)
+	if err1 != nil || err2 != nil || parts[2] == "" {
+		return 0, 0, "", ErrInvalidCode
+	}
+
+	return eventID, userID, parts[2], nil
+}
+
+func (s *Signer) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)[:macSize]
+}
@@ -0,0 +1,52 @@
+package ticket
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	signer := New("secret")
+	code := signer.Code(42, 7, "nonce-1")
+	payload, mac, _ := strings.Cut(code, ".")
+
+	tests := []struct {
+		name      string
+		code      string
+		wantEvent int64
+		wantUser  int64
+		wantNonce string
+		wantErr   bool
+	}{
+		{name: "valid", code: code, wantEvent: 42, wantUser: 7, wantNonce: "nonce-1"},
+		{name: "surrounding spaces", code: " " + code + "\n", wantEvent: 42, wantUser: 7, wantNonce: "nonce-1"},
+		{name: "nonce with dots", code: signer.Code(1, 2, "a.b"), wantEvent: 1, wantUser: 2, wantNonce: "a.b"},
+		{name: "other secret", code: New("other").Code(42, 7, "nonce-1"), wantErr: true},
+		{name: "tampered payload", code: base64.RawURLEncoding.EncodeToString([]byte("42.8.nonce-1")) + "." + mac, wantErr: true},
+		{name: "tampered mac", code: payload + "." + base64.RawURLEncoding.EncodeToString(make([]byte, macSize)), wantErr: true},
+		{name: "without mac", code: payload, wantErr: true},
+		{name: "not base64", code: "!!!." + mac, wantErr: true},
+		{name: "empty", code: "", wantErr: true},
+		{name: "empty nonce", code: signer.Code(42, 7, ""), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventID, userID, nonce, err := signer.Parse(tt.code)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCode) {
+					t.Fatalf("Parse() error = %v, want ErrInvalidCode", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			if eventID != tt.wantEvent || userID != tt.wantUser || nonce != tt.wantNonce {
+				t.Errorf("Parse() = %d, %d, %q, want %d, %d, %q", eventID, userID, nonce, tt.wantEvent, tt.wantUser, tt.wantNonce)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package lifecycle
+
+import (
+	"Backend/internal/lib/logger/sl"
+	"context"
+	"log/slog"
+	"time"
+)
+
+type EventStorage interface {
+	CompletePastEvents(now time.Time) (int64, error)
+	ExtendSeries(now time.Time) (int, error)
+}
+
+// Completer периодически переводит прошедшие опубликованные события в статус completed
+// и создает очередные повторения серий
+type Completer struct {
+	log      *slog.Logger
+	storage  EventStorage
+	interval time.Duration
+}
+
+func New(log *slog.Logger, eventStorage EventStorage, interval time.Duration) *Completer {
+	return &Completer{
+		log:      log,
+		storage:  eventStorage,
+		interval: interval,
+	}
+}
+
+// Run завершает прошедшие события и продлевает серии сразу при запуске и затем с заданным интервалом до отмены контекста
+func (c *Completer) Run(ctx context.Context) {
+	const op = "lifecycle.Run"
+
+	log := c.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		completed, err := c.storage.CompletePastEvents(time.Now())
+		if err != nil {
+			log.Error("failed to complete past events", sl.Err(err))
+		} else if completed > 0 {
+			log.Info("past events completed", slog.Int64("count", completed))
+		}
+
+		created, err := c.storage.ExtendSeries(time.Now())
+		if err != nil {
+			log.Error("failed to extend event series", sl.Err(err))
+		}
+		if created > 0 {
+			log.Info("series occurrences created", slog.Int("count", created))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"Backend/internal/lib/identity"
+	"Backend/internal/lib/logger/sl"
+	"Backend/internal/lib/response"
+	"context"
+	"crypto"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// claims повторяет набор полей, который выпускает AuthService.generateJWT
+type claims struct {
+	UserID        int64  `json:"user_id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Role          string `json:"role"`
+	SessionID     string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// KeySource возвращает открытый ключ, которым AuthService подписал токен
+type KeySource interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// SessionChecker проверяет, не отозвана ли сессия, в рамках которой выпущен токен
+type SessionChecker interface {
+	SessionActive(ctx context.Context, sessionID, token string) (bool, error)
+}
+
+type ctxKey struct{}
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// New проверяет заголовок Authorization и кладёт пользователя в контекст запроса.
+// Запросы без токена пропускаются дальше анонимными, запросы с невалидным токеном
+// или отозванной сессией отклоняются с 401. Если sessions равен nil, отзыв сессий не проверяется.
+func New(log *slog.Logger, keys KeySource, sessions SessionChecker) func(next http.Handler) http.Handler {
+	const op = "middleware.auth.New"
+
+	log = log.With(slog.String("op", op))
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			tokenString, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok {
+				unauthorized(w, r)
+				return
+			}
+
+			user, err := parseToken(r.Context(), parser, tokenString, keys)
+			if err != nil {
+				log.Info("invalid token", sl.Err(err))
+				unauthorized(w, r)
+				return
+			}
+
+			if sessions != nil {
+				if user.SessionID == "" {
+					unauthorized(w, r)
+					return
+				}
+
+				active, err := sessions.SessionActive(r.Context(), user.SessionID, tokenString)
+				if err != nil {
+					log.Error("failed to check session", sl.Err(err))
+					render.Status(r, http.StatusServiceUnavailable)
+					render.JSON(w, r, response.Error("сервис авторизации недоступен"))
+					return
+				}
+				if !active {
+					unauthorized(w, r)
+					return
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), ctxKey{}, user)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Required отклоняет запросы без аутентифицированного пользователя с 401
+func Required(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := UserFromContext(r.Context()); !ok {
+			unauthorized(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// VerifiedEmail отклоняет запросы пользователей с неподтверждённой почтой с 403.
+// Используется после Required.
+func VerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, _ := UserFromContext(r.Context()); !user.EmailVerified {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.ErrorWithCode(response.CodeEmailNotVerified, "подтвердите адрес электронной почты"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UserFromContext возвращает пользователя, положенного в контекст middleware New
+func UserFromContext(ctx context.Context) (identity.User, bool) {
+	user, ok := ctx.Value(ctxKey{}).(identity.User)
+	return user, ok
+}
+
+func parseToken(ctx context.Context, parser *jwt.Parser, tokenString string, keys KeySource) (identity.User, error) {
+	var c claims
+
+	_, err := parser.ParseWithClaims(tokenString, &c, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return identity.User{}, err
+	}
+
+	if c.UserID <= 0 {
+		return identity.User{}, ErrInvalidToken
+	}
+
+	return identity.User{
+		ID:            c.UserID,
+		Email:         c.Email,
+		EmailVerified: c.EmailVerified,
+		Role:          c.Role,
+		SessionID:     c.SessionID,
+	}, nil
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, response.ErrorWithCode(response.CodeUnauthorized, "требуется авторизация"))
+}
@@ -0,0 +1,325 @@
+package search
+
+import (
+	"Backend/internal/lib/logger/sl"
+	"Backend/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/lang/ru"
+	"github.com/blevesearch/bleve/v2/index/scorch"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/highlight/highlighter/html"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+const (
+	// batchSize - число событий, загружаемых из базы за один запрос
+	batchSize = 500
+	// syncOverlap - окно повторного чтения изменений. Транзакция может зафиксироваться позже,
+	// чем записанное в UpdatedAt время, и без перекрытия такие изменения были бы пропущены.
+	syncOverlap = 10 * time.Second
+	// maxQueryWords ограничивает число слов запроса, каждое из которых порождает несколько подзапросов
+	maxQueryWords = 10
+	// fuzzyBoost понижает вес нечетких совпадений относительно точных
+	fuzzyBoost = 0.5
+)
+
+// ErrNotReady возвращается, пока индекс не загружен в первый раз
+var ErrNotReady = errors.New("search index is not ready")
+
+// fields - индексируемые поля и их вес при ранжировании
+var fields = []struct {
+	name  string
+	boost float64
+}{
+	{"title", 3},
+	{"tags", 2},
+	{"description", 1},
+	{"address", 1},
+}
+
+// highlightFields - поля, для которых возвращаются фрагменты с подсветкой
+var highlightFields = []string{"title", "description"}
+
+type Source interface {
+	GetEventsForIndex(after time.Time, afterID int64, limit int) ([]storage.SearchDocument, error)
+}
+
+// Hit - событие, найденное по запросу
+type Hit struct {
+	EventID int64
+	Score   float64
+	// Highlights - фрагменты полей, где совпадения обернуты в <mark>
+	Highlights map[string][]string
+}
+
+// document - представление события в индексе, имена полей берутся из тегов json
+type document struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Address     string   `json:"address"`
+	Tags        []string `json:"tags"`
+}
+
+// Index - полнотекстовый индекс событий в памяти с русской морфологией.
+// Индекс строится из базы при запуске и затем догоняет изменения по UpdatedAt.
+// Удаленные события могут оставаться в индексе до вызова Remove, поэтому результаты поиска
+// всегда дополнительно фильтруются запросом к базе.
+type Index struct {
+	log      *slog.Logger
+	source   Source
+	interval time.Duration
+	index    bleve.Index
+	notify   chan struct{}
+	ready    atomic.Bool
+
+	// synced - наибольшее время изменения среди проиндексированных событий
+	synced time.Time
+}
+
+func New(log *slog.Logger, source Source, interval time.Duration) (*Index, error) {
+	const op = "search.New"
+
+	// scorch без пути хранит индекс только в памяти. В отличие от NewMemOnly он сравнивает
+	// термы при нечетком поиске посимвольно, а не побайтово, что важно для кириллицы.
+	index, err := bleve.NewUsing("", newMapping(), scorch.Name, scorch.Name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Index{
+		log:      log,
+		source:   source,
+		interval: interval,
+		index:    index,
+		notify:   make(chan struct{}, 1),
+	}, nil
+}
+
+func newMapping() mapping.IndexMapping {
+	doc := bleve.NewDocumentMapping()
+	for _, field := range fields {
+		fm := bleve.NewTextFieldMapping()
+		fm.Analyzer = ru.AnalyzerName
+		// Хранение значений и векторов терминов нужно для подсветки
+		fm.Store = true
+		fm.IncludeTermVectors = true
+		doc.AddFieldMappingsAt(field.name, fm)
+	}
+
+	im := bleve.NewIndexMapping()
+	im.DefaultMapping = doc
+	im.DefaultAnalyzer = ru.AnalyzerName
+
+	return im
+}
+
+// Run загружает события в индекс и затем синхронизирует изменения с заданным интервалом
+// или сразу после Notify до отмены контекста
+func (i *Index) Run(ctx context.Context) {
+	const op = "search.Run"
+
+	log := i.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(i.interval)
+	defer ticker.Stop()
+
+	for {
+		indexed, err := i.sync()
+		if err != nil {
+			log.Error("failed to sync search index", sl.Err(err))
+		} else {
+			if !i.ready.Swap(true) {
+				log.Info("search index loaded", slog.Int("count", indexed))
+			} else if indexed > 0 {
+				log.Debug("search index synced", slog.Int("count", indexed))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-i.notify:
+		}
+	}
+}
+
+// Notify просит синхронизировать индекс, не дожидаясь очередного интервала
+func (i *Index) Notify() {
+	select {
+	case i.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Remove удаляет событие из индекса
+func (i *Index) Remove(eventID int64) error {
+	const op = "search.Remove"
+
+	if err := i.index.Delete(strconv.FormatInt(eventID, 10)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// sync индексирует события, измененные после последней синхронизации, и возвращает их число
+func (i *Index) sync() (int, error) {
+	after := i.synced
+	if !after.IsZero() {
+		after = after.Add(-syncOverlap)
+	}
+	var afterID int64
+	indexed := 0
+
+	for {
+		docs, err := i.source.GetEventsForIndex(after, afterID, batchSize)
+		if err != nil {
+			return indexed, err
+		}
+		if len(docs) == 0 {
+			return indexed, nil
+		}
+
+		batch := i.index.NewBatch()
+		for _, doc := range docs {
+			err := batch.Index(strconv.FormatInt(doc.ID, 10), document{
+				Title:       doc.Title,
+				Description: doc.Description,
+				Address:     doc.Address,
+				Tags:        doc.Tags,
+			})
+			if err != nil {
+				return indexed, fmt.Errorf("failed to index event %d: %w", doc.ID, err)
+			}
+		}
+		if err := i.index.Batch(batch); err != nil {
+			return indexed, fmt.Errorf("failed to apply batch: %w", err)
+		}
+
+		last := docs[len(docs)-1]
+		after, afterID = last.UpdatedAt, last.ID
+		if last.UpdatedAt.After(i.synced) {
+			i.synced = last.UpdatedAt
+		}
+		indexed += len(docs)
+
+		if len(docs) < batchSize {
+			return indexed, nil
+		}
+	}
+}
+
+// Search ищет события по тексту и возвращает не больше limit совпадений в порядке убывания релевантности
+func (i *Index) Search(text string, limit int) ([]Hit, error) {
+	const op = "search.Search"
+
+	if !i.ready.Load() {
+		return nil, ErrNotReady
+	}
+
+	q := buildQuery(text)
+	if q == nil {
+		return []Hit{}, nil
+	}
+
+	req := bleve.NewSearchRequestOptions(q, limit, 0, false)
+	req.Highlight = bleve.NewHighlightWithStyle(html.Name)
+	for _, field := range highlightFields {
+		req.Highlight.AddField(field)
+	}
+
+	result, err := i.index.Search(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	hits := make([]Hit, 0, len(result.Hits))
+	for _, match := range result.Hits {
+		id, err := strconv.ParseInt(match.ID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid document id %q: %w", op, match.ID, err)
+		}
+		hits = append(hits, Hit{
+			EventID:    id,
+			Score:      match.Score,
+			Highlights: highlights(match.Fragments),
+		})
+	}
+
+	return hits, nil
+}
+
+// highlights оставляет только фрагменты с совпадениями: для полей без совпадений
+// подсветка возвращает начало текста как есть
+func highlights(fragments map[string][]string) map[string][]string {
+	result := make(map[string][]string, len(fragments))
+	for field, values := range fragments {
+		for _, fragment := range values {
+			if strings.Contains(fragment, "<mark>") {
+				result[field] = append(result[field], fragment)
+			}
+		}
+	}
+	return result
+}
+
+// buildQuery строит запрос, в котором событие подходит, если совпало хотя бы одно слово.
+// Каждое слово ищется во всех полях точно (после стемминга) и с опечатками, точные совпадения весят больше.
+func buildQuery(text string) query.Query {
+	words := strings.Fields(strings.ToLower(text))
+	if len(words) == 0 {
+		return nil
+	}
+	if len(words) > maxQueryWords {
+		words = words[:maxQueryWords]
+	}
+
+	wordQueries := make([]query.Query, 0, len(words))
+	for _, word := range words {
+		fuzziness := fuzzinessFor(word)
+
+		var disjuncts []query.Query
+		for _, field := range fields {
+			exact := bleve.NewMatchQuery(word)
+			exact.SetField(field.name)
+			exact.SetBoost(field.boost)
+			disjuncts = append(disjuncts, exact)
+
+			if fuzziness > 0 {
+				fuzzy := bleve.NewMatchQuery(word)
+				fuzzy.SetField(field.name)
+				fuzzy.SetFuzziness(fuzziness)
+				fuzzy.SetBoost(field.boost * fuzzyBoost)
+				disjuncts = append(disjuncts, fuzzy)
+			}
+		}
+
+		wordQueries = append(wordQueries, bleve.NewDisjunctionQuery(disjuncts...))
+	}
+
+	return bleve.NewDisjunctionQuery(wordQueries...)
+}
+
+// fuzzinessFor возвращает допустимое число опечаток: короткие слова ищутся только точно,
+// иначе запрос находил бы слишком много посторонних слов
+func fuzzinessFor(word string) int {
+	switch n := utf8.RuneCountInString(word); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,108 @@
+package mysql
+
+import (
+	"Backend/internal/storage"
+	"fmt"
+	"time"
+)
+
+// ReviewApplications выносит решение по заявкам пользователей на событие с одобрением. Одобренная заявка
+// занимает свободное место или встает в лист ожидания в порядке подачи заявок, отклоненная остается
+// со статусом rejected. Пользователи без рассматриваемой заявки пропускаются.
+func (r *Storage) ReviewApplications(eventID int, userIDs []int64, approve bool, message string) ([]storage.ApplicationDecision, error) {
+	const op = "mysql.ReviewApplications"
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	event, err := lockEvent(tx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if event.status != storage.EventPublished {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrEventNotOpen)
+	}
+
+	args := []any{eventID, storage.RegistrationPending}
+	for _, id := range userIDs {
+		args = append(args, id)
+	}
+
+	rows, err := tx.Query(`
+		SELECT r.UserID, u.Email, COALESCE(r.TicketNonce, '')
+		FROM Registration r
+		JOIN User u ON u.UserID = r.UserID
+		WHERE r.EventID = ? AND r.Status = ? AND r.UserID IN (`+placeholders(len(userIDs))+`)
+		ORDER BY r.RegisteredAt, r.UserID
+		FOR UPDATE
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to query applications: %w", op, err)
+	}
+
+	var decisions []storage.ApplicationDecision
+	for rows.Next() {
+		var d storage.ApplicationDecision
+		if err := rows.Scan(&d.UserID, &d.UserEmail, &d.TicketNonce); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("%s: failed to scan application: %w", op, err)
+		}
+		decisions = append(decisions, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: failed to read applications: %w", op, err)
+	}
+
+	if len(decisions) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrApplicationNotFound)
+	}
+
+	var eventName, timeZone string
+	var eventDate time.Time
+	err = tx.QueryRow("SELECT Title, EventDate, TimeZone FROM Event WHERE EventID = ?", eventID).Scan(&eventName, &eventDate, &timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to fetch event: %w", op, err)
+	}
+	eventDate = eventDate.In(eventLocation(timeZone))
+
+	for i := range decisions {
+		d := &decisions[i]
+		d.EventName, d.EventDate = eventName, eventDate
+
+		// Места распределяются по одному, чтобы заявки, поданные раньше, заняли их первыми
+		d.Status = storage.RegistrationRejected
+		if approve {
+			d.Status, err = seatStatus(tx, eventID, event)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		_, err := tx.Exec(`
+			UPDATE Registration
+			SET Status = ?, ReviewedAt = CURRENT_TIMESTAMP(6), ReviewMessage = NULLIF(?, '')
+			WHERE EventID = ? AND UserID = ?
+		`, d.Status, message, eventID, d.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to update application: %w", op, err)
+		}
+
+		if d.Status == storage.RegistrationWaitlisted {
+			d.Position, err = waitlistPosition(tx, eventID, d.UserID)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return decisions, nil
+}
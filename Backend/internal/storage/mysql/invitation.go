@@ -0,0 +1,177 @@
+package mysql
+
+import (
+	"Backend/internal/storage"
+	"database/sql"
+	"fmt"
+)
+
+// AddInvitations приглашает на событие пользователей по email и возвращает адреса, приглашенные впервые.
+// Уже существующие приглашения и ответы на них не меняются.
+func (r *Storage) AddInvitations(eventID int, invitedBy int64, emails []string) ([]string, error) {
+	const op = "mysql.AddInvitations"
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockEvent(tx, eventID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var added []string
+	for _, email := range emails {
+		result, err := tx.Exec(
+			"INSERT IGNORE INTO EventInvitation (EventID, Email, Status, InvitedByUserID) VALUES (?, ?, ?, ?)",
+			eventID, email, storage.InvitationInvited, invitedBy,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+			added = append(added, email)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return added, nil
+}
+
+// GetInvitations возвращает приглашения на событие в порядке отправки
+func (r *Storage) GetInvitations(eventID int) ([]storage.Invitation, error) {
+	const op = "mysql.GetInvitations"
+
+	rows, err := r.db.Query(`
+		SELECT i.Email, u.UserID, i.Status, i.InvitedAt, i.RespondedAt
+		FROM EventInvitation i
+		LEFT JOIN User u ON u.Email = i.Email
+		WHERE i.EventID = ?
+		ORDER BY i.InvitedAt, i.Email
+	`, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	invitations := []storage.Invitation{}
+	for rows.Next() {
+		var i storage.Invitation
+		var respondedAt sql.NullTime
+		if err := rows.Scan(&i.Email, &i.UserID, &i.Status, &i.InvitedAt, &respondedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if respondedAt.Valid {
+			i.RespondedAt = &respondedAt.Time
+		}
+		invitations = append(invitations, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return invitations, nil
+}
+
+// RemoveInvitation отзывает приглашение. Регистрация приглашенного, если она есть, сохраняется.
+func (r *Storage) RemoveInvitation(eventID int, email string) error {
+	const op = "mysql.RemoveInvitation"
+
+	result, err := r.db.Exec("DELETE FROM EventInvitation WHERE EventID = ? AND Email = ?", eventID, email)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrInvitationNotFound)
+	}
+
+	return nil
+}
+
+// DeclineInvitation отмечает отказ пользователя от приглашения
+func (r *Storage) DeclineInvitation(eventID int, userID int64) error {
+	const op = "mysql.DeclineInvitation"
+
+	result, err := r.db.Exec(`
+		UPDATE EventInvitation SET Status = ?, RespondedAt = CURRENT_TIMESTAMP(6)
+		WHERE EventID = ? AND Email = (SELECT Email FROM User WHERE UserID = ?)
+	`, storage.InvitationDeclined, eventID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// RespondedAt меняется при каждом ответе, поэтому существующее приглашение всегда затрагивается
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrInvitationNotFound)
+	}
+
+	return nil
+}
+
+// HasEventAccess сообщает, приглашен ли пользователь на событие по email или уже записан на него
+func (r *Storage) HasEventAccess(eventID int, userID int64) (bool, error) {
+	const op = "mysql.HasEventAccess"
+
+	var access bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM EventInvitation i JOIN User u ON u.Email = i.Email
+			WHERE i.EventID = ? AND u.UserID = ?
+		) OR EXISTS(
+			SELECT 1 FROM Registration WHERE EventID = ? AND UserID = ?
+		)
+	`, eventID, userID, eventID, userID).Scan(&access)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return access, nil
+}
+
+// RotateInviteLinks делает недействительными все выданные ссылки-приглашения на событие
+func (r *Storage) RotateInviteLinks(eventID int) error {
+	const op = "mysql.RotateInviteLinks"
+
+	result, err := r.db.Exec("UPDATE Event SET InviteVersion = InviteVersion + 1 WHERE EventID = ?", eventID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrEventNotFound)
+	}
+
+	return nil
+}
+
+// acceptInvitation отмечает принятие приглашения при записи на событие только по приглашениям.
+// Записавшийся по ссылке без приглашения по email тоже попадает в список приглашенных.
+func acceptInvitation(q queryer, eventID int, email string) error {
+	_, err := q.Exec(`
+		INSERT INTO EventInvitation (EventID, Email, Status, RespondedAt)
+		SELECT EventID, ?, ?, CURRENT_TIMESTAMP(6) FROM Event WHERE EventID = ? AND Visibility = ?
+		ON DUPLICATE KEY UPDATE Status = VALUES(Status), RespondedAt = VALUES(RespondedAt)
+	`, email, storage.InvitationAccepted, eventID, storage.VisibilityInviteOnly)
+	if err != nil {
+		return fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	return nil
+}
@@ -1,12 +1,16 @@
 package mysql
 
 import (
+	"Backend/internal/lib/geo"
+	"Backend/internal/lib/ticket"
 	"Backend/internal/storage"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/mysql"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -16,6 +20,93 @@ type Storage struct {
 	db *sql.DB
 }
 
+// registrationCounts - подзапросы количества подтвержденных регистраций и листа ожидания события e
+const registrationCounts = `(SELECT COUNT(*) FROM Registration WHERE EventID = e.EventID AND Status = 'confirmed') AS UsersCount,
+                   (SELECT COUNT(*) FROM Registration WHERE EventID = e.EventID AND Status = 'waitlisted') AS WaitlistCount`
+
+// eventColumns - поля события для выборок, в которых Event имеет псевдоним e, Category - c, а Venue - v
+const eventColumns = `e.EventID, e.Title, e.Description, e.EventDate, e.EventAddress,
+                   e.CreatorUserID, e.VKLink, e.TGLink, e.ImageURL, e.Capacity,
+                   ` + registrationCounts + `, e.Status, COALESCE(e.CancelReason, ''), c.Slug, c.Name, e.CreatedAt, e.TimeZone,
+                   e.Latitude, e.Longitude, e.DurationMinutes, v.VenueID, v.Name, e.Visibility, e.InviteVersion,
+                   e.RequiresApproval`
+
+// eventFrom - источник выборок с eventColumns
+const eventFrom = `FROM Event e
+           LEFT JOIN Category c ON c.CategoryID = e.CategoryID
+           LEFT JOIN Venue v ON v.VenueID = e.VenueID`
+
+// rowScanner - общая часть *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanEvent читает событие, выбранное через eventColumns. Дополнительные колонки после них
+// записываются в extra.
+func scanEvent(row rowScanner, extra ...any) (storage.Event, error) {
+	var e storage.Event
+	var categorySlug, categoryName sql.NullString
+	var lat, lng sql.NullFloat64
+	var venueID sql.NullInt64
+	var venueName sql.NullString
+
+	dest := []any{
+		&e.EventID,
+		&e.Title,
+		&e.Description,
+		&e.EventDate,
+		&e.EventAddress,
+		&e.CreatorUserID,
+		&e.VKLink,
+		&e.TGLink,
+		&e.ImageURL,
+		&e.Capacity,
+		&e.UsersCount,
+		&e.WaitlistCount,
+		&e.Status,
+		&e.CancelReason,
+		&categorySlug,
+		&categoryName,
+		&e.CreatedAt,
+		&e.TimeZone,
+		&lat,
+		&lng,
+		&e.DurationMinutes,
+		&venueID,
+		&venueName,
+		&e.Visibility,
+		&e.InviteVersion,
+		&e.RequiresApproval,
+	}
+
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return storage.Event{}, err
+	}
+
+	e.EventDate = e.EventDate.In(eventLocation(e.TimeZone))
+	if lat.Valid && lng.Valid {
+		e.Location = &geo.Point{Lat: lat.Float64, Lng: lng.Float64}
+	}
+
+	if categorySlug.Valid {
+		e.Category = &storage.Category{Slug: categorySlug.String, Name: categoryName.String}
+	}
+
+	if venueID.Valid {
+		e.Venue = &storage.VenueRef{ID: venueID.Int64, Name: venueName.String}
+	}
+
+	return e, nil
+}
+
+// coordinates раскладывает точку на широту и долготу для записи, nil дает NULL
+func coordinates(p *geo.Point) (*float64, *float64) {
+	if p == nil {
+		return nil, nil
+	}
+	return &p.Lat, &p.Lng
+}
+
 func New(storagePath string) (*Storage, error) {
 	const op = "storage.mysql.New"
 
@@ -36,94 +127,402 @@ func New(storagePath string) (*Storage, error) {
 	return &Storage{db: db}, nil
 }
 
-func (r *Storage) DeleteEvent(eventID int) error {
-	query := `DELETE FROM Event WHERE EventID = ?`
+// DeleteEvent удаляет событие вместе с регистрациями и возвращает идентификаторы их напоминаний,
+// которые нужно удалить в EmailSenderService
+func (r *Storage) DeleteEvent(eventID int) ([]int64, error) {
+	const op = "mysql.DeleteEvent"
 
-	result, err := r.db.Exec(query, eventID)
+	tx, err := r.db.Begin()
 	if err != nil {
-		return fmt.Errorf("mysql.DeleteEvent - query execution error: %w", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	defer tx.Rollback()
 
-	// Проверяем, было ли действительно удалено событие
-	rowsAffected, err := result.RowsAffected()
+	if _, err := lockEvent(tx, eventID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := excludeOccurrence(tx, eventID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Напоминания удаляются каскадно вместе с регистрациями, поэтому их нужно прочитать заранее
+	reminders, err := reminderIDs(tx, "EventID = ?", eventID)
 	if err != nil {
-		return fmt.Errorf("mysql.DeleteEvent - couldn't get rows affected: %w", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("mysql.DeleteEvent - event with ID %d not found", eventID)
+	if _, err := tx.Exec("DELETE FROM Event WHERE EventID = ?", eventID); err != nil {
+		return nil, fmt.Errorf("%s - query execution error: %w", op, err)
 	}
 
-	return nil
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return reminders, nil
 }
 
-func (r *Storage) CancelRegistration(eventId, userId int) error {
-	query := `DELETE FROM Registration WHERE EventID = ? AND UserID = ?`
+// CancelEvent отменяет опубликованное событие. Регистрации сохраняются для истории,
+// а их напоминания удаляются и возвращаются вместе с участниками для уведомления.
+// Отмененное повторение серии исключается из ее расписания.
+func (r *Storage) CancelEvent(eventID int, reason string) (storage.EventCancellation, error) {
+	const op = "mysql.CancelEvent"
 
-	result, err := r.db.Exec(query, eventId, userId)
+	tx, err := r.db.Begin()
 	if err != nil {
-		return fmt.Errorf("mysql.CancelRegistration - query execution error: %w", err)
+		return storage.EventCancellation{}, fmt.Errorf("%s: %w", op, err)
 	}
+	defer tx.Rollback()
 
-	// Проверяем, было ли действительно удалено событие
-	rowsAffected, err := result.RowsAffected()
+	event, err := lockEvent(tx, eventID)
 	if err != nil {
-		return fmt.Errorf("mysql.CancelRegistration - couldn't get rows affected: %w", err)
+		return storage.EventCancellation{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("mysql.CancelRegistration - event with ID %d not found", eventId)
+	if event.status != storage.EventPublished {
+		return storage.EventCancellation{}, fmt.Errorf("%s: event is %s: %w", op, event.status, storage.ErrInvalidTransition)
 	}
 
-	return nil
+	if err := excludeOccurrence(tx, eventID); err != nil {
+		return storage.EventCancellation{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result, err := cancelEvent(tx, eventID, reason)
+	if err != nil {
+		return storage.EventCancellation{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.EventCancellation{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+// cancelEvent переводит заблокированное событие в статус cancelled и удаляет записи о напоминаниях
+func cancelEvent(tx *sql.Tx, eventID int, reason string) (storage.EventCancellation, error) {
+	result := storage.EventCancellation{Reason: reason}
+
+	var timeZone string
+	err := tx.QueryRow("SELECT Title, EventDate, TimeZone FROM Event WHERE EventID = ?", eventID).Scan(&result.EventName, &result.EventDate, &timeZone)
+	if err != nil {
+		return storage.EventCancellation{}, fmt.Errorf("failed to fetch event: %w", err)
+	}
+	result.EventDate = result.EventDate.In(eventLocation(timeZone))
+
+	rows, err := tx.Query(`
+		SELECT u.UserID, u.Email
+		FROM Registration r
+		JOIN User u ON u.UserID = r.UserID
+		WHERE r.EventID = ? AND r.Status <> ?
+		ORDER BY r.RegisteredAt, r.UserID
+	`, eventID, storage.RegistrationRejected)
+	if err != nil {
+		return storage.EventCancellation{}, fmt.Errorf("failed to query registrants: %w", err)
+	}
+	for rows.Next() {
+		var registrant storage.Registrant
+		if err := rows.Scan(&registrant.UserID, &registrant.UserEmail); err != nil {
+			rows.Close()
+			return storage.EventCancellation{}, fmt.Errorf("failed to scan registrant: %w", err)
+		}
+		result.Registrants = append(result.Registrants, registrant)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return storage.EventCancellation{}, fmt.Errorf("failed to read registrants: %w", err)
+	}
+
+	result.ReminderIDs, err = reminderIDs(tx, "EventID = ?", eventID)
+	if err != nil {
+		return storage.EventCancellation{}, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM Reminder WHERE EventID = ?", eventID); err != nil {
+		return storage.EventCancellation{}, fmt.Errorf("failed to delete reminders: %w", err)
+	}
+
+	_, err = tx.Exec("UPDATE Event SET Status = ?, CancelReason = NULLIF(?, '') WHERE EventID = ?",
+		storage.EventCancelled, reason, eventID)
+	if err != nil {
+		return storage.EventCancellation{}, fmt.Errorf("failed to cancel event: %w", err)
+	}
+
+	return result, nil
+}
+
+// CompletePastEvents завершает опубликованные события, которые уже закончились. Событие заканчивается
+// через DurationMinutes после начала, а без продолжительности - в момент начала.
+func (r *Storage) CompletePastEvents(now time.Time) (int64, error) {
+	const op = "mysql.CompletePastEvents"
+
+	result, err := r.db.Exec(
+		"UPDATE Event SET Status = ? WHERE Status = ? AND EventDate + INTERVAL COALESCE(DurationMinutes, 0) MINUTE < ?",
+		storage.EventCompleted, storage.EventPublished, now)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	completed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	return completed, nil
+}
+
+// CancelRegistration удаляет регистрацию пользователя. Если освободилось место,
+// на него переводится первый пользователь из листа ожидания.
+func (r *Storage) CancelRegistration(eventId, userId int) (storage.CancelResult, error) {
+	const op = "mysql.CancelRegistration"
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return storage.CancelResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	// Блокировка события упорядочивает все изменения его регистраций
+	event, err := lockEvent(tx, eventId)
+	if err != nil {
+		return storage.CancelResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var status string
+	err = tx.QueryRow("SELECT Status FROM Registration WHERE EventID = ? AND UserID = ?", eventId, userId).Scan(&status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.CancelResult{}, fmt.Errorf("%s: %w", op, storage.ErrRegistrationNotFound)
+		}
+		return storage.CancelResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var result storage.CancelResult
+
+	result.ReminderIDs, err = reminderIDs(tx, "UserID = ? AND EventID = ?", userId, eventId)
+	if err != nil {
+		return storage.CancelResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM Registration WHERE EventID = ? AND UserID = ?", eventId, userId); err != nil {
+		return storage.CancelResult{}, fmt.Errorf("%s: query execution error: %w", op, err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE EventInvitation SET Status = ?, RespondedAt = CURRENT_TIMESTAMP(6)
+		WHERE EventID = ? AND Email = (SELECT Email FROM User WHERE UserID = ?)
+	`, storage.InvitationDeclined, eventId, userId)
+	if err != nil {
+		return storage.CancelResult{}, fmt.Errorf("%s: failed to decline invitation: %w", op, err)
+	}
+
+	// Места отмененного или прошедшего события никому не передаются
+	if status == storage.RegistrationConfirmed && event.status == storage.EventPublished {
+		result.Promoted, err = promoteWaitlisted(tx, eventId)
+		if err != nil {
+			return storage.CancelResult{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.CancelResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
 }
 
-func (r *Storage) EditEvent(eventId int64, dto storage.EventCreateDto) error {
+// EditEvent обновляет событие. Если вместимость увеличилась, пользователи из листа ожидания
+// занимают новые места и возвращаются для уведомления. Уменьшение вместимости
+// не отменяет уже подтвержденные регистрации. Отмененные и завершенные события не редактируются.
+// Для повторения серии scope определяет, меняется ли только оно, оно и следующие или вся серия.
+func (r *Storage) EditEvent(eventId int64, dto storage.EventCreateDto, scope string) (storage.EditResult, error) {
 	const op = "storage.postgres.UpdateEvent"
 
+	tx, err := r.db.Begin()
+	if err != nil {
+		return storage.EditResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	event, err := lockEvent(tx, int(eventId))
+	if err != nil {
+		return storage.EditResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if event.status == storage.EventCancelled || event.status == storage.EventCompleted {
+		return storage.EditResult{}, fmt.Errorf("%s: %w", op, storage.ErrEventClosed)
+	}
+
+	var result storage.EditResult
+
+	switch scope {
+	case "", storage.ScopeThis:
+		// Правило повторения относится ко всей серии и не меняется для одного повторения
+		if dto.Recurrence != nil {
+			return storage.EditResult{}, fmt.Errorf("%s: recurrence requires series scope: %w", op, storage.ErrInvalidScope)
+		}
+
+		grew, err := updateEventFields(tx, int(eventId), dto, &dto.EventDate)
+		if err != nil {
+			return storage.EditResult{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if grew {
+			result.Promoted, err = promoteWaitlisted(tx, int(eventId))
+			if err != nil {
+				return storage.EditResult{}, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	case storage.ScopeFollowing, storage.ScopeAll:
+		result, err = editSeries(tx, int(eventId), dto, scope)
+		if err != nil {
+			return storage.EditResult{}, fmt.Errorf("%s: %w", op, err)
+		}
+	default:
+		return storage.EditResult{}, fmt.Errorf("%s: %q: %w", op, scope, storage.ErrInvalidScope)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.EditResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+// updateEventFields обновляет поля, категорию и теги события из формы. Если date равна nil, дата не меняется.
+// Неуказанные вместимость, категория, площадка, координаты, длительность, часовой пояс и теги сохраняют прежние
+// значения, пока поле не перечислено в dto.Clear. Возвращает true, если вместимость выросла и можно перевести
+// пользователей из листа ожидания.
+func updateEventFields(tx *sql.Tx, eventId int, dto storage.EventCreateDto, date *time.Time) (bool, error) {
+	event, err := lockEvent(tx, eventId)
+	if err != nil {
+		return false, err
+	}
+
+	if err := applyVenue(tx, &dto); err != nil {
+		return false, err
+	}
+
 	query := `
         UPDATE Event SET
             Title = ?,
             Description = ?,
-            EventDate = ?,
+            EventDate = COALESCE(?, EventDate),
             EventAddress = ?,
             VKLink = ?,
             TGLink = ?,
-            ImageURL = ?
+            ImageURL = ?,
+            Capacity = IF(?, NULL, COALESCE(?, Capacity)),
+            CategoryID = IF(?, NULL, COALESCE(?, CategoryID)),
+            TimeZone = COALESCE(NULLIF(?, ''), TimeZone),
+            Latitude = IF(?, ?, COALESCE(?, Latitude)),
+            Longitude = IF(?, ?, COALESCE(?, Longitude)),
+            VenueID = IF(?, NULL, COALESCE(?, VenueID)),
+            DurationMinutes = IF(?, NULL, COALESCE(?, DurationMinutes)),
+            Visibility = COALESCE(NULLIF(?, ''), Visibility),
+            RequiresApproval = COALESCE(?, RequiresApproval)
         WHERE EventID = ?
     `
 
-	result, err := r.db.Exec(
+	category, err := categoryID(tx, dto.Category)
+	if err != nil {
+		return false, err
+	}
+
+	lat, lng := coordinates(dto.Location)
+	// Координаты площадки заменяют прежние, даже если у нее их нет
+	replaceLocation := dto.Clears(storage.FieldLocation) || dto.VenueID != nil
+
+	_, err = tx.Exec(
 		query,
 		dto.Title,
 		dto.Description,
-		dto.EventDate,
+		date,
 		dto.EventAddress,
 		dto.VKLink,
 		dto.TGLink,
 		dto.ImageURL,
+		dto.Clears(storage.FieldCapacity), dto.Capacity,
+		dto.Clears(storage.FieldCategory), category,
+		dto.TimeZone,
+		replaceLocation, lat, lat,
+		replaceLocation, lng, lng,
+		dto.Clears(storage.FieldVenue), dto.VenueID,
+		dto.Clears(storage.FieldDurationMinutes), dto.DurationMinutes,
+		dto.Visibility,
+		dto.RequiresApproval,
 		eventId,
 	)
-
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return false, err
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	if err := refreshLocalStart(tx, eventId); err != nil {
+		return false, err
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("%s: event with ID %d not found", op, eventId)
+	if dto.Tags != nil {
+		if err := setEventTags(tx, int64(eventId), dto.Tags); err != nil {
+			return false, err
+		}
 	}
 
-	return nil
+	capacity := event.capacity
+	switch {
+	case dto.Capacity != nil:
+		capacity = sql.NullInt64{Int64: int64(*dto.Capacity), Valid: true}
+	case dto.Clears(storage.FieldCapacity):
+		capacity = sql.NullInt64{}
+	}
+
+	return capacityGrew(event.capacity, capacity), nil
+}
+
+// capacityGrew сообщает, освободила ли новая вместимость места: она больше прежней или стала неограниченной
+func capacityGrew(before, after sql.NullInt64) bool {
+	if !after.Valid {
+		return before.Valid
+	}
+	return before.Valid && after.Int64 > before.Int64
 }
 
 func (r *Storage) AddEvent(dto storage.EventCreateDto) (int64, error) {
 	const op = "storage.postgres.AddEvent"
 
+	if dto.Recurrence != nil {
+		id, err := r.addSeries(dto)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		return id, nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	id, err := insertEvent(tx, dto, dto.EventDate, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// insertEvent создает событие на указанную дату. Для повторения серии передается ее идентификатор,
+// а дата становится датой повторения.
+func insertEvent(q queryer, dto storage.EventCreateDto, date time.Time, seriesID *int64) (int64, error) {
+	if err := applyVenue(q, &dto); err != nil {
+		return 0, err
+	}
+
 	query := `
         INSERT INTO Event (
             Title, 
@@ -133,29 +532,85 @@ func (r *Storage) AddEvent(dto storage.EventCreateDto) (int64, error) {
             CreatorUserID, 
             VKLink, 
             TGLink, 
-            ImageURL
-        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+            ImageURL,
+            Capacity,
+            Status,
+            SeriesID,
+            OccurrenceDate,
+            CategoryID,
+            TimeZone,
+            LocalStart,
+            Latitude,
+            Longitude,
+            VenueID,
+            DurationMinutes,
+            Visibility,
+            RequiresApproval
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
     `
 
-	result, err := r.db.Exec(
+	category, err := categoryID(q, dto.Category)
+	if err != nil {
+		return 0, err
+	}
+
+	status := dto.Status
+	if status == "" {
+		status = storage.EventPublished
+	}
+
+	var occurrence *time.Time
+	if seriesID != nil {
+		occurrence = &date
+	}
+
+	timeZone := dto.TimeZone
+	if timeZone == "" {
+		timeZone = storage.DefaultTimeZone
+	}
+
+	visibility := dto.Visibility
+	if visibility == "" {
+		visibility = storage.VisibilityPublic
+	}
+
+	lat, lng := coordinates(dto.Location)
+
+	result, err := q.Exec(
 		query,
 		dto.Title,
 		dto.Description,
-		dto.EventDate,
+		date,
 		dto.EventAddress,
 		dto.CreatorUserID,
 		dto.VKLink,
 		dto.TGLink,
 		dto.ImageURL,
+		dto.Capacity,
+		status,
+		seriesID,
+		occurrence,
+		category,
+		timeZone,
+		localStart(date, timeZone),
+		lat,
+		lng,
+		dto.VenueID,
+		dto.DurationMinutes,
+		visibility,
+		dto.RequiresApproval != nil && *dto.RequiresApproval,
 	)
-
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return 0, err
 	}
 
 	id, err := result.LastInsertId()
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return 0, err
+	}
+
+	if err := setEventTags(q, id, dto.Tags); err != nil {
+		return 0, err
 	}
 
 	return id, nil
@@ -169,35 +624,44 @@ func (r *Storage) GetEvent(eventId int) (storage.Event, error) {
 		return storage.Event{}, fmt.Errorf("%s - invalid event ID: %d", op, eventId)
 	}
 
-	query := `SELECT e.EventID, e.Title, e.Description, e.EventDate, e.EventAddress, 
-                    e.CreatorUserID, e.VKLink, e.TGLink, e.ImageURL
-              FROM Event e
+	query := `SELECT ` + eventColumns + `, e.SeriesID, COALESCE(s.RRule, '')
+              ` + eventFrom + `
+              LEFT JOIN EventSeries s ON s.SeriesID = e.SeriesID
               WHERE e.EventID = ?`
 
 	// Для отладки: логирование запроса
 	fmt.Printf("Executing query: %s with ID: %d\n", query, eventId)
 
-	row := r.db.QueryRow(query, eventId)
-
-	var event storage.Event
-	err := row.Scan(
-		&event.EventID,
-		&event.Title,
-		&event.Description,
-		&event.EventDate,
-		&event.EventAddress,
-		&event.CreatorUserID,
-		&event.VKLink,
-		&event.TGLink,
-		&event.ImageURL,
-	)
-
+	var seriesID *int64
+	var recurrence string
+	event, err := scanEvent(r.db.QueryRow(query, eventId), &seriesID, &recurrence)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return storage.Event{}, fmt.Errorf("%s - event with ID %d not found", op, eventId)
+			return storage.Event{}, fmt.Errorf("%s - event with ID %d: %w", op, eventId, storage.ErrEventNotFound)
 		}
 		return storage.Event{}, fmt.Errorf("%s - row scanning error: %w", op, err)
 	}
+	event.SeriesID = seriesID
+	event.Recurrence = recurrence
+
+	tags, err := eventTags(r.db, []int64{event.EventID})
+	if err != nil {
+		return storage.Event{}, fmt.Errorf("%s: %w", op, err)
+	}
+	event.Tags = tags[event.EventID]
+	if event.Tags == nil {
+		event.Tags = []string{}
+	}
+
+	event.Organizers, err = eventOrganizers(r.db, event.EventID)
+	if err != nil {
+		return storage.Event{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	event.Questions, err = eventQuestions(r.db, event.EventID)
+	if err != nil {
+		return storage.Event{}, fmt.Errorf("%s: %w", op, err)
+	}
 
 	// Для отладки: вывод полученного события
 	fmt.Printf("Retrieved event: %+v\n", event)
@@ -205,127 +669,364 @@ func (r *Storage) GetEvent(eventId int) (storage.Event, error) {
 	return event, nil
 }
 
-func (r *Storage) GetFilteredEvents(title, date, address string) ([]storage.Event, error) {
-	query := `SELECT e.EventID, e.Title, e.Description, e.EventDate, e.EventAddress, e.CreatorUserID, e.VKLink, e.TGLink, e.ImageURL,
-               COUNT(er.UserID) as UsersCount
-               FROM event e
-               LEFT JOIN registration er ON e.EventID = er.EventID
-               WHERE 1=1`
+// GetFilteredEvents возвращает страницу опубликованных, отмененных и завершенных событий, подходящих под фильтр
+func (r *Storage) GetFilteredEvents(filter storage.EventFilter, page storage.PageRequest) (storage.EventPage, error) {
+	where, args := eventFilterWhere(filter)
+
+	result, err := r.queryEventPage(eventPageQuery{
+		from:  eventFrom,
+		where: where,
+		args:  args,
+		scan:  scanPlainEvent,
+		ids:   filter.IDs,
+		near:  filter.Near,
+	}, normalizePage(page))
+	if err != nil {
+		return storage.EventPage{}, fmt.Errorf("mysql.GetFilteredEvents - %w", err)
+	}
 
-	args := []interface{}{}
+	return result, nil
+}
 
-	// Добавляем условия фильтрации, если параметры не пусты
-	if title != "" {
-		query += " AND e.Title LIKE ?"
-		args = append(args, "%"+title+"%")
+// UpsertUser создает или обновляет копию пользователя из AuthService.
+// version растет с каждым изменением учетной записи, поэтому устаревшие и повторные события не применяются.
+// Запись, созданная до перехода на общий идентификатор, находится по email и получает идентификатор AuthService.
+func (s *Storage) UpsertUser(user storage.SyncedUser, version int64) error {
+	const op = "storage.UpsertUser"
+
+	// Version обновляется последним, чтобы остальные условия сравнивали с прежним значением
+	_, err := s.db.Exec(`
+		INSERT INTO User (UserID, Email, FirstName, LastName, Version) VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			UserID = IF(VALUES(Version) > Version, VALUES(UserID), UserID),
+			Email = IF(VALUES(Version) > Version, VALUES(Email), Email),
+			FirstName = IF(VALUES(Version) > Version, VALUES(FirstName), FirstName),
+			LastName = IF(VALUES(Version) > Version, VALUES(LastName), LastName),
+			Version = GREATEST(Version, VALUES(Version))
+	`, user.ID, user.Email, user.FirstName, user.LastName, version)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	if date != "" {
-		// Предполагаем, что дата приходит в формате YYYY-MM-DD
-		query += " AND e.EventDate = ?"
-		args = append(args, date)
+	return nil
+}
+
+// LinkUser присваивает записи, созданной до AuthService, идентификатор его учетной записи.
+// Событие не содержит имен, поэтому имена записи сохраняются и возвращаются, чтобы заполнить ими
+// профиль в AuthService. Если записи с таким email нет, она создается без имен.
+func (s *Storage) LinkUser(user storage.SyncedUser, version int64) (storage.SyncedUser, error) {
+	const op = "storage.LinkUser"
+
+	_, err := s.db.Exec(`
+		INSERT INTO User (UserID, Email, Version) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			UserID = IF(VALUES(Version) > Version, VALUES(UserID), UserID),
+			Version = GREATEST(Version, VALUES(Version))
+	`, user.ID, user.Email, version)
+	if err != nil {
+		return storage.SyncedUser{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	// Фильтрация по адресу
-	if address != "" {
-		query += " AND e.EventAddress LIKE ?"
-		args = append(args, "%"+address+"%")
+	linked := storage.SyncedUser{ID: user.ID}
+	err = s.db.QueryRow(
+		"SELECT Email, FirstName, LastName FROM User WHERE UserID = ?", user.ID,
+	).Scan(&linked.Email, &linked.FirstName, &linked.LastName)
+	if err != nil {
+		return storage.SyncedUser{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	// Добавляем GROUP BY, так как используем агрегатную функцию COUNT
-	query += " GROUP BY e.EventID"
+	return linked, nil
+}
 
-	// Сортируем по дате, чтобы сначала показывались ближайшие события
-	query += " ORDER BY e.EventDate ASC"
+// DeleteUser удаляет пользователя вместе с его событиями и регистрациями,
+// если запись не изменилась после события удаления
+func (s *Storage) DeleteUser(userID int64, version int64) error {
+	const op = "storage.DeleteUser"
 
-	rows, err := r.db.Query(query, args...)
+	_, err := s.db.Exec("DELETE FROM User WHERE UserID = ? AND Version < ?", userID, version)
 	if err != nil {
-		return nil, fmt.Errorf("mysql.GetFilteredEvents - query execution error: %w", err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
-	defer rows.Close()
 
-	var events []storage.Event
-	for rows.Next() {
-		var e storage.Event
-		var UsersCount int
-		err := rows.Scan(
-			&e.EventID,
-			&e.Title,
-			&e.Description,
-			&e.EventDate,
-			&e.EventAddress,
-			&e.CreatorUserID,
-			&e.VKLink,
-			&e.TGLink,
-			&e.ImageURL,
-			&UsersCount,
-		)
+	return nil
+}
+
+// UpdateUserAvatar сохраняет ссылку на аватар пользователя
+func (s *Storage) UpdateUserAvatar(userID int64, imageURL string) error {
+	const op = "storage.UpdateUserAvatar"
+
+	result, err := s.db.Exec("UPDATE User SET ImageUrl = ? WHERE UserID = ?", imageURL, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: user with ID %d not found", op, userID)
+	}
+
+	return nil
+}
+
+// RegisterUserForEvent регистрирует пользователя на мероприятие. Если свободных мест нет,
+// пользователь попадает в конец листа ожидания. Ответы на анкету регистрации сохраняются вместе с записью.
+func (s *Storage) RegisterUserForEvent(userId int, eventId int, answers []storage.Answer) (storage.RegistrationResult, error) {
+	const op = "storage.RegisterUserForEvent"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return storage.RegistrationResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	// Блокировка события не дает параллельным запросам занять одно и то же место
+	event, err := lockEvent(tx, eventId)
+	if err != nil {
+		return storage.RegistrationResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if event.status != storage.EventPublished {
+		return storage.RegistrationResult{}, fmt.Errorf("%s: %w", op, storage.ErrEventNotOpen)
+	}
+
+	// На событие с одобрением место не занимается до решения организатора
+	result := storage.RegistrationResult{Status: storage.RegistrationPending}
+	if !event.requiresApproval {
+		result.Status, err = seatStatus(tx, eventId, event)
 		if err != nil {
-			return nil, fmt.Errorf("mysql.GetFilteredEvents - row scanning error: %w", err)
+			return storage.RegistrationResult{}, fmt.Errorf("%s: %w", op, err)
 		}
-		e.UsersCount = UsersCount
-		events = append(events, e)
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("mysql.GetFilteredEvents - rows iteration error: %w", err)
+	result.TicketNonce, err = ticket.NewNonce()
+	if err != nil {
+		return storage.RegistrationResult{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return events, nil
-}
+	// Регистрируем пользователя на мероприятие
+	_, err = tx.Exec(
+		"INSERT INTO Registration (UserID, EventID, Status, TicketNonce) VALUES (?, ?, ?, ?)",
+		userId, eventId, result.Status, result.TicketNonce,
+	)
+	if err != nil {
+		var mysqlErr *mysqldriver.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return storage.RegistrationResult{}, fmt.Errorf("%s: %w", op, storage.ErrAlreadyRegistered)
+		}
+		return storage.RegistrationResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := saveAnswers(tx, userId, eventId, answers); err != nil {
+		return storage.RegistrationResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.Status == storage.RegistrationWaitlisted {
+		result.Position, err = waitlistPosition(tx, eventId, int64(userId))
+		if err != nil {
+			return storage.RegistrationResult{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
 
-// AddUser добавляет нового пользователя в таблицу `users`.
-func (s *Storage) AddUser(dto storage.CrateUserDto) (int64, error) {
-	const op = "storage.AddUser"
+	// Получаем email пользователя
+	err = tx.QueryRow("SELECT Email FROM User WHERE UserID = ?", userId).Scan(&result.UserEmail)
+	if err != nil {
+		return storage.RegistrationResult{}, fmt.Errorf("%s: failed to fetch user email: %w", op, err)
+	}
 
-	// Подготовка SQL-запроса для вставки нового пользователя
-	stmt, err := s.db.Prepare("INSERT INTO User (FirstName, LastName, Email, ImageUrl) VALUES (?, ?, ?, ?)")
+	// Получаем название и дату события
+	var timeZone string
+	err = tx.QueryRow("SELECT Title, EventDate, TimeZone FROM Event WHERE EventID = ?", eventId).Scan(&result.EventName, &result.EventDate, &timeZone)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return storage.RegistrationResult{}, fmt.Errorf("%s: failed to fetch event name: %w", op, err)
 	}
-	defer stmt.Close()
+	result.EventDate = result.EventDate.In(eventLocation(timeZone))
+
+	// Запись на событие по приглашению считается его принятием, в том числе по ссылке без приглашения по email
+	if err := acceptInvitation(tx, eventId, result.UserEmail); err != nil {
+		return storage.RegistrationResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.RegistrationResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+// AddReminders сохраняет идентификаторы напоминаний, созданных для регистрации
+func (s *Storage) AddReminders(userId, eventId int, notificationIDs []int64) error {
+	const op = "storage.AddReminders"
+
+	for _, id := range notificationIDs {
+		_, err := s.db.Exec("INSERT IGNORE INTO Reminder (UserID, EventID, NotificationID) VALUES (?, ?, ?)", userId, eventId, id)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// lockedEvent - поля события, прочитанные под блокировкой
+type lockedEvent struct {
+	capacity         sql.NullInt64
+	status           string
+	requiresApproval bool
+	// endsAt - окончание события: начало плюс продолжительность, если она указана
+	endsAt time.Time
+}
+
+// lockEvent блокирует строку события до конца транзакции и возвращает его вместимость, статус, необходимость одобрения
+// и время окончания
+func lockEvent(tx *sql.Tx, eventId int) (lockedEvent, error) {
+	var event lockedEvent
 
-	// Выполнение запроса
-	res, err := stmt.Exec(dto.FirstName, dto.LastName, dto.Email, dto.ImageURL)
+	err := tx.QueryRow(
+		`SELECT Capacity, Status, RequiresApproval, EventDate + INTERVAL COALESCE(DurationMinutes, 0) MINUTE
+		FROM Event WHERE EventID = ? FOR UPDATE`, eventId,
+	).Scan(&event.capacity, &event.status, &event.requiresApproval, &event.endsAt)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return lockedEvent{}, storage.ErrEventNotFound
+		}
+		return lockedEvent{}, fmt.Errorf("failed to lock event: %w", err)
 	}
 
-	id, err := res.LastInsertId()
+	return event, nil
+}
+
+// seatStatus возвращает статус новой подтвержденной записи: confirmed, если есть свободное место, иначе waitlisted.
+// Событие должно быть заблокировано через lockEvent.
+func seatStatus(tx *sql.Tx, eventId int, event lockedEvent) (string, error) {
+	if !event.capacity.Valid {
+		return storage.RegistrationConfirmed, nil
+	}
+
+	var confirmed int
+	err := tx.QueryRow(
+		"SELECT COUNT(*) FROM Registration WHERE EventID = ? AND Status = ?", eventId, storage.RegistrationConfirmed,
+	).Scan(&confirmed)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return "", fmt.Errorf("failed to count registrations: %w", err)
 	}
 
-	return id, nil
+	if confirmed >= int(event.capacity.Int64) {
+		return storage.RegistrationWaitlisted, nil
+	}
+	return storage.RegistrationConfirmed, nil
 }
 
-// RegisterUserForEvent регистрирует пользователя на мероприятие.
-func (s *Storage) RegisterUserForEvent(userId int, eventId int) (userEmail string, eventName string, err error) {
-	const op = "storage.RegisterUserForEvent"
+// waitlistPosition возвращает место пользователя в листе ожидания события, начиная с 1
+func waitlistPosition(tx *sql.Tx, eventId int, userId int64) (int, error) {
+	var position int
+	err := tx.QueryRow(`
+		SELECT COUNT(*)
+		FROM Registration w
+		JOIN Registration r ON r.EventID = w.EventID AND r.UserID = ?
+		WHERE w.EventID = ? AND w.Status = ? AND (w.RegisteredAt, w.UserID) <= (r.RegisteredAt, r.UserID)
+	`, userId, eventId, storage.RegistrationWaitlisted).Scan(&position)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get waitlist position: %w", err)
+	}
 
-	// Регистрируем пользователя на мероприятие
-	_, err = s.db.Exec("INSERT INTO Registration (UserID, EventID) VALUES (?, ?)", userId, eventId)
+	return position, nil
+}
+
+// promoteWaitlisted переводит пользователей из листа ожидания в порядке записи, пока есть свободные места.
+// Событие должно быть заблокировано через lockEvent.
+func promoteWaitlisted(tx *sql.Tx, eventId int) ([]storage.Promotion, error) {
+	var capacity sql.NullInt64
+	var confirmed int
+	err := tx.QueryRow(`
+		SELECT e.Capacity, (SELECT COUNT(*) FROM Registration WHERE EventID = e.EventID AND Status = ?)
+		FROM Event e
+		WHERE e.EventID = ?
+	`, storage.RegistrationConfirmed, eventId).Scan(&capacity, &confirmed)
 	if err != nil {
-		return "", "", fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("failed to count free seats: %w", err)
 	}
 
-	// Получаем email пользователя
-	err = s.db.QueryRow("SELECT Email FROM user WHERE ID = ?", userId).Scan(&userEmail)
+	free := -1 // без ограничения мест переводятся все
+	if capacity.Valid {
+		free = int(capacity.Int64) - confirmed
+		if free <= 0 {
+			return nil, nil
+		}
+	}
+
+	query := `
+		SELECT r.EventID, r.UserID, u.Email, e.Title, e.EventDate, e.TimeZone, COALESCE(r.TicketNonce, '')
+		FROM Registration r
+		JOIN User u ON u.UserID = r.UserID
+		JOIN Event e ON e.EventID = r.EventID
+		WHERE r.EventID = ? AND r.Status = ?
+		ORDER BY r.RegisteredAt, r.UserID`
+	args := []any{eventId, storage.RegistrationWaitlisted}
+	if free > 0 {
+		query += " LIMIT ?"
+		args = append(args, free)
+	}
+
+	rows, err := tx.Query(query+" FOR UPDATE", args...)
 	if err != nil {
-		return "", "", fmt.Errorf("%s: failed to fetch user email: %w", op, err)
+		return nil, fmt.Errorf("failed to query waitlist: %w", err)
 	}
 
-	// Получаем название события
-	err = s.db.QueryRow("SELECT Title FROM event WHERE ID = ?", eventId).Scan(&eventName)
+	var promoted []storage.Promotion
+	for rows.Next() {
+		var p storage.Promotion
+		var timeZone string
+		if err := rows.Scan(&p.EventID, &p.UserID, &p.UserEmail, &p.EventName, &p.EventDate, &timeZone, &p.TicketNonce); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan waitlist: %w", err)
+		}
+		p.EventDate = p.EventDate.In(eventLocation(timeZone))
+		promoted = append(promoted, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read waitlist: %w", err)
+	}
+
+	for _, p := range promoted {
+		_, err := tx.Exec(
+			"UPDATE Registration SET Status = ? WHERE EventID = ? AND UserID = ?",
+			storage.RegistrationConfirmed, eventId, p.UserID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to promote user %d: %w", p.UserID, err)
+		}
+	}
+
+	return promoted, nil
+}
+
+// reminderIDs возвращает идентификаторы напоминаний, подходящих под условие
+func reminderIDs(tx *sql.Tx, where string, args ...any) ([]int64, error) {
+	rows, err := tx.Query("SELECT NotificationID FROM Reminder WHERE "+where, args...)
 	if err != nil {
-		return "", "", fmt.Errorf("%s: failed to fetch event name: %w", op, err)
+		return nil, fmt.Errorf("failed to query reminders: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan reminder: %w", err)
+		}
+		ids = append(ids, id)
 	}
 
-	return userEmail, eventName, nil
+	return ids, rows.Err()
 }
 
 func (r *Storage) GetUserInfo(userId int) (storage.UserInfo, error) {
-	query := `SELECT u.Email, u.FirstName, u.LastName , u.ImageUrl
+	query := `SELECT u.Email, u.FirstName, u.LastName, COALESCE(u.ImageUrl, '')
 	          FROM User u 
 	          WHERE u.UserID = ?`
 
@@ -344,62 +1045,33 @@ func (r *Storage) GetUserInfo(userId int) (storage.UserInfo, error) {
 	return userInfo, nil
 }
 
-func (r *Storage) GetEventsByUser(userId int) ([]storage.Event, error) {
-	query := `SELECT e.EventID, e.Title, e.Description, e.EventDate, e.EventAddress,
-                   e.CreatorUserID, e.VKLink, e.TGLink, e.ImageURL,
-                   COALESCE(COUNT(DISTINCT er.UserID), 0) as UsersCount
-           FROM Event e
-           LEFT JOIN registration er ON e.EventID = er.EventID
-           WHERE e.CreatorUserID = ?
-           GROUP BY e.EventID, e.Title, e.Description, e.EventDate, e.EventAddress,
-                   e.CreatorUserID, e.VKLink, e.TGLink, e.ImageURL
-           ORDER BY e.EventDate ASC`
-
-	rows, err := r.db.Query(query, userId)
+// GetEventsByUser возвращает страницу событий, созданных пользователем. Черновики и непубличные события
+// видны только самому автору.
+func (r *Storage) GetEventsByUser(userId int, includeHidden bool, page storage.PageRequest) (storage.EventPage, error) {
+	result, err := r.queryEventPage(eventPageQuery{
+		from:  eventFrom,
+		where: "e.CreatorUserID = ? AND (? OR (e.Status <> 'draft' AND e.Visibility = 'public'))",
+		args:  []any{userId, includeHidden},
+		scan:  scanPlainEvent,
+	}, normalizePage(page))
 	if err != nil {
-		return nil, fmt.Errorf("mysql.GetEventsByUser - query execution error: %w", err)
-	}
-	defer rows.Close()
-
-	var events []storage.Event
-	for rows.Next() {
-		var e storage.Event
-		err := rows.Scan(
-			&e.EventID,
-			&e.Title,
-			&e.Description,
-			&e.EventDate,
-			&e.EventAddress,
-			&e.CreatorUserID,
-			&e.VKLink,
-			&e.TGLink,
-			&e.ImageURL,
-			&e.UsersCount,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("mysql.GetEventsByUser - row scanning error: %w", err)
-		}
-		events = append(events, e)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("mysql.GetEventsByUser - rows iteration error: %w", err)
+		return storage.EventPage{}, fmt.Errorf("mysql.GetEventsByUser - %w", err)
 	}
 
-	return events, nil
+	return result, nil
 }
 
 func (r *Storage) GetEventRegisteredUsers(eventId, creatorId int) ([]storage.UserInfo, error) {
 	// Этот запрос объединяет две выборки: зарегистрированных пользователей и создателя
 	query := `
-        SELECT u.UserID, u.Email, u.FirstName, u.LastName, u.ImageUrl
+        SELECT u.UserID, u.Email, u.FirstName, u.LastName, COALESCE(u.ImageUrl, '')
         FROM User u
         JOIN Registration reg ON u.UserID = reg.UserID
-        WHERE reg.EventID = ?
+        WHERE reg.EventID = ? AND reg.Status = 'confirmed'
         
         UNION
         
-        SELECT UserID, Email, FirstName, LastName, ImageUrl
+        SELECT UserID, Email, FirstName, LastName, COALESCE(ImageUrl, '')
         FROM User
         WHERE UserID = ?
     `
@@ -429,47 +1101,25 @@ func (r *Storage) GetEventRegisteredUsers(eventId, creatorId int) ([]storage.Use
 
 	return users, nil
 }
-func (r *Storage) GetRegisteredEventsByUser(userId int) ([]storage.Event, error) {
-	query := `SELECT e.EventID, e.Title, e.Description, e.EventDate, e.EventAddress,
-                   e.CreatorUserID, e.VKLink, e.TGLink, e.ImageURL,
-                   (SELECT COUNT(DISTINCT UserID) FROM registration WHERE EventID = e.EventID) as UsersCount
-           FROM Event e
-           INNER JOIN registration r ON e.EventID = r.EventID
-           WHERE r.UserID = ?
-           GROUP BY e.EventID, e.Title, e.Description, e.EventDate, e.EventAddress,
-                   e.CreatorUserID, e.VKLink, e.TGLink, e.ImageURL
-           ORDER BY e.EventDate ASC`
 
-	rows, err := r.db.Query(query, userId)
+// GetRegisteredEventsByUser возвращает страницу событий, на которые записан пользователь, со статусом записи.
+// Непубличные события видны только самому пользователю.
+func (r *Storage) GetRegisteredEventsByUser(userId int, includeHidden bool, page storage.PageRequest) (storage.EventPage, error) {
+	result, err := r.queryEventPage(eventPageQuery{
+		from:    eventFrom + " INNER JOIN Registration r ON e.EventID = r.EventID",
+		where:   "r.UserID = ? AND (? OR e.Visibility = 'public')",
+		args:    []any{userId, includeHidden},
+		columns: ", r.Status",
+		scan: func(row rowScanner, extra ...any) (storage.Event, error) {
+			var registrationStatus string
+			e, err := scanEvent(row, append([]any{&registrationStatus}, extra...)...)
+			e.RegistrationStatus = registrationStatus
+			return e, err
+		},
+	}, normalizePage(page))
 	if err != nil {
-		return nil, fmt.Errorf("mysql.GetRegisteredEventsByUser - query execution error: %w", err)
-	}
-	defer rows.Close()
-
-	var events []storage.Event
-	for rows.Next() {
-		var e storage.Event
-		err := rows.Scan(
-			&e.EventID,
-			&e.Title,
-			&e.Description,
-			&e.EventDate,
-			&e.EventAddress,
-			&e.CreatorUserID,
-			&e.VKLink,
-			&e.TGLink,
-			&e.ImageURL,
-			&e.UsersCount,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("mysql.GetRegisteredEventsByUser - row scanning error: %w", err)
-		}
-		events = append(events, e)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("mysql.GetRegisteredEventsByUser - rows iteration error: %w", err)
+		return storage.EventPage{}, fmt.Errorf("mysql.GetRegisteredEventsByUser - %w", err)
 	}
 
-	return events, nil
+	return result, nil
 }
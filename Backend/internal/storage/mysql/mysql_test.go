@@ -0,0 +1,33 @@
+package mysql
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestCapacityGrew(t *testing.T) {
+	seats := func(n int64) sql.NullInt64 { return sql.NullInt64{Int64: n, Valid: true} }
+	unlimited := sql.NullInt64{}
+
+	tests := []struct {
+		name   string
+		before sql.NullInt64
+		after  sql.NullInt64
+		want   bool
+	}{
+		{name: "increased", before: seats(10), after: seats(12), want: true},
+		{name: "unchanged", before: seats(10), after: seats(10), want: false},
+		{name: "decreased", before: seats(10), after: seats(8), want: false},
+		{name: "became unlimited", before: seats(10), after: unlimited, want: true},
+		{name: "stays unlimited", before: unlimited, after: unlimited, want: false},
+		{name: "became limited", before: unlimited, after: seats(100), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := capacityGrew(tt.before, tt.after); got != tt.want {
+				t.Errorf("capacityGrew() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package mysql
+
+import (
+	"Backend/internal/lib/geo"
+	"Backend/internal/storage"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// popularityExpr совпадает с подзапросом UsersCount из registrationCounts. Псевдоним колонки
+// нельзя использовать в WHERE, поэтому для курсора выражение повторяется.
+const popularityExpr = `(SELECT COUNT(*) FROM Registration WHERE EventID = e.EventID AND Status = 'confirmed')`
+
+// distanceExpr - расстояние в километрах от события e до точки с долготой и широтой в параметрах.
+// Для событий без координат результат NULL.
+const distanceExpr = `ST_Distance_Sphere(POINT(e.Longitude, e.Latitude), POINT(?, ?)) / 1000`
+
+// cursor - позиция последнего события страницы. Значение ключа сортировки хранится в поле
+// соответствующего типа, EventID разрешает равенство ключей.
+type cursor struct {
+	Sort  string    `json:"s"`
+	Order string    `json:"o"`
+	Time  time.Time `json:"t"`
+	Int   int64     `json:"n"`
+	Float float64   `json:"f"`
+	ID    int64     `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string, page storage.PageRequest) (cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor{}, storage.ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return cursor{}, storage.ErrInvalidCursor
+	}
+
+	// Курсор другой сортировки указывает на позицию, которой в этой выдаче нет
+	if c.Sort != page.Sort || c.Order != page.Order {
+		return cursor{}, storage.ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// eventSort описывает ключ сортировки событий с псевдонимом e
+type eventSort struct {
+	expr string
+	args []any
+	// value возвращает значение ключа для курсора и записывает его в c
+	value func(e storage.Event, c *cursor)
+	// arg возвращает значение ключа из курсора для сравнения
+	arg func(c cursor) any
+}
+
+// newEventSort возвращает ключ сортировки. ids задает порядок для сортировки по релевантности,
+// near - точку для сортировки по расстоянию.
+func newEventSort(sort string, ids []int64, near *geo.Point) (eventSort, error) {
+	switch sort {
+	case storage.SortDate:
+		return eventSort{
+			expr:  "e.EventDate",
+			value: func(e storage.Event, c *cursor) { c.Time = e.EventDate },
+			arg:   func(c cursor) any { return c.Time },
+		}, nil
+
+	case storage.SortCreated:
+		return eventSort{
+			expr:  "e.CreatedAt",
+			value: func(e storage.Event, c *cursor) { c.Time = e.CreatedAt },
+			arg:   func(c cursor) any { return c.Time },
+		}, nil
+
+	case storage.SortPopularity:
+		return eventSort{
+			expr:  popularityExpr,
+			value: func(e storage.Event, c *cursor) { c.Int = int64(e.UsersCount) },
+			arg:   func(c cursor) any { return c.Int },
+		}, nil
+
+	case storage.SortRelevance:
+		// Без результатов поиска выборка пуста или не ограничена поиском, и релевантность не определена
+		if len(ids) == 0 {
+			return newEventSort(storage.SortDate, nil, nil)
+		}
+
+		// Позиция события в результатах поиска, начиная с 1
+		rank := make(map[int64]int64, len(ids))
+		args := make([]any, len(ids))
+		for i, id := range ids {
+			rank[id] = int64(i + 1)
+			args[i] = id
+		}
+
+		return eventSort{
+			expr:  "FIELD(e.EventID, " + placeholders(len(ids)) + ")",
+			args:  args,
+			value: func(e storage.Event, c *cursor) { c.Int = rank[e.EventID] },
+			arg:   func(c cursor) any { return c.Int },
+		}, nil
+
+	case storage.SortDistance:
+		if near == nil {
+			return newEventSort(storage.SortDate, nil, nil)
+		}
+
+		return eventSort{
+			expr: distanceExpr,
+			args: []any{near.Lng, near.Lat},
+			value: func(e storage.Event, c *cursor) {
+				if e.DistanceKm != nil {
+					c.Float = *e.DistanceKm
+				}
+			},
+			arg: func(c cursor) any { return c.Float },
+		}, nil
+	}
+
+	return eventSort{}, fmt.Errorf("unknown sort %q", sort)
+}
+
+// eventPageQuery - выборка событий для постраничной выдачи
+type eventPageQuery struct {
+	// from - источник строк, начинающийся с eventFrom
+	from  string
+	where string
+	args  []any
+	// columns - дополнительные колонки после eventColumns, которые читает scan
+	columns string
+	// scan читает eventColumns, columns и затем extra
+	scan func(row rowScanner, extra ...any) (storage.Event, error)
+	// ids - порядок событий для сортировки по релевантности
+	ids []int64
+	// near - точка, до которой вычисляется расстояние
+	near *geo.Point
+}
+
+// queryEventPage выбирает страницу событий с keyset-пагинацией по паре (ключ сортировки, EventID).
+// В отличие от OFFSET курсор не сдвигается, когда между запросами страниц добавляются или удаляются события.
+func (r *Storage) queryEventPage(q eventPageQuery, page storage.PageRequest) (storage.EventPage, error) {
+	order, err := newEventSort(page.Sort, q.ids, q.near)
+	if err != nil {
+		return storage.EventPage{}, err
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) "+q.from+" WHERE "+q.where, q.args...).Scan(&total); err != nil {
+		return storage.EventPage{}, fmt.Errorf("failed to count events: %w", err)
+	}
+
+	dir, cmp := "ASC", ">"
+	if page.Order == storage.OrderDesc {
+		dir, cmp = "DESC", "<"
+	}
+
+	columns := q.columns
+	var args []any
+	var distance sql.NullFloat64
+	var extra []any
+	if q.near != nil {
+		columns += ", " + distanceExpr
+		args = append(args, q.near.Lng, q.near.Lat)
+		extra = append(extra, &distance)
+	}
+
+	where := q.where
+	args = append(args, q.args...)
+
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor, page)
+		if err != nil {
+			return storage.EventPage{}, err
+		}
+
+		where += fmt.Sprintf(" AND (%[1]s %[2]s ? OR (%[1]s = ? AND e.EventID %[2]s ?))", order.expr, cmp)
+		args = append(args, order.args...)
+		args = append(args, order.arg(c))
+		args = append(args, order.args...)
+		args = append(args, order.arg(c), c.ID)
+	}
+
+	query := "SELECT " + eventColumns + columns + " " + q.from +
+		" WHERE " + where +
+		" ORDER BY " + order.expr + " " + dir + ", e.EventID " + dir +
+		" LIMIT ?"
+	args = append(args, order.args...)
+	// Лишняя строка показывает, что есть следующая страница
+	args = append(args, page.Limit+1)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return storage.EventPage{}, fmt.Errorf("query execution error: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]storage.Event, 0, page.Limit)
+	for rows.Next() {
+		e, err := q.scan(rows, extra...)
+		if err != nil {
+			return storage.EventPage{}, fmt.Errorf("row scanning error: %w", err)
+		}
+		if distance.Valid {
+			km := distance.Float64
+			e.DistanceKm = &km
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return storage.EventPage{}, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	result := storage.EventPage{PageInfo: storage.PageInfo{Total: total}}
+
+	if len(events) > page.Limit {
+		events = events[:page.Limit]
+
+		last := events[len(events)-1]
+		c := cursor{Sort: page.Sort, Order: page.Order, ID: last.EventID}
+		order.value(last, &c)
+		result.NextCursor = encodeCursor(c)
+	}
+
+	if err := r.attachTags(events); err != nil {
+		return storage.EventPage{}, err
+	}
+	result.Events = events
+
+	return result, nil
+}
+
+// scanPlainEvent читает строку, выбранную через eventColumns без собственных дополнительных колонок
+func scanPlainEvent(row rowScanner, extra ...any) (storage.Event, error) {
+	return scanEvent(row, extra...)
+}
+
+// normalizePage подставляет значения по умолчанию для пустых параметров страницы
+func normalizePage(page storage.PageRequest) storage.PageRequest {
+	if page.Sort == "" {
+		page.Sort = storage.SortDate
+	}
+	if page.Order == "" {
+		page.Order = storage.DefaultOrder(page.Sort)
+	}
+	if page.Limit <= 0 {
+		page.Limit = storage.DefaultPageLimit
+	}
+	if page.Limit > storage.MaxPageLimit {
+		page.Limit = storage.MaxPageLimit
+	}
+	return page
+}
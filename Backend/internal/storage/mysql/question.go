@@ -0,0 +1,203 @@
+package mysql
+
+import (
+	"Backend/internal/storage"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// eventQuestions возвращает анкету регистрации события в порядке вопросов
+func eventQuestions(q queryer, eventID int64) ([]storage.Question, error) {
+	rows, err := q.Query(`
+		SELECT QuestionID, Type, Label, Required, Options, MaxLength, COALESCE(Pattern, '')
+		FROM RegistrationQuestion
+		WHERE EventID = ?
+		ORDER BY Position
+	`, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query questions: %w", err)
+	}
+	defer rows.Close()
+
+	var questions []storage.Question
+	for rows.Next() {
+		var question storage.Question
+		var options []byte
+		var maxLength sql.NullInt64
+		err := rows.Scan(
+			&question.ID, &question.Type, &question.Label, &question.Required, &options, &maxLength, &question.Pattern,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan question: %w", err)
+		}
+		if options != nil {
+			if err := json.Unmarshal(options, &question.Options); err != nil {
+				return nil, fmt.Errorf("failed to decode question options: %w", err)
+			}
+		}
+		if maxLength.Valid {
+			n := int(maxLength.Int64)
+			question.MaxLength = &n
+		}
+		questions = append(questions, question)
+	}
+
+	return questions, rows.Err()
+}
+
+// SetEventQuestions заменяет анкету регистрации события. Вопросы с ID изменяются и сохраняют ответы,
+// вопросы без ID добавляются, отсутствующие в анкете удаляются вместе с ответами.
+// Возвращает анкету с идентификаторами новых вопросов.
+func (r *Storage) SetEventQuestions(eventID int, questions []storage.Question) ([]storage.Question, error) {
+	const op = "mysql.SetEventQuestions"
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockEvent(tx, eventID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	current, err := eventQuestions(tx, int64(eventID))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	removed := make(map[int64]bool, len(current))
+	for _, question := range current {
+		removed[question.ID] = true
+	}
+
+	saved := make([]storage.Question, 0, len(questions))
+	for position, question := range questions {
+		var options []byte
+		if len(question.Options) > 0 {
+			options, err = json.Marshal(question.Options)
+			if err != nil {
+				return nil, fmt.Errorf("%s: failed to encode options: %w", op, err)
+			}
+		}
+
+		args := []any{position, question.Type, question.Label, question.Required, options, question.MaxLength, question.Pattern}
+
+		if question.ID != 0 {
+			if !removed[question.ID] {
+				return nil, fmt.Errorf("%s: %w", op, storage.ErrQuestionNotFound)
+			}
+			delete(removed, question.ID)
+
+			_, err := tx.Exec(`
+				UPDATE RegistrationQuestion
+				SET Position = ?, Type = ?, Label = ?, Required = ?, Options = ?, MaxLength = ?, Pattern = NULLIF(?, '')
+				WHERE QuestionID = ?
+			`, append(args, question.ID)...)
+			if err != nil {
+				return nil, fmt.Errorf("%s: failed to update question: %w", op, err)
+			}
+
+			// Ответы, которые не подходят к измененному вопросу, перестают иметь смысл
+			if question.Type != questionType(current, question.ID) {
+				if _, err := tx.Exec("DELETE FROM RegistrationAnswer WHERE QuestionID = ?", question.ID); err != nil {
+					return nil, fmt.Errorf("%s: failed to delete answers: %w", op, err)
+				}
+			}
+		} else {
+			result, err := tx.Exec(`
+				INSERT INTO RegistrationQuestion (EventID, Position, Type, Label, Required, Options, MaxLength, Pattern)
+				VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''))
+			`, append([]any{eventID}, args...)...)
+			if err != nil {
+				return nil, fmt.Errorf("%s: failed to insert question: %w", op, err)
+			}
+			question.ID, err = result.LastInsertId()
+			if err != nil {
+				return nil, fmt.Errorf("%s: failed to get question id: %w", op, err)
+			}
+		}
+
+		saved = append(saved, question)
+	}
+
+	for id := range removed {
+		if _, err := tx.Exec("DELETE FROM RegistrationQuestion WHERE QuestionID = ?", id); err != nil {
+			return nil, fmt.Errorf("%s: failed to delete question: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+// saveAnswers сохраняет ответы участника на анкету регистрации
+func saveAnswers(tx *sql.Tx, userID, eventID int, answers []storage.Answer) error {
+	for _, answer := range answers {
+		value := answer.Value
+		if answer.Values != nil {
+			encoded, err := json.Marshal(answer.Values)
+			if err != nil {
+				return fmt.Errorf("failed to encode answer: %w", err)
+			}
+			value = string(encoded)
+		}
+
+		_, err := tx.Exec(
+			"INSERT INTO RegistrationAnswer (UserID, EventID, QuestionID, Value) VALUES (?, ?, ?, ?)",
+			userID, eventID, answer.QuestionID, value,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save answer: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// attendeeAnswers возвращает ответы участников события на анкету по идентификаторам пользователей
+func attendeeAnswers(q queryer, eventID int) (map[int64][]storage.Answer, error) {
+	rows, err := q.Query(`
+		SELECT a.UserID, a.QuestionID, q.Type, a.Value
+		FROM RegistrationAnswer a
+		JOIN RegistrationQuestion q ON q.QuestionID = a.QuestionID
+		WHERE a.EventID = ?
+		ORDER BY q.Position
+	`, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query answers: %w", err)
+	}
+	defer rows.Close()
+
+	answers := make(map[int64][]storage.Answer)
+	for rows.Next() {
+		var userID int64
+		var answer storage.Answer
+		var questionType string
+		if err := rows.Scan(&userID, &answer.QuestionID, &questionType, &answer.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan answer: %w", err)
+		}
+		if questionType == storage.QuestionMulti {
+			if err := json.Unmarshal([]byte(answer.Value), &answer.Values); err != nil {
+				return nil, fmt.Errorf("failed to decode answer: %w", err)
+			}
+			answer.Value = ""
+		}
+		answers[userID] = append(answers[userID], answer)
+	}
+
+	return answers, rows.Err()
+}
+
+func questionType(questions []storage.Question, id int64) string {
+	for _, question := range questions {
+		if question.ID == id {
+			return question.Type
+		}
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+package mysql
+
+import (
+	"Backend/internal/storage"
+	"fmt"
+	"time"
+)
+
+// GetEventsForIndex возвращает события, измененные начиная с after, для поискового индекса.
+// Выборка упорядочена по (UpdatedAt, EventID) и продолжается после пары (after, afterID),
+// чтобы события с одинаковым временем изменения не терялись между страницами.
+func (r *Storage) GetEventsForIndex(after time.Time, afterID int64, limit int) ([]storage.SearchDocument, error) {
+	const op = "storage.GetEventsForIndex"
+
+	rows, err := r.db.Query(`
+		SELECT EventID, Title, Description, EventAddress, UpdatedAt
+		FROM Event
+		WHERE UpdatedAt > ? OR (UpdatedAt = ? AND EventID > ?)
+		ORDER BY UpdatedAt, EventID
+		LIMIT ?
+	`, after, after, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var docs []storage.SearchDocument
+	var ids []int64
+	for rows.Next() {
+		var doc storage.SearchDocument
+		if err := rows.Scan(&doc.ID, &doc.Title, &doc.Description, &doc.Address, &doc.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		docs = append(docs, doc)
+		ids = append(ids, doc.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tags, err := eventTags(r.db, ids)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	for i := range docs {
+		docs[i].Tags = tags[docs[i].ID]
+	}
+
+	return docs, nil
+}
@@ -0,0 +1,702 @@
+package mysql
+
+import (
+	"Backend/internal/lib/geo"
+	"Backend/internal/lib/rrule"
+	"Backend/internal/storage"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// seriesHorizon - на сколько вперед создаются повторения серии. Более поздние повторения
+// создаются фоновой задачей по мере приближения, поэтому бесконечные правила допустимы.
+const seriesHorizon = 180 * 24 * time.Hour
+
+// seriesExtendMargin - насколько горизонт серии может отстать, прежде чем она будет продлена,
+// чтобы фоновая задача не обновляла каждую серию при каждом запуске
+const seriesExtendMargin = 24 * time.Hour
+
+// scheduleChangedReason - причина отмены повторений, исключенных новым правилом серии
+const scheduleChangedReason = "Изменено расписание серии мероприятий"
+
+// series - серия повторяющихся событий
+type series struct {
+	id                int64
+	creatorUserID     int64
+	start             time.Time
+	rule              rrule.Rule
+	exDates           []time.Time
+	materializedUntil time.Time
+}
+
+// occurrence - повторение серии, которое затрагивает изменение
+type occurrence struct {
+	eventID int
+	date    time.Time
+}
+
+// addSeries создает серию и ее повторения до горизонта и возвращает первое из них
+func (r *Storage) addSeries(dto storage.EventCreateDto) (int64, error) {
+	rule, err := rrule.Parse(dto.Recurrence.RRule)
+	if err != nil {
+		return 0, err
+	}
+
+	s := series{
+		creatorUserID: dto.CreatorUserID,
+		// Повторения вычисляются по часам места проведения
+		start:   dto.EventDate.In(eventLocation(dto.TimeZone)),
+		rule:    rule,
+		exDates: dto.Recurrence.Exceptions,
+	}
+	s.materializedUntil = horizon(s.start, time.Now())
+
+	dates := s.expand(time.Time{}, s.materializedUntil)
+	if len(dates) == 0 {
+		return 0, storage.ErrNoOccurrences
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := insertSeries(tx, &s); err != nil {
+		return 0, err
+	}
+
+	var firstID int64
+	for i, date := range dates {
+		id, err := insertEvent(tx, dto, date, &s.id)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create occurrence %s: %w", date.Format(time.RFC3339), err)
+		}
+		if i == 0 {
+			firstID = id
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return firstID, nil
+}
+
+// editSeries применяет изменение к повторению и следующим за ним или ко всем повторениям серии.
+// Перенос даты сдвигает все затронутые повторения на ту же величину. Если меняется правило
+// или переносятся только следующие повторения, расписание вычисляется заново: совпавшие
+// повторения сохраняют регистрации, лишние удаляются или отменяются, недостающие создаются.
+func editSeries(tx *sql.Tx, eventId int, dto storage.EventCreateDto, scope string) (storage.EditResult, error) {
+	var seriesID sql.NullInt64
+	var occurrenceDate sql.NullTime
+	var eventDate time.Time
+	err := tx.QueryRow(
+		"SELECT SeriesID, OccurrenceDate, EventDate FROM Event WHERE EventID = ?", eventId,
+	).Scan(&seriesID, &occurrenceDate, &eventDate)
+	if err != nil {
+		return storage.EditResult{}, fmt.Errorf("failed to fetch event: %w", err)
+	}
+
+	if !seriesID.Valid {
+		return storage.EditResult{}, storage.ErrNotRecurring
+	}
+
+	s, err := lockSeries(tx, seriesID.Int64)
+	if err != nil {
+		return storage.EditResult{}, err
+	}
+	if dto.TimeZone != "" {
+		s.start = s.start.In(eventLocation(dto.TimeZone))
+	}
+
+	loc := s.start.Location()
+	from := occurrenceDate.Time.In(loc)
+	if scope == storage.ScopeAll {
+		from = time.Time{}
+	}
+	shift := dto.EventDate.Sub(eventDate)
+
+	targets, err := seriesOccurrences(tx, s.id, from)
+	if err != nil {
+		return storage.EditResult{}, err
+	}
+
+	// Без нового правила серия просто сдвигается целиком, пересчет расписания не нужен
+	if dto.Recurrence == nil && (scope == storage.ScopeAll || shift == 0) {
+		return shiftOccurrences(tx, &s, targets, dto, shift, scope == storage.ScopeAll)
+	}
+
+	rule := s.rule
+	exDates := shiftDates(s.exDates, shift)
+	if dto.Recurrence != nil {
+		rule, err = rrule.Parse(dto.Recurrence.RRule)
+		if err != nil {
+			return storage.EditResult{}, err
+		}
+		exDates = append(exDates, dto.Recurrence.Exceptions...)
+	}
+
+	target := s
+	target.start = s.start.Add(shift)
+	target.rule = rule
+	target.exDates = exDates
+
+	// Изменение следующих повторений отделяет их в новую серию, а прежняя заканчивается перед ними
+	if scope == storage.ScopeFollowing && from.After(s.start) {
+		if dto.Recurrence == nil && s.rule.Count > 0 {
+			target.rule.Count = remainingCount(s.rule, s.start, from)
+			if target.rule.Count <= 0 {
+				return storage.EditResult{}, storage.ErrNoOccurrences
+			}
+		}
+
+		s.rule.Count = 0
+		s.rule.Until = from.Add(-time.Second)
+		if err := updateSeries(tx, s); err != nil {
+			return storage.EditResult{}, err
+		}
+
+		target.start = from.Add(shift)
+		if err := insertSeries(tx, &target); err != nil {
+			return storage.EditResult{}, err
+		}
+	}
+
+	var status string
+	if err := tx.QueryRow("SELECT Status FROM Event WHERE EventID = ?", eventId).Scan(&status); err != nil {
+		return storage.EditResult{}, fmt.Errorf("failed to fetch event status: %w", err)
+	}
+
+	// Новые повторения создаются от имени автора серии в статусе изменяемого повторения
+	dto.CreatorUserID = s.creatorUserID
+	dto.Status = status
+
+	return reschedule(tx, target, targets, dto, shift)
+}
+
+// shiftOccurrences обновляет поля повторений и сдвигает их даты
+func shiftOccurrences(tx *sql.Tx, s *series, targets []occurrence, dto storage.EventCreateDto, shift time.Duration, wholeSeries bool) (storage.EditResult, error) {
+	var result storage.EditResult
+
+	for _, t := range moveOrder(targets, shift) {
+		grew, err := updateEventFields(tx, t.eventID, dto, nil)
+		if err != nil {
+			return storage.EditResult{}, err
+		}
+
+		if shift != 0 {
+			seconds := int64(shift / time.Second)
+			_, err := tx.Exec(
+				"UPDATE Event SET EventDate = EventDate + INTERVAL ? SECOND, OccurrenceDate = OccurrenceDate + INTERVAL ? SECOND WHERE EventID = ?",
+				seconds, seconds, t.eventID,
+			)
+			if err != nil {
+				return storage.EditResult{}, fmt.Errorf("failed to move occurrence: %w", err)
+			}
+			if err := refreshLocalStart(tx, t.eventID); err != nil {
+				return storage.EditResult{}, err
+			}
+		}
+
+		if !grew {
+			continue
+		}
+		promoted, err := promoteWaitlisted(tx, t.eventID)
+		if err != nil {
+			return storage.EditResult{}, err
+		}
+		result.Promoted = append(result.Promoted, promoted...)
+	}
+
+	if wholeSeries && shift != 0 {
+		s.start = s.start.Add(shift)
+		s.exDates = shiftDates(s.exDates, shift)
+		s.materializedUntil = s.materializedUntil.Add(shift)
+		if err := updateSeries(tx, *s); err != nil {
+			return storage.EditResult{}, err
+		}
+	}
+
+	return result, nil
+}
+
+// reschedule приводит повторения targets в соответствие с правилом серии s начиная с текущего момента.
+// Повторение сохраняется, если его дата, сдвинутая на shift, есть в новом расписании.
+func reschedule(tx *sql.Tx, s series, targets []occurrence, dto storage.EventCreateDto, shift time.Duration) (storage.EditResult, error) {
+	var result storage.EditResult
+
+	now := time.Now()
+	s.materializedUntil = horizon(s.start, now)
+	if err := updateSeries(tx, s); err != nil {
+		return storage.EditResult{}, err
+	}
+
+	kept, removed, created := planSchedule(s, targets, shift, now)
+
+	for _, t := range removed {
+		cancellation, err := removeOccurrence(tx, t.eventID)
+		if err != nil {
+			return storage.EditResult{}, err
+		}
+		if cancellation != nil {
+			result.Cancelled = append(result.Cancelled, *cancellation)
+		}
+	}
+
+	for _, t := range moveOrder(kept, shift) {
+		date := t.date.Add(shift)
+		grew, err := updateEventFields(tx, t.eventID, dto, &date)
+		if err != nil {
+			return storage.EditResult{}, err
+		}
+
+		_, err = tx.Exec("UPDATE Event SET SeriesID = ?, OccurrenceDate = ? WHERE EventID = ?", s.id, date, t.eventID)
+		if err != nil {
+			return storage.EditResult{}, fmt.Errorf("failed to move occurrence: %w", err)
+		}
+
+		if !grew {
+			continue
+		}
+		promoted, err := promoteWaitlisted(tx, t.eventID)
+		if err != nil {
+			return storage.EditResult{}, err
+		}
+		result.Promoted = append(result.Promoted, promoted...)
+	}
+
+	for _, date := range created {
+		if _, err := insertEvent(tx, dto, date, &s.id); err != nil {
+			return storage.EditResult{}, fmt.Errorf("failed to create occurrence %s: %w", date.Format(time.RFC3339), err)
+		}
+	}
+
+	return result, nil
+}
+
+// planSchedule сопоставляет повторения targets, сдвинутые на shift, с расписанием серии s до ее горизонта.
+// Повторения с датой из расписания сохраняются (kept), остальные будущие убираются (removed), а даты
+// расписания без повторения возвращаются по возрастанию для создания (created). Уже начавшиеся повторения
+// остаются как есть и завершаются обычным порядком, прошедшие даты не создаются заново.
+func planSchedule(s series, targets []occurrence, shift time.Duration, now time.Time) (kept, removed []occurrence, created []time.Time) {
+	planned := make(map[int64]time.Time)
+	for _, date := range s.expand(time.Time{}, s.materializedUntil) {
+		if !date.Before(now) {
+			planned[date.Unix()] = date
+		}
+	}
+
+	for _, t := range targets {
+		if t.date.Before(now) {
+			continue
+		}
+		date := t.date.Add(shift)
+		if _, ok := planned[date.Unix()]; ok {
+			kept = append(kept, t)
+			delete(planned, date.Unix())
+			continue
+		}
+		removed = append(removed, t)
+	}
+
+	for _, date := range planned {
+		created = append(created, date)
+	}
+	sort.Slice(created, func(i, j int) bool { return created[i].Before(created[j]) })
+
+	return kept, removed, created
+}
+
+// removeOccurrence убирает повторение, исключенное из расписания. Повторение без регистраций
+// удаляется, а с регистрациями отменяется, чтобы участники получили уведомление.
+func removeOccurrence(tx *sql.Tx, eventID int) (*storage.EventCancellation, error) {
+	var registrations int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM Registration WHERE EventID = ?", eventID).Scan(&registrations); err != nil {
+		return nil, fmt.Errorf("failed to count registrations: %w", err)
+	}
+
+	if registrations == 0 {
+		if _, err := tx.Exec("DELETE FROM Event WHERE EventID = ?", eventID); err != nil {
+			return nil, fmt.Errorf("failed to delete occurrence: %w", err)
+		}
+		return nil, nil
+	}
+
+	cancellation, err := cancelEvent(tx, eventID, scheduleChangedReason)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cancellation, nil
+}
+
+// remainingCount возвращает, сколько повторений правила с COUNT, начинающегося в start, остается с from,
+// когда серия делится на две: прежняя заканчивается перед from, а новая забирает оставшиеся повторения
+func remainingCount(rule rrule.Rule, start, from time.Time) int {
+	before, _ := rule.Occurrences(start, from.Add(-time.Second), 0)
+	return rule.Count - len(before)
+}
+
+// moveOrder возвращает повторения в порядке, в котором их можно сдвинуть, не заняв дату соседнего:
+// при сдвиге вперед сначала переносятся поздние повторения
+func moveOrder(occurrences []occurrence, shift time.Duration) []occurrence {
+	ordered := append([]occurrence(nil), occurrences...)
+	sort.Slice(ordered, func(i, j int) bool {
+		if shift > 0 {
+			return ordered[i].date.After(ordered[j].date)
+		}
+		return ordered[i].date.Before(ordered[j].date)
+	})
+	return ordered
+}
+
+// PublishEvent переводит черновик в опубликованные, после чего событие видно всем и открыто для регистрации.
+// Для повторения серии scope позволяет опубликовать также следующие или все черновики серии.
+func (r *Storage) PublishEvent(eventID int, scope string) error {
+	const op = "mysql.PublishEvent"
+
+	var status string
+	var seriesID sql.NullInt64
+	var occurrenceDate sql.NullTime
+	err := r.db.QueryRow(
+		"SELECT Status, SeriesID, OccurrenceDate FROM Event WHERE EventID = ?", eventID,
+	).Scan(&status, &seriesID, &occurrenceDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s: %w", op, storage.ErrEventNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := "UPDATE Event SET Status = ? WHERE Status = ? AND EventID = ?"
+	args := []any{storage.EventPublished, storage.EventDraft, eventID}
+
+	switch scope {
+	case "", storage.ScopeThis:
+	case storage.ScopeFollowing, storage.ScopeAll:
+		if !seriesID.Valid {
+			return fmt.Errorf("%s: %w", op, storage.ErrNotRecurring)
+		}
+
+		query = "UPDATE Event SET Status = ? WHERE Status = ? AND SeriesID = ?"
+		args = []any{storage.EventPublished, storage.EventDraft, seriesID.Int64}
+		if scope == storage.ScopeFollowing {
+			query += " AND OccurrenceDate >= ?"
+			args = append(args, occurrenceDate.Time)
+		}
+	default:
+		return fmt.Errorf("%s: %q: %w", op, scope, storage.ErrInvalidScope)
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: event is %s: %w", op, status, storage.ErrInvalidTransition)
+	}
+
+	return nil
+}
+
+// ExtendSeries создает повторения серий, горизонт которых подошел к концу.
+// Новые повторения копируют последнее действующее повторение серии, поэтому
+// изменения "это и следующие" распространяются и на них.
+func (r *Storage) ExtendSeries(now time.Time) (int, error) {
+	const op = "mysql.ExtendSeries"
+
+	rows, err := r.db.Query("SELECT SeriesID FROM EventSeries WHERE MaterializedUntil < ?", now.Add(seriesHorizon-seriesExtendMargin))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Ошибка в одной серии не должна останавливать продление остальных
+	created := 0
+	var errs []error
+	for _, id := range ids {
+		n, err := r.extendSeries(id, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("series %d: %w", id, err))
+			continue
+		}
+		created += n
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return created, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+func (r *Storage) extendSeries(seriesID int64, now time.Time) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	s, err := lockSeries(tx, seriesID)
+	if err != nil {
+		return 0, err
+	}
+
+	until := horizon(s.start, now)
+	dates := s.expand(s.materializedUntil, until)
+
+	if len(dates) > 0 {
+		var dto storage.EventCreateDto
+		var templateID int64
+		var templateCategory sql.NullString
+		var lat, lng sql.NullFloat64
+		var requiresApproval bool
+		err := tx.QueryRow(`
+			SELECT e.EventID, e.Title, COALESCE(e.Description, ''), e.EventAddress, e.CreatorUserID, COALESCE(e.VKLink, ''),
+			       COALESCE(e.TGLink, ''), COALESCE(e.ImageURL, ''), e.Capacity, e.Status, c.Slug, e.TimeZone,
+			       e.Latitude, e.Longitude, e.VenueID, e.DurationMinutes, e.Visibility,
+			       e.RequiresApproval
+			FROM Event e
+			LEFT JOIN Category c ON c.CategoryID = e.CategoryID
+			WHERE e.SeriesID = ? AND e.Status IN (?, ?)
+			ORDER BY e.OccurrenceDate DESC
+			LIMIT 1
+		`, s.id, storage.EventDraft, storage.EventPublished).Scan(
+			&templateID, &dto.Title, &dto.Description, &dto.EventAddress, &dto.CreatorUserID, &dto.VKLink,
+			&dto.TGLink, &dto.ImageURL, &dto.Capacity, &dto.Status, &templateCategory, &dto.TimeZone,
+			&lat, &lng, &dto.VenueID, &dto.DurationMinutes, &dto.Visibility,
+			&requiresApproval,
+		)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			// Все повторения отменены или завершены, серию продолжать не по чему
+			dates = nil
+		case err != nil:
+			return 0, fmt.Errorf("failed to fetch template occurrence: %w", err)
+		default:
+			dto.Category = templateCategory.String
+			dto.RequiresApproval = &requiresApproval
+			if lat.Valid && lng.Valid {
+				dto.Location = &geo.Point{Lat: lat.Float64, Lng: lng.Float64}
+			}
+			tags, err := eventTags(tx, []int64{templateID})
+			if err != nil {
+				return 0, err
+			}
+			dto.Tags = tags[templateID]
+		}
+
+		for _, date := range dates {
+			if _, err := insertEvent(tx, dto, date, &s.id); err != nil {
+				return 0, fmt.Errorf("failed to create occurrence %s: %w", date.Format(time.RFC3339), err)
+			}
+		}
+	}
+
+	s.materializedUntil = until
+	if err := updateSeries(tx, s); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return len(dates), nil
+}
+
+// excludeOccurrence добавляет дату повторения в исключения серии, чтобы оно не появилось снова
+// при пересчете расписания. Для событий вне серии ничего не делает.
+func excludeOccurrence(tx *sql.Tx, eventID int) error {
+	var seriesID sql.NullInt64
+	var occurrenceDate sql.NullTime
+	err := tx.QueryRow("SELECT SeriesID, OccurrenceDate FROM Event WHERE EventID = ?", eventID).Scan(&seriesID, &occurrenceDate)
+	if err != nil {
+		return fmt.Errorf("failed to fetch occurrence: %w", err)
+	}
+
+	if !seriesID.Valid || !occurrenceDate.Valid {
+		return nil
+	}
+
+	s, err := lockSeries(tx, seriesID.Int64)
+	if err != nil {
+		return err
+	}
+
+	s.exDates = append(s.exDates, occurrenceDate.Time)
+
+	return updateSeries(tx, s)
+}
+
+// lockSeries блокирует серию до конца транзакции. Даты серии возвращаются в ее часовом поясе.
+func lockSeries(tx *sql.Tx, seriesID int64) (series, error) {
+	s := series{id: seriesID}
+
+	var offset int
+	var timeZone, ruleValue string
+	var exDates sql.NullString
+	err := tx.QueryRow(`
+		SELECT CreatorUserID, StartDate, UTCOffset, COALESCE(TimeZone, ''), RRule, ExDates, MaterializedUntil
+		FROM EventSeries
+		WHERE SeriesID = ?
+		FOR UPDATE
+	`, seriesID).Scan(&s.creatorUserID, &s.start, &offset, &timeZone, &ruleValue, &exDates, &s.materializedUntil)
+	if err != nil {
+		return series{}, fmt.Errorf("failed to lock series: %w", err)
+	}
+
+	loc := seriesLocation(timeZone, offset)
+	s.start = s.start.In(loc)
+	s.materializedUntil = s.materializedUntil.In(loc)
+
+	s.rule, err = rrule.Parse(ruleValue)
+	if err != nil {
+		return series{}, fmt.Errorf("series %d: %w", seriesID, err)
+	}
+
+	if exDates.String != "" {
+		for _, value := range strings.Split(exDates.String, ",") {
+			date, err := rrule.ParseDate(value)
+			if err != nil {
+				return series{}, fmt.Errorf("series %d: invalid exception %q: %w", seriesID, value, err)
+			}
+			s.exDates = append(s.exDates, date)
+		}
+	}
+
+	return s, nil
+}
+
+// insertSeries сохраняет новую серию и записывает ее идентификатор в s
+func insertSeries(tx *sql.Tx, s *series) error {
+	_, offset := s.start.Zone()
+
+	result, err := tx.Exec(`
+		INSERT INTO EventSeries (CreatorUserID, StartDate, UTCOffset, TimeZone, RRule, ExDates, MaterializedUntil)
+		VALUES (?, ?, ?, NULLIF(?, ''), ?, ?, ?)
+	`, s.creatorUserID, s.start, offset, zoneName(s.start.Location()), s.rule.String(), formatExDates(s.exDates), s.materializedUntil)
+	if err != nil {
+		return fmt.Errorf("failed to create series: %w", err)
+	}
+
+	s.id, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get series id: %w", err)
+	}
+
+	return nil
+}
+
+func updateSeries(tx *sql.Tx, s series) error {
+	_, offset := s.start.Zone()
+
+	_, err := tx.Exec(`
+		UPDATE EventSeries
+		SET StartDate = ?, UTCOffset = ?, TimeZone = NULLIF(?, ''), RRule = ?, ExDates = ?, MaterializedUntil = ?
+		WHERE SeriesID = ?
+	`, s.start, offset, zoneName(s.start.Location()), s.rule.String(), formatExDates(s.exDates), s.materializedUntil, s.id)
+	if err != nil {
+		return fmt.Errorf("failed to update series: %w", err)
+	}
+
+	return nil
+}
+
+// seriesOccurrences блокирует и возвращает незакрытые повторения серии с датой не раньше from
+func seriesOccurrences(tx *sql.Tx, seriesID int64, from time.Time) ([]occurrence, error) {
+	rows, err := tx.Query(`
+		SELECT EventID, OccurrenceDate
+		FROM Event
+		WHERE SeriesID = ? AND Status IN (?, ?) AND OccurrenceDate >= ?
+		ORDER BY OccurrenceDate
+		FOR UPDATE
+	`, seriesID, storage.EventDraft, storage.EventPublished, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query occurrences: %w", err)
+	}
+	defer rows.Close()
+
+	var occurrences []occurrence
+	for rows.Next() {
+		var o occurrence
+		if err := rows.Scan(&o.eventID, &o.date); err != nil {
+			return nil, fmt.Errorf("failed to scan occurrence: %w", err)
+		}
+		occurrences = append(occurrences, o)
+	}
+
+	return occurrences, rows.Err()
+}
+
+// expand возвращает повторения серии в интервале (after, before] без исключенных дат
+func (s series) expand(after, before time.Time) []time.Time {
+	excluded := make(map[int64]bool, len(s.exDates))
+	for _, date := range s.exDates {
+		excluded[date.Unix()] = true
+	}
+
+	all, _ := s.rule.Occurrences(s.start, before, 0)
+
+	var dates []time.Time
+	for _, date := range all {
+		if date.After(after) && !excluded[date.Unix()] {
+			dates = append(dates, date)
+		}
+	}
+
+	return dates
+}
+
+// horizon возвращает дату, до которой создаются повторения серии, начинающейся в start
+func horizon(start, now time.Time) time.Time {
+	if start.After(now) {
+		return start.Add(seriesHorizon)
+	}
+	return now.In(start.Location()).Add(seriesHorizon)
+}
+
+func shiftDates(dates []time.Time, shift time.Duration) []time.Time {
+	shifted := make([]time.Time, 0, len(dates))
+	for _, date := range dates {
+		shifted = append(shifted, date.Add(shift))
+	}
+	return shifted
+}
+
+func formatExDates(dates []time.Time) any {
+	if len(dates) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(dates))
+	for _, date := range dates {
+		values = append(values, rrule.FormatDate(date))
+	}
+	return strings.Join(values, ",")
+}
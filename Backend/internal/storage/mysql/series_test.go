@@ -0,0 +1,191 @@
+package mysql
+
+import (
+	"Backend/internal/lib/rrule"
+	"slices"
+	"testing"
+	"time"
+)
+
+// day возвращает 10:00 UTC указанного дня января 2025 года
+func day(n int) time.Time {
+	return time.Date(2025, time.January, n, 10, 0, 0, 0, time.UTC)
+}
+
+func mustRule(t *testing.T, value string) rrule.Rule {
+	t.Helper()
+	rule, err := rrule.Parse(value)
+	if err != nil {
+		t.Fatalf("rrule.Parse(%q) unexpected error: %v", value, err)
+	}
+	return rule
+}
+
+func eventIDs(occurrences []occurrence) []int {
+	ids := make([]int, 0, len(occurrences))
+	for _, o := range occurrences {
+		ids = append(ids, o.eventID)
+	}
+	return ids
+}
+
+func equalDates(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMoveOrder(t *testing.T) {
+	occurrences := []occurrence{{eventID: 2, date: day(7)}, {eventID: 1, date: day(6)}, {eventID: 3, date: day(8)}}
+
+	tests := []struct {
+		name  string
+		shift time.Duration
+		want  []int
+	}{
+		{name: "forward moves later first", shift: 24 * time.Hour, want: []int{3, 2, 1}},
+		{name: "backward moves earlier first", shift: -24 * time.Hour, want: []int{1, 2, 3}},
+		{name: "no shift keeps date order", shift: 0, want: []int{1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := eventIDs(moveOrder(occurrences, tt.shift))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("moveOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := eventIDs(occurrences); !slices.Equal(got, []int{2, 1, 3}) {
+		t.Errorf("moveOrder() reordered its input: %v", got)
+	}
+}
+
+func TestRemainingCount(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		from time.Time
+		want int
+	}{
+		{name: "split in the middle", rule: "FREQ=DAILY;COUNT=5", from: day(8), want: 3},
+		{name: "split at the start", rule: "FREQ=DAILY;COUNT=5", from: day(6), want: 5},
+		{name: "split at the last occurrence", rule: "FREQ=DAILY;COUNT=5", from: day(10), want: 1},
+		{name: "split after the rule ends", rule: "FREQ=DAILY;COUNT=5", from: day(12), want: 0},
+		{name: "split between occurrences", rule: "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4", from: day(10), want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := remainingCount(mustRule(t, tt.rule), day(6), tt.from); got != tt.want {
+				t.Errorf("remainingCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanSchedule(t *testing.T) {
+	daily := []occurrence{
+		{eventID: 1, date: day(6)},
+		{eventID: 2, date: day(7)},
+		{eventID: 3, date: day(8)},
+		{eventID: 4, date: day(9)},
+	}
+
+	tests := []struct {
+		name        string
+		rule        string
+		start       time.Time
+		exDates     []time.Time
+		targets     []occurrence
+		shift       time.Duration
+		now         time.Time
+		wantKept    []int
+		wantRemoved []int
+		wantCreated []time.Time
+	}{
+		{
+			name:     "same schedule keeps everything",
+			rule:     "FREQ=DAILY;COUNT=4",
+			start:    day(6),
+			targets:  daily,
+			now:      day(1),
+			wantKept: []int{1, 2, 3, 4},
+		},
+		{
+			name:        "sparser schedule removes occurrences",
+			rule:        "FREQ=DAILY;INTERVAL=2;COUNT=2",
+			start:       day(6),
+			targets:     daily,
+			now:         day(1),
+			wantKept:    []int{1, 3},
+			wantRemoved: []int{2, 4},
+		},
+		{
+			name:        "longer schedule creates dates in order",
+			rule:        "FREQ=DAILY;COUNT=6",
+			start:       day(6),
+			targets:     daily,
+			now:         day(1),
+			wantKept:    []int{1, 2, 3, 4},
+			wantCreated: []time.Time{day(10), day(11)},
+		},
+		{
+			name:     "shifted occurrences match the shifted schedule",
+			rule:     "FREQ=DAILY;COUNT=4",
+			start:    day(6).Add(2 * time.Hour),
+			targets:  daily,
+			shift:    2 * time.Hour,
+			now:      day(1),
+			wantKept: []int{1, 2, 3, 4},
+		},
+		{
+			name:        "excluded dates are removed",
+			rule:        "FREQ=DAILY;COUNT=4",
+			start:       day(6),
+			exDates:     []time.Time{day(7)},
+			targets:     daily,
+			now:         day(1),
+			wantKept:    []int{1, 3, 4},
+			wantRemoved: []int{2},
+		},
+		{
+			name:        "started occurrences stay and past dates are not recreated",
+			rule:        "FREQ=DAILY;INTERVAL=2;COUNT=3",
+			start:       day(5),
+			targets:     daily,
+			now:         day(7).Add(time.Hour),
+			wantKept:    []int{4},
+			wantRemoved: []int{3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := series{
+				start:             tt.start,
+				rule:              mustRule(t, tt.rule),
+				exDates:           tt.exDates,
+				materializedUntil: day(31),
+			}
+
+			kept, removed, created := planSchedule(s, tt.targets, tt.shift, tt.now)
+			if got := eventIDs(kept); !slices.Equal(got, tt.wantKept) {
+				t.Errorf("planSchedule() kept = %v, want %v", got, tt.wantKept)
+			}
+			if got := eventIDs(removed); !slices.Equal(got, tt.wantRemoved) {
+				t.Errorf("planSchedule() removed = %v, want %v", got, tt.wantRemoved)
+			}
+			if !equalDates(created, tt.wantCreated) {
+				t.Errorf("planSchedule() created = %v, want %v", created, tt.wantCreated)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package mysql
+
+import (
+	"Backend/internal/storage"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// eventOrganizers возвращает создателя события и его команду в порядке добавления
+// вместе с еще не принятыми приглашениями в команду
+func eventOrganizers(q queryer, eventID int64) ([]storage.Organizer, error) {
+	rows, err := q.Query(`
+		SELECT u.UserID, u.FirstName, u.LastName, COALESCE(u.ImageUrl, ''), ?, ?, 0, e.CreatedAt
+		FROM Event e
+		JOIN User u ON u.UserID = e.CreatorUserID
+		WHERE e.EventID = ?
+
+		UNION ALL
+
+		SELECT u.UserID, u.FirstName, u.LastName, COALESCE(u.ImageUrl, ''), s.Role, s.Status, 1, s.AddedAt
+		FROM EventStaff s
+		JOIN User u ON u.UserID = s.UserID
+		WHERE s.EventID = ?
+
+		ORDER BY 7, 8
+	`, storage.StaffOwner, storage.StaffAccepted, eventID, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organizers: %w", err)
+	}
+	defer rows.Close()
+
+	var organizers []storage.Organizer
+	for rows.Next() {
+		var o storage.Organizer
+		var rank int
+		var added sql.NullTime
+		if err := rows.Scan(&o.UserID, &o.FirstName, &o.LastName, &o.ImageUrl, &o.Role, &o.Status, &rank, &added); err != nil {
+			return nil, fmt.Errorf("failed to scan organizer: %w", err)
+		}
+		organizers = append(organizers, o)
+	}
+
+	return organizers, rows.Err()
+}
+
+// AddEventStaff приглашает пользователя с указанным email в команду события или меняет его роль.
+// Новый участник команды получает права роли только после AcceptEventStaff, принятое приглашение при смене роли сохраняется.
+func (r *Storage) AddEventStaff(eventID int, email, role string) (storage.Organizer, error) {
+	const op = "mysql.AddEventStaff"
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return storage.Organizer{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockEvent(tx, eventID); err != nil {
+		return storage.Organizer{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	member, err := userByEmail(tx, email)
+	if err != nil {
+		return storage.Organizer{}, fmt.Errorf("%s: %w", op, err)
+	}
+	member.Role = role
+
+	var creatorID int64
+	if err := tx.QueryRow("SELECT CreatorUserID FROM Event WHERE EventID = ?", eventID).Scan(&creatorID); err != nil {
+		return storage.Organizer{}, fmt.Errorf("%s: failed to fetch event: %w", op, err)
+	}
+	if creatorID == member.UserID {
+		return storage.Organizer{}, fmt.Errorf("%s: %w", op, storage.ErrAlreadyOwner)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO EventStaff (EventID, UserID, Role, Status) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE Role = VALUES(Role)
+	`, eventID, member.UserID, role, storage.StaffInvited)
+	if err != nil {
+		return storage.Organizer{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = tx.QueryRow(
+		"SELECT Status FROM EventStaff WHERE EventID = ? AND UserID = ?", eventID, member.UserID,
+	).Scan(&member.Status)
+	if err != nil {
+		return storage.Organizer{}, fmt.Errorf("%s: failed to fetch staff status: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.Organizer{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return member, nil
+}
+
+// AcceptEventStaff принимает приглашение пользователя в команду события. Повторное принятие ничего не меняет.
+func (r *Storage) AcceptEventStaff(eventID int, userID int64) (storage.Organizer, error) {
+	const op = "mysql.AcceptEventStaff"
+
+	var member storage.Organizer
+	err := r.db.QueryRow(`
+		SELECT u.UserID, u.FirstName, u.LastName, COALESCE(u.ImageUrl, ''), s.Role
+		FROM EventStaff s
+		JOIN User u ON u.UserID = s.UserID
+		WHERE s.EventID = ? AND s.UserID = ?
+	`, eventID, userID).Scan(&member.UserID, &member.FirstName, &member.LastName, &member.ImageUrl, &member.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Organizer{}, fmt.Errorf("%s: %w", op, storage.ErrStaffNotFound)
+	}
+	if err != nil {
+		return storage.Organizer{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE EventStaff SET Status = ?, AcceptedAt = CURRENT_TIMESTAMP(6)
+		WHERE EventID = ? AND UserID = ? AND Status = ?
+	`, storage.StaffAccepted, eventID, userID, storage.StaffInvited)
+	if err != nil {
+		return storage.Organizer{}, fmt.Errorf("%s: %w", op, err)
+	}
+	member.Status = storage.StaffAccepted
+
+	return member, nil
+}
+
+// RemoveEventStaff исключает пользователя из команды события или отзывает приглашение в нее
+func (r *Storage) RemoveEventStaff(eventID int, userID int64) error {
+	const op = "mysql.RemoveEventStaff"
+
+	result, err := r.db.Exec("DELETE FROM EventStaff WHERE EventID = ? AND UserID = ?", eventID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrStaffNotFound)
+	}
+
+	return nil
+}
+
+// TransferOwnership передает событие пользователю с указанным email. Прежний создатель остается
+// в команде соорганизатором. Повторения серии передаются вместе с серией, чтобы новые повторения
+// создавались от имени нового владельца.
+func (r *Storage) TransferOwnership(eventID int, email string) (storage.Organizer, error) {
+	const op = "mysql.TransferOwnership"
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return storage.Organizer{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockEvent(tx, eventID); err != nil {
+		return storage.Organizer{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	owner, err := userByEmail(tx, email)
+	if err != nil {
+		return storage.Organizer{}, fmt.Errorf("%s: %w", op, err)
+	}
+	owner.Role, owner.Status = storage.StaffOwner, storage.StaffAccepted
+
+	var creatorID int64
+	var seriesID sql.NullInt64
+	err = tx.QueryRow("SELECT CreatorUserID, SeriesID FROM Event WHERE EventID = ?", eventID).Scan(&creatorID, &seriesID)
+	if err != nil {
+		return storage.Organizer{}, fmt.Errorf("%s: failed to fetch event: %w", op, err)
+	}
+	if creatorID == owner.UserID {
+		return storage.Organizer{}, fmt.Errorf("%s: %w", op, storage.ErrAlreadyOwner)
+	}
+
+	// Передаются только события прежнего владельца: повторения серии могли быть переданы раньше по отдельности
+	where, args := "EventID = ?", []any{eventID}
+	if seriesID.Valid {
+		where, args = "SeriesID = ? AND CreatorUserID = ?", []any{seriesID.Int64, creatorID}
+
+		_, err := tx.Exec("UPDATE EventSeries SET CreatorUserID = ? WHERE SeriesID = ?", owner.UserID, seriesID.Int64)
+		if err != nil {
+			return storage.Organizer{}, fmt.Errorf("%s: failed to transfer series: %w", op, err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO EventStaff (EventID, UserID, Role, Status, AcceptedAt)
+		SELECT EventID, CreatorUserID, ?, ?, CURRENT_TIMESTAMP(6) FROM Event WHERE `+where+`
+		ON DUPLICATE KEY UPDATE Role = VALUES(Role), Status = VALUES(Status), AcceptedAt = VALUES(AcceptedAt)
+	`, append([]any{storage.StaffCoOrganizer, storage.StaffAccepted}, args...)...)
+	if err != nil {
+		return storage.Organizer{}, fmt.Errorf("%s: failed to keep previous owner: %w", op, err)
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM EventStaff
+		WHERE UserID = ? AND EventID IN (SELECT EventID FROM Event WHERE `+where+`)
+	`, append([]any{owner.UserID}, args...)...)
+	if err != nil {
+		return storage.Organizer{}, fmt.Errorf("%s: failed to remove new owner from staff: %w", op, err)
+	}
+
+	_, err = tx.Exec("UPDATE Event SET CreatorUserID = ? WHERE "+where, append([]any{owner.UserID}, args...)...)
+	if err != nil {
+		return storage.Organizer{}, fmt.Errorf("%s: failed to transfer events: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.Organizer{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return owner, nil
+}
+
+// GetEventAttendees возвращает подтвержденных участников и лист ожидания события в порядке записи
+// вместе с ответами на анкету регистрации
+func (r *Storage) GetEventAttendees(eventID int) ([]storage.Attendee, error) {
+	const op = "mysql.GetEventAttendees"
+
+	answers, err := attendeeAnswers(r.db, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := r.db.Query(`
+		SELECT u.UserID, u.Email, u.FirstName, u.LastName, r.Status, r.RegisteredAt, r.CheckedInAt
+		FROM Registration r
+		JOIN User u ON u.UserID = r.UserID
+		WHERE r.EventID = ?
+		ORDER BY r.RegisteredAt, r.UserID
+	`, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	attendees := []storage.Attendee{}
+	for rows.Next() {
+		var a storage.Attendee
+		if err := rows.Scan(&a.UserID, &a.Email, &a.FirstName, &a.LastName, &a.Status, &a.RegisteredAt, &a.CheckedInAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		a.Answers = answers[a.UserID]
+		if a.Answers == nil {
+			a.Answers = []storage.Answer{}
+		}
+		attendees = append(attendees, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return attendees, nil
+}
+
+// userByEmail находит пользователя для добавления в команду события
+func userByEmail(q queryer, email string) (storage.Organizer, error) {
+	var o storage.Organizer
+
+	err := q.QueryRow(
+		"SELECT UserID, FirstName, LastName, COALESCE(ImageUrl, '') FROM User WHERE Email = ?", email,
+	).Scan(&o.UserID, &o.FirstName, &o.LastName, &o.ImageUrl)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Organizer{}, storage.ErrUserNotFound
+	}
+	if err != nil {
+		return storage.Organizer{}, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	return o, nil
+}
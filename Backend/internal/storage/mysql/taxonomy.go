@@ -0,0 +1,362 @@
+package mysql
+
+import (
+	"Backend/internal/lib/geo"
+	"Backend/internal/storage"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxTagFacets ограничивает число тегов в фасетах самыми популярными
+const maxTagFacets = 50
+
+// GetCategories возвращает справочник категорий
+func (r *Storage) GetCategories() ([]storage.Category, error) {
+	const op = "mysql.GetCategories"
+
+	rows, err := r.db.Query("SELECT Slug, Name FROM Category ORDER BY CategoryID")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var categories []storage.Category
+	for rows.Next() {
+		var c storage.Category
+		if err := rows.Scan(&c.Slug, &c.Name); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		categories = append(categories, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return categories, nil
+}
+
+// GetEventFacets возвращает количество подходящих под фильтр событий по категориям и тегам
+func (r *Storage) GetEventFacets(filter storage.EventFilter) (storage.Facets, error) {
+	const op = "mysql.GetEventFacets"
+
+	facets := storage.Facets{Categories: []storage.FacetCount{}, Tags: []storage.FacetCount{}}
+
+	// Выбранные категории не сужают собственный фасет, иначе в нем осталась бы только выбранная
+	withoutCategories := filter
+	withoutCategories.Categories = nil
+	where, args := eventFilterWhere(withoutCategories)
+
+	rows, err := r.db.Query(`
+		SELECT c.Slug, c.Name, COUNT(*)
+		FROM Event e
+		JOIN Category c ON c.CategoryID = e.CategoryID
+		WHERE `+where+`
+		GROUP BY c.CategoryID, c.Slug, c.Name
+		ORDER BY COUNT(*) DESC, c.Slug
+	`, args...)
+	if err != nil {
+		return storage.Facets{}, fmt.Errorf("%s: failed to count categories: %w", op, err)
+	}
+	for rows.Next() {
+		var f storage.FacetCount
+		if err := rows.Scan(&f.Value, &f.Name, &f.Count); err != nil {
+			rows.Close()
+			return storage.Facets{}, fmt.Errorf("%s: %w", op, err)
+		}
+		facets.Categories = append(facets.Categories, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return storage.Facets{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	withoutTags := filter
+	withoutTags.Tags = nil
+	where, args = eventFilterWhere(withoutTags)
+
+	rows, err = r.db.Query(`
+		SELECT t.Name, COUNT(*)
+		FROM Event e
+		JOIN EventTag et ON et.EventID = e.EventID
+		JOIN Tag t ON t.TagID = et.TagID
+		WHERE `+where+`
+		GROUP BY t.TagID, t.Name
+		ORDER BY COUNT(*) DESC, t.Name
+		LIMIT ?
+	`, append(args, maxTagFacets)...)
+	if err != nil {
+		return storage.Facets{}, fmt.Errorf("%s: failed to count tags: %w", op, err)
+	}
+	for rows.Next() {
+		var f storage.FacetCount
+		if err := rows.Scan(&f.Value, &f.Count); err != nil {
+			rows.Close()
+			return storage.Facets{}, fmt.Errorf("%s: %w", op, err)
+		}
+		facets.Tags = append(facets.Tags, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return storage.Facets{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return facets, nil
+}
+
+// eventFilterWhere строит условие WHERE общего списка событий для псевдонима e.
+// Черновики и непубличные события в общий список не попадают.
+func eventFilterWhere(filter storage.EventFilter) (string, []any) {
+	where := []string{"e.Status <> 'draft'", "e.Visibility = 'public'"}
+	var args []any
+
+	if filter.IDs != nil {
+		if len(filter.IDs) == 0 {
+			where = append(where, "FALSE")
+		} else {
+			where = append(where, "e.EventID IN ("+placeholders(len(filter.IDs))+")")
+			for _, id := range filter.IDs {
+				args = append(args, id)
+			}
+		}
+	}
+
+	if filter.Title != "" {
+		where = append(where, "e.Title LIKE ?")
+		args = append(args, "%"+filter.Title+"%")
+	}
+
+	if filter.From != nil {
+		where = append(where, "e.EventDate >= ?")
+		args = append(args, *filter.From)
+	}
+
+	if filter.To != nil {
+		where = append(where, "e.EventDate <= ?")
+		args = append(args, *filter.To)
+	}
+
+	// Даты и время суток сравниваются с часами места проведения
+	if filter.FromDate != "" {
+		where = append(where, "e.LocalStart >= ?")
+		args = append(args, filter.FromDate)
+	}
+
+	if filter.ToDate != "" {
+		where = append(where, "e.LocalStart < DATE_ADD(?, INTERVAL 1 DAY)")
+		args = append(args, filter.ToDate)
+	}
+
+	switch {
+	case filter.TimeFrom != "" && filter.TimeTo != "" && filter.TimeFrom > filter.TimeTo:
+		// Интервал через полночь, например с 22:00 до 02:00
+		where = append(where, "(TIME(e.LocalStart) >= ? OR TIME(e.LocalStart) <= ?)")
+		args = append(args, filter.TimeFrom, filter.TimeTo)
+	default:
+		if filter.TimeFrom != "" {
+			where = append(where, "TIME(e.LocalStart) >= ?")
+			args = append(args, filter.TimeFrom)
+		}
+		if filter.TimeTo != "" {
+			where = append(where, "TIME(e.LocalStart) <= ?")
+			args = append(args, filter.TimeTo)
+		}
+	}
+
+	if filter.Address != "" {
+		where = append(where, "e.EventAddress LIKE ?")
+		args = append(args, "%"+filter.Address+"%")
+	}
+
+	if filter.Near != nil {
+		// Прямоугольник вокруг круга использует индекс по координатам, точное расстояние проверяется после
+		box := geo.Around(*filter.Near, filter.RadiusKm)
+		cond, boxArgs := bboxWhere(box)
+		where = append(where, cond, distanceExpr+" <= ?")
+		args = append(args, boxArgs...)
+		args = append(args, filter.Near.Lng, filter.Near.Lat, filter.RadiusKm)
+	}
+
+	if filter.BBox != nil {
+		cond, boxArgs := bboxWhere(*filter.BBox)
+		where = append(where, cond)
+		args = append(args, boxArgs...)
+	}
+
+	if filter.VenueID != nil {
+		where = append(where, "e.VenueID = ?")
+		args = append(args, *filter.VenueID)
+	}
+
+	if len(filter.Categories) > 0 {
+		where = append(where, "e.CategoryID IN (SELECT CategoryID FROM Category WHERE Slug IN ("+placeholders(len(filter.Categories))+"))")
+		for _, slug := range filter.Categories {
+			args = append(args, slug)
+		}
+	}
+
+	if tags := normalizeTags(filter.Tags); len(tags) > 0 {
+		subquery := `e.EventID IN (
+			SELECT et.EventID FROM EventTag et JOIN Tag t ON t.TagID = et.TagID
+			WHERE t.Name IN (` + placeholders(len(tags)) + `)`
+		for _, tag := range tags {
+			args = append(args, tag)
+		}
+		if filter.MatchAllTags {
+			subquery += " GROUP BY et.EventID HAVING COUNT(*) = ?"
+			args = append(args, len(tags))
+		}
+		where = append(where, subquery+")")
+	}
+
+	return strings.Join(where, " AND "), args
+}
+
+// bboxWhere строит условие попадания координат события в прямоугольник
+func bboxWhere(box geo.BBox) (string, []any) {
+	cond := "e.Latitude BETWEEN ? AND ? AND "
+	args := []any{box.South, box.North, box.West, box.East}
+
+	// Прямоугольник через 180-й меридиан состоит из двух полос долготы
+	if box.West > box.East {
+		return cond + "(e.Longitude >= ? OR e.Longitude <= ?)", args
+	}
+	return cond + "e.Longitude BETWEEN ? AND ?", args
+}
+
+// queryer - общая часть *sql.DB и *sql.Tx
+type queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// categoryID находит категорию по slug. Пустой slug означает событие без категории.
+func categoryID(q queryer, slug string) (*int64, error) {
+	if slug == "" {
+		return nil, nil
+	}
+
+	var id int64
+	err := q.QueryRow("SELECT CategoryID FROM Category WHERE Slug = ?", slug).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%q: %w", slug, storage.ErrCategoryNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find category: %w", err)
+	}
+
+	return &id, nil
+}
+
+// setEventTags заменяет теги события, создавая новые теги при необходимости
+func setEventTags(q queryer, eventID int64, tags []string) error {
+	if _, err := q.Exec("DELETE FROM EventTag WHERE EventID = ?", eventID); err != nil {
+		return fmt.Errorf("failed to clear tags: %w", err)
+	}
+
+	// Изменение тегов не затрагивает строку события, поэтому отмечаем его для поискового индекса явно
+	if _, err := q.Exec("UPDATE Event SET UpdatedAt = CURRENT_TIMESTAMP(6) WHERE EventID = ?", eventID); err != nil {
+		return fmt.Errorf("failed to touch event: %w", err)
+	}
+
+	for _, tag := range normalizeTags(tags) {
+		// LAST_INSERT_ID(TagID) возвращает идентификатор и для уже существующего тега
+		result, err := q.Exec("INSERT INTO Tag (Name) VALUES (?) ON DUPLICATE KEY UPDATE TagID = LAST_INSERT_ID(TagID)", tag)
+		if err != nil {
+			return fmt.Errorf("failed to create tag %q: %w", tag, err)
+		}
+
+		tagID, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get tag id: %w", err)
+		}
+
+		if _, err := q.Exec("INSERT IGNORE INTO EventTag (EventID, TagID) VALUES (?, ?)", eventID, tagID); err != nil {
+			return fmt.Errorf("failed to tag event: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// eventTags возвращает теги событий по их идентификаторам
+func eventTags(q queryer, eventIDs []int64) (map[int64][]string, error) {
+	tags := make(map[int64][]string, len(eventIDs))
+	if len(eventIDs) == 0 {
+		return tags, nil
+	}
+
+	args := make([]any, len(eventIDs))
+	for i, id := range eventIDs {
+		args[i] = id
+	}
+
+	rows, err := q.Query(`
+		SELECT et.EventID, t.Name
+		FROM EventTag et
+		JOIN Tag t ON t.TagID = et.TagID
+		WHERE et.EventID IN (`+placeholders(len(eventIDs))+`)
+		ORDER BY t.Name
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var eventID int64
+		var tag string
+		if err := rows.Scan(&eventID, &tag); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags[eventID] = append(tags[eventID], tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// attachTags заполняет теги событий одним запросом
+func (r *Storage) attachTags(events []storage.Event) error {
+	ids := make([]int64, len(events))
+	for i, e := range events {
+		ids[i] = e.EventID
+	}
+
+	tags, err := eventTags(r.db, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range events {
+		events[i].Tags = tags[events[i].EventID]
+		if events[i].Tags == nil {
+			events[i].Tags = []string{}
+		}
+	}
+
+	return nil
+}
+
+// normalizeTags приводит теги к нижнему регистру, убирает "#" в начале, пустые значения и повторы
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#")))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+
+	return result
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
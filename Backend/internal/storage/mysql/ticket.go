@@ -0,0 +1,291 @@
+package mysql
+
+import (
+	"Backend/internal/storage"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// GetTicket возвращает билет пользователя на событие
+func (r *Storage) GetTicket(eventID int, userID int64) (storage.Ticket, error) {
+	const op = "mysql.GetTicket"
+
+	t := storage.Ticket{EventID: int64(eventID), UserID: userID}
+	err := r.db.QueryRow(
+		"SELECT Status, COALESCE(TicketNonce, ''), CheckedInAt FROM Registration WHERE EventID = ? AND UserID = ?",
+		eventID, userID,
+	).Scan(&t.Status, &t.Nonce, &t.CheckedInAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Ticket{}, fmt.Errorf("%s: %w", op, storage.ErrRegistrationNotFound)
+	}
+	if err != nil {
+		return storage.Ticket{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return t, nil
+}
+
+// CheckIn отмечает участника на входе по билету. Билет действителен, если nonce совпадает с регистрацией,
+// место подтверждено, а событие опубликовано и закончилось не раньше чем CheckInClosesAfter назад. При повторной отметке возвращается ErrAlreadyCheckedIn
+// вместе с данными первой отметки.
+func (r *Storage) CheckIn(eventID int, userID int64, nonce string, staffID int64) (storage.CheckIn, error) {
+	const op = "mysql.CheckIn"
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return storage.CheckIn{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	event, err := lockEvent(tx, eventID)
+	if err != nil {
+		return storage.CheckIn{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Событие получает статус completed сразу после окончания, а опоздавших отмечают еще некоторое время
+	if event.status != storage.EventPublished && event.status != storage.EventCompleted {
+		return storage.CheckIn{}, fmt.Errorf("%s: %w", op, storage.ErrEventNotOpen)
+	}
+	if time.Now().After(event.endsAt.Add(storage.CheckInClosesAfter)) {
+		return storage.CheckIn{}, fmt.Errorf("%s: %w", op, storage.ErrEventNotOpen)
+	}
+
+	var result storage.CheckIn
+	var ticketNonce string
+	var checkedInAt sql.NullTime
+	a := &result.Attendee
+	err = tx.QueryRow(`
+		SELECT u.UserID, u.Email, u.FirstName, u.LastName, r.Status, r.RegisteredAt, COALESCE(r.TicketNonce, ''), r.CheckedInAt
+		FROM Registration r
+		JOIN User u ON u.UserID = r.UserID
+		WHERE r.EventID = ? AND r.UserID = ?
+		FOR UPDATE
+	`, eventID, userID).Scan(&a.UserID, &a.Email, &a.FirstName, &a.LastName, &a.Status, &a.RegisteredAt, &ticketNonce, &checkedInAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.CheckIn{}, fmt.Errorf("%s: %w", op, storage.ErrTicketNotValid)
+	}
+	if err != nil {
+		return storage.CheckIn{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if ticketNonce == "" || ticketNonce != nonce || a.Status != storage.RegistrationConfirmed {
+		return storage.CheckIn{}, fmt.Errorf("%s: %w", op, storage.ErrTicketNotValid)
+	}
+
+	if checkedInAt.Valid {
+		result.CheckedInAt = checkedInAt.Time
+		a.CheckedInAt = &result.CheckedInAt
+		result.Stats, err = checkInStats(tx, eventID)
+		if err != nil {
+			return storage.CheckIn{}, fmt.Errorf("%s: %w", op, err)
+		}
+		return result, fmt.Errorf("%s: %w", op, storage.ErrAlreadyCheckedIn)
+	}
+
+	result.CheckedInAt = time.Now().UTC()
+	_, err = tx.Exec(
+		"UPDATE Registration SET CheckedInAt = ?, CheckedInByUserID = ? WHERE EventID = ? AND UserID = ?",
+		result.CheckedInAt, staffID, eventID, userID,
+	)
+	if err != nil {
+		return storage.CheckIn{}, fmt.Errorf("%s: failed to check in: %w", op, err)
+	}
+	a.CheckedInAt = &result.CheckedInAt
+
+	result.Stats, err = checkInStats(tx, eventID)
+	if err != nil {
+		return storage.CheckIn{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.CheckIn{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+// GetCheckInStats возвращает число подтвержденных участников события и отмеченных на входе
+func (r *Storage) GetCheckInStats(eventID int) (storage.CheckInStats, error) {
+	const op = "mysql.GetCheckInStats"
+
+	stats, err := checkInStats(r.db, eventID)
+	if err != nil {
+		return storage.CheckInStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+func checkInStats(q queryer, eventID int) (storage.CheckInStats, error) {
+	var stats storage.CheckInStats
+	err := q.QueryRow(`
+		SELECT COUNT(*), COUNT(CheckedInAt)
+		FROM Registration
+		WHERE EventID = ? AND Status = ?
+	`, eventID, storage.RegistrationConfirmed).Scan(&stats.Confirmed, &stats.CheckedIn)
+	if err != nil {
+		return storage.CheckInStats{}, fmt.Errorf("failed to count check-ins: %w", err)
+	}
+
+	return stats, nil
+}
+
+// GetCheckInSnapshot возвращает действующие билеты события для выгрузки на устройства сотрудников
+func (r *Storage) GetCheckInSnapshot(eventID int) ([]storage.SnapshotTicket, error) {
+	const op = "mysql.GetCheckInSnapshot"
+
+	rows, err := r.db.Query(`
+		SELECT u.UserID, u.FirstName, u.LastName, r.TicketNonce, r.CheckedInAt
+		FROM Registration r
+		JOIN User u ON u.UserID = r.UserID
+		WHERE r.EventID = ? AND r.Status = ? AND r.TicketNonce IS NOT NULL
+		ORDER BY u.UserID
+	`, eventID, storage.RegistrationConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	tickets := []storage.SnapshotTicket{}
+	for rows.Next() {
+		var t storage.SnapshotTicket
+		if err := rows.Scan(&t.UserID, &t.FirstName, &t.LastName, &t.Nonce, &t.CheckedInAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		tickets = append(tickets, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tickets, nil
+}
+
+// SyncCheckIns сохраняет отметки, отсканированные устройством без сети. Результаты возвращаются
+// в порядке сканирований.
+//
+// Конфликт, когда билет отсканирован несколько раз, разрешается в пользу самого раннего сканирования,
+// а при одинаковом времени - устройства с меньшим идентификатором. Итог не зависит от порядка,
+// в котором устройства загружают отметки, и повторной загрузки той же пачки.
+func (r *Storage) SyncCheckIns(eventID int, staffID int64, deviceID string, scans []storage.OfflineScan) ([]storage.SyncResult, storage.CheckInStats, error) {
+	const op = "mysql.SyncCheckIns"
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, storage.CheckInStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	event, err := lockEvent(tx, eventID)
+	if err != nil {
+		return nil, storage.CheckInStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if event.status != storage.EventPublished && event.status != storage.EventCompleted {
+		return nil, storage.CheckInStats{}, fmt.Errorf("%s: %w", op, storage.ErrEventNotOpen)
+	}
+
+	// Сканирования одного билета обрабатываются от раннего к позднему, а билеты - по возрастанию
+	// участника, чтобы строки блокировались в одном порядке
+	order := make([]int, len(scans))
+	for i := range order {
+		order[i] = i
+		// DATETIME(6) хранит микросекунды, сравниваем с сохраненными отметками в той же точности
+		scans[i].ScannedAt = scans[i].ScannedAt.UTC().Truncate(time.Microsecond)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := scans[order[i]], scans[order[j]]
+		if a.UserID != b.UserID {
+			return a.UserID < b.UserID
+		}
+		return a.ScannedAt.Before(b.ScannedAt)
+	})
+
+	results := make([]storage.SyncResult, len(scans))
+	for _, i := range order {
+		scan := scans[i]
+		result := &results[i]
+		result.UserID = scan.UserID
+
+		var status, nonce, device string
+		var checkedInAt sql.NullTime
+		err := tx.QueryRow(`
+			SELECT Status, COALESCE(TicketNonce, ''), CheckedInAt, COALESCE(CheckedInDevice, '')
+			FROM Registration
+			WHERE EventID = ? AND UserID = ?
+			FOR UPDATE
+		`, eventID, scan.UserID).Scan(&status, &nonce, &checkedInAt, &device)
+		if errors.Is(err, sql.ErrNoRows) {
+			result.Status = storage.SyncInvalid
+			continue
+		}
+		if err != nil {
+			return nil, storage.CheckInStats{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if nonce == "" || nonce != scan.Nonce || status != storage.RegistrationConfirmed {
+			result.Status = storage.SyncInvalid
+			continue
+		}
+
+		if outcome := resolveScan(scan.ScannedAt, deviceID, checkedInAt, device); outcome != storage.SyncAccepted {
+			result.Status = outcome
+			result.CheckedInAt, result.DeviceID = &checkedInAt.Time, device
+			continue
+		}
+		if checkedInAt.Valid {
+			result.ReplacedAt, result.ReplacedDevice = &checkedInAt.Time, device
+		}
+
+		_, err = tx.Exec(
+			"UPDATE Registration SET CheckedInAt = ?, CheckedInByUserID = ?, CheckedInDevice = ? WHERE EventID = ? AND UserID = ?",
+			scan.ScannedAt, staffID, deviceID, eventID, scan.UserID,
+		)
+		if err != nil {
+			return nil, storage.CheckInStats{}, fmt.Errorf("%s: failed to check in: %w", op, err)
+		}
+
+		scannedAt := scan.ScannedAt
+		result.Status = storage.SyncAccepted
+		result.CheckedInAt, result.DeviceID = &scannedAt, deviceID
+	}
+
+	stats, err := checkInStats(tx, eventID)
+	if err != nil {
+		return nil, storage.CheckInStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, storage.CheckInStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return results, stats, nil
+}
+
+// resolveScan сопоставляет сканирование устройства deviceID с сохраненной отметкой билета checkedInAt,
+// сделанной устройством device. SyncAccepted означает, что сканирование нужно сохранить: отметки еще нет
+// или сканирование ее вытесняет. SyncAlreadySynced - это та же отметка, загруженная повторно,
+// SyncDuplicate - сохраненная отметка раньше.
+func resolveScan(scannedAt time.Time, deviceID string, checkedInAt sql.NullTime, device string) string {
+	switch {
+	case !checkedInAt.Valid:
+		return storage.SyncAccepted
+	case checkedInAt.Time.Equal(scannedAt) && device == deviceID:
+		return storage.SyncAlreadySynced
+	case !scanEarlier(scannedAt, deviceID, checkedInAt.Time, device):
+		return storage.SyncDuplicate
+	}
+	return storage.SyncAccepted
+}
+
+// scanEarlier сообщает, побеждает ли сканирование a сканирование b в конфликте отметок.
+// Онлайн-отметки хранятся без устройства и при одинаковом времени побеждают.
+func scanEarlier(aTime time.Time, aDevice string, bTime time.Time, bDevice string) bool {
+	if !aTime.Equal(bTime) {
+		return aTime.Before(bTime)
+	}
+	return aDevice < bDevice
+}
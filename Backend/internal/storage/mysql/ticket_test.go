@@ -0,0 +1,64 @@
+package mysql
+
+import (
+	"Backend/internal/storage"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestResolveScan(t *testing.T) {
+	at := time.Date(2025, time.January, 6, 19, 0, 0, 0, time.UTC)
+	stored := func(d time.Time) sql.NullTime { return sql.NullTime{Time: d, Valid: true} }
+
+	tests := []struct {
+		name        string
+		scannedAt   time.Time
+		deviceID    string
+		checkedInAt sql.NullTime
+		device      string
+		want        string
+	}{
+		{name: "first scan", scannedAt: at, deviceID: "gate-a", want: storage.SyncAccepted},
+		{name: "same scan uploaded again", scannedAt: at, deviceID: "gate-a", checkedInAt: stored(at), device: "gate-a", want: storage.SyncAlreadySynced},
+		{name: "earlier scan replaces", scannedAt: at, deviceID: "gate-a", checkedInAt: stored(at.Add(time.Minute)), device: "gate-b", want: storage.SyncAccepted},
+		{name: "later scan is a duplicate", scannedAt: at.Add(time.Minute), deviceID: "gate-a", checkedInAt: stored(at), device: "gate-b", want: storage.SyncDuplicate},
+		{name: "later scan of the same device is a duplicate", scannedAt: at.Add(time.Minute), deviceID: "gate-a", checkedInAt: stored(at), device: "gate-a", want: storage.SyncDuplicate},
+		{name: "tie goes to the smaller device", scannedAt: at, deviceID: "gate-a", checkedInAt: stored(at), device: "gate-b", want: storage.SyncAccepted},
+		{name: "tie lost to the smaller device", scannedAt: at, deviceID: "gate-b", checkedInAt: stored(at), device: "gate-a", want: storage.SyncDuplicate},
+		{name: "tie lost to an online check-in", scannedAt: at, deviceID: "gate-a", checkedInAt: stored(at), device: "", want: storage.SyncDuplicate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveScan(tt.scannedAt, tt.deviceID, tt.checkedInAt, tt.device); got != tt.want {
+				t.Errorf("resolveScan() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanEarlierIsStrictOrder(t *testing.T) {
+	at := time.Date(2025, time.January, 6, 19, 0, 0, 0, time.UTC)
+	scans := []struct {
+		at     time.Time
+		device string
+	}{
+		{at, ""}, {at, "gate-a"}, {at, "gate-b"}, {at.Add(time.Second), ""}, {at.Add(-time.Second), "gate-b"},
+	}
+
+	// Из двух разных сканирований побеждает ровно одно, иначе устройства разойдутся в итоге синхронизации
+	for _, a := range scans {
+		for _, b := range scans {
+			ab := scanEarlier(a.at, a.device, b.at, b.device)
+			ba := scanEarlier(b.at, b.device, a.at, a.device)
+			same := a.at.Equal(b.at) && a.device == b.device
+			if same && (ab || ba) {
+				t.Errorf("scanEarlier(%v %q) against itself = true", a.at, a.device)
+			}
+			if !same && ab == ba {
+				t.Errorf("scanEarlier(%v %q, %v %q) = %v both ways", a.at, a.device, b.at, b.device, ab)
+			}
+		}
+	}
+}
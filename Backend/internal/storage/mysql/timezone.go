@@ -0,0 +1,72 @@
+package mysql
+
+import (
+	"Backend/internal/storage"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// locations кэширует загруженные часовые пояса
+var locations sync.Map
+
+// eventLocation возвращает часовой пояс события. Неизвестное имя заменяется на UTC,
+// чтобы ошибочная запись не мешала читать событие.
+func eventLocation(name string) *time.Location {
+	if name == "" {
+		name = storage.DefaultTimeZone
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location)
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	locations.Store(name, loc)
+
+	return loc
+}
+
+// localStart возвращает время начала по часам места проведения. Результат помечен как UTC,
+// чтобы драйвер записал в DATETIME именно эти часы.
+func localStart(date time.Time, timeZone string) time.Time {
+	t := date.In(eventLocation(timeZone))
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+// refreshLocalStart пересчитывает LocalStart после изменения даты или часового пояса события
+func refreshLocalStart(q queryer, eventID int) error {
+	var date time.Time
+	var timeZone string
+	if err := q.QueryRow("SELECT EventDate, TimeZone FROM Event WHERE EventID = ?", eventID).Scan(&date, &timeZone); err != nil {
+		return fmt.Errorf("failed to fetch event date: %w", err)
+	}
+
+	if _, err := q.Exec("UPDATE Event SET LocalStart = ? WHERE EventID = ?", localStart(date, timeZone), eventID); err != nil {
+		return fmt.Errorf("failed to update local start: %w", err)
+	}
+
+	return nil
+}
+
+// seriesLocation возвращает часовой пояс серии: именованный, если он сохранен, иначе фиксированное смещение
+func seriesLocation(timeZone string, offset int) *time.Location {
+	if timeZone != "" {
+		if loc, err := time.LoadLocation(timeZone); err == nil {
+			return loc
+		}
+	}
+	return time.FixedZone("", offset)
+}
+
+// zoneName возвращает имя часового пояса IANA или пустую строку для фиксированного смещения
+func zoneName(loc *time.Location) string {
+	switch name := loc.String(); name {
+	case "", "Local":
+		return ""
+	default:
+		return name
+	}
+}
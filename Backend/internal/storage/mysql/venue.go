@@ -0,0 +1,358 @@
+package mysql
+
+import (
+	"Backend/internal/lib/geo"
+	"Backend/internal/storage"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultEventDuration - продолжительность события без DurationMinutes при поиске пересечений на площадке
+const defaultEventDuration = 120 * time.Minute
+
+// maxVenueConflicts ограничивает число возвращаемых пересечений
+const maxVenueConflicts = 100
+
+// venueColumns - поля площадки для выборок, в которых Venue имеет псевдоним v
+const venueColumns = `v.VenueID, v.OwnerUserID, v.Name, v.Address, v.Latitude, v.Longitude, v.Capacity,
+                   COALESCE(v.AccessibilityNotes, ''), v.CreatedAt`
+
+func scanVenue(row rowScanner) (storage.Venue, error) {
+	var v storage.Venue
+	var lat, lng sql.NullFloat64
+
+	err := row.Scan(&v.ID, &v.OwnerUserID, &v.Name, &v.Address, &lat, &lng, &v.Capacity, &v.AccessibilityNotes, &v.CreatedAt)
+	if err != nil {
+		return storage.Venue{}, err
+	}
+
+	if lat.Valid && lng.Valid {
+		v.Location = &geo.Point{Lat: lat.Float64, Lng: lng.Float64}
+	}
+	v.Photos = []string{}
+
+	return v, nil
+}
+
+// AddVenue создает площадку вместе с фотографиями
+func (r *Storage) AddVenue(dto storage.VenueDto) (int64, error) {
+	const op = "mysql.AddVenue"
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	lat, lng := coordinates(dto.Location)
+
+	result, err := tx.Exec(`
+		INSERT INTO Venue (OwnerUserID, Name, Address, Latitude, Longitude, Capacity, AccessibilityNotes)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, dto.OwnerUserID, dto.Name, dto.Address, lat, lng, dto.Capacity, dto.AccessibilityNotes)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := setVenuePhotos(tx, id, dto.Photos); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// GetVenue возвращает площадку с фотографиями
+func (r *Storage) GetVenue(venueID int64) (storage.Venue, error) {
+	const op = "mysql.GetVenue"
+
+	venue, err := scanVenue(r.db.QueryRow(`SELECT `+venueColumns+` FROM Venue v WHERE v.VenueID = ?`, venueID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.Venue{}, fmt.Errorf("%s: %w", op, storage.ErrVenueNotFound)
+		}
+		return storage.Venue{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	venues := []storage.Venue{venue}
+	if err := attachVenuePhotos(r.db, venues); err != nil {
+		return storage.Venue{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return venues[0], nil
+}
+
+// GetVenues возвращает площадки, подходящие под фильтр, в порядке названия
+func (r *Storage) GetVenues(filter storage.VenueFilter) ([]storage.Venue, error) {
+	const op = "mysql.GetVenues"
+
+	where := []string{"TRUE"}
+	var args []any
+
+	if filter.Search != "" {
+		where = append(where, "(v.Name LIKE ? OR v.Address LIKE ?)")
+		args = append(args, "%"+filter.Search+"%", "%"+filter.Search+"%")
+	}
+
+	if filter.OwnerUserID != 0 {
+		where = append(where, "v.OwnerUserID = ?")
+		args = append(args, filter.OwnerUserID)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > storage.MaxPageLimit {
+		limit = storage.MaxPageLimit
+	}
+	args = append(args, limit)
+
+	rows, err := r.db.Query(`
+		SELECT `+venueColumns+`
+		FROM Venue v
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY v.Name, v.VenueID
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	venues := []storage.Venue{}
+	for rows.Next() {
+		venue, err := scanVenue(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		venues = append(venues, venue)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := attachVenuePhotos(r.db, venues); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return venues, nil
+}
+
+// EditVenue обновляет площадку и заменяет ее фотографии. Новые адрес и координаты переносятся
+// в предстоящие события площадки, вместимость событий не меняется.
+func (r *Storage) EditVenue(venueID int64, dto storage.VenueDto) error {
+	const op = "mysql.EditVenue"
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	lat, lng := coordinates(dto.Location)
+
+	result, err := tx.Exec(`
+		UPDATE Venue
+		SET Name = ?, Address = ?, Latitude = ?, Longitude = ?, Capacity = ?, AccessibilityNotes = ?
+		WHERE VenueID = ?
+	`, dto.Name, dto.Address, lat, lng, dto.Capacity, dto.AccessibilityNotes, venueID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Без изменений MySQL возвращает 0 затронутых строк, поэтому существование проверяется отдельно
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM Venue WHERE VenueID = ?)", venueID).Scan(&exists); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if !exists {
+			return fmt.Errorf("%s: %w", op, storage.ErrVenueNotFound)
+		}
+	}
+
+	if err := setVenuePhotos(tx, venueID, dto.Photos); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE Event SET EventAddress = ?, Latitude = ?, Longitude = ?
+		WHERE VenueID = ? AND Status IN (?, ?)
+	`, dto.Address, lat, lng, venueID, storage.EventDraft, storage.EventPublished)
+	if err != nil {
+		return fmt.Errorf("%s: failed to update venue events: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteVenue удаляет площадку. События площадки сохраняют скопированные из нее адрес и координаты.
+func (r *Storage) DeleteVenue(venueID int64) error {
+	const op = "mysql.DeleteVenue"
+
+	result, err := r.db.Exec("DELETE FROM Venue WHERE VenueID = ?", venueID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrVenueNotFound)
+	}
+
+	return nil
+}
+
+// GetVenueConflicts возвращает события, которые пересекаются по времени с событием на той же площадке.
+// Для повторения серии проверяются также все следующие повторения. Учитываются опубликованные
+// события и черновики того же автора, отмененные и завершенные не мешают.
+func (r *Storage) GetVenueConflicts(eventID int64) ([]storage.VenueConflict, error) {
+	const op = "mysql.GetVenueConflicts"
+
+	minutes := int(defaultEventDuration / time.Minute)
+
+	rows, err := r.db.Query(`
+		SELECT a.EventID, b.EventID, b.Title, b.EventDate, b.DurationMinutes, b.TimeZone
+		FROM Event a
+		JOIN Event b ON b.VenueID = a.VenueID AND b.EventID <> a.EventID
+		JOIN Event t ON t.EventID = ?
+		WHERE (a.EventID = t.EventID OR (a.SeriesID = t.SeriesID AND a.EventDate >= t.EventDate))
+		  AND a.Status IN (?, ?)
+		  AND (b.Status = ? OR (b.Status = ? AND b.CreatorUserID = a.CreatorUserID))
+		  AND b.EventDate < DATE_ADD(a.EventDate, INTERVAL COALESCE(a.DurationMinutes, ?) MINUTE)
+		  AND a.EventDate < DATE_ADD(b.EventDate, INTERVAL COALESCE(b.DurationMinutes, ?) MINUTE)
+		ORDER BY a.EventDate, b.EventDate, b.EventID
+		LIMIT ?
+	`, eventID,
+		storage.EventDraft, storage.EventPublished,
+		storage.EventPublished, storage.EventDraft,
+		minutes, minutes, maxVenueConflicts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var conflicts []storage.VenueConflict
+	for rows.Next() {
+		var c storage.VenueConflict
+		var duration sql.NullInt64
+		var timeZone string
+		if err := rows.Scan(&c.EventID, &c.ConflictingEventID, &c.Title, &c.Start, &duration, &timeZone); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		c.Start = c.Start.In(eventLocation(timeZone))
+		length := defaultEventDuration
+		if duration.Valid {
+			length = time.Duration(duration.Int64) * time.Minute
+		}
+		c.End = c.Start.Add(length)
+
+		conflicts = append(conflicts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return conflicts, nil
+}
+
+// applyVenue подставляет в событие адрес и координаты площадки, а также ее вместимость,
+// если своя вместимость события не указана
+func applyVenue(q queryer, dto *storage.EventCreateDto) error {
+	if dto.VenueID == nil {
+		return nil
+	}
+
+	var lat, lng sql.NullFloat64
+	var capacity sql.NullInt64
+	err := q.QueryRow(
+		"SELECT Address, Latitude, Longitude, Capacity FROM Venue WHERE VenueID = ?", *dto.VenueID,
+	).Scan(&dto.EventAddress, &lat, &lng, &capacity)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%d: %w", *dto.VenueID, storage.ErrVenueNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch venue: %w", err)
+	}
+
+	dto.Location = nil
+	if lat.Valid && lng.Valid {
+		dto.Location = &geo.Point{Lat: lat.Float64, Lng: lng.Float64}
+	}
+
+	if dto.Capacity == nil && capacity.Valid {
+		seats := int(capacity.Int64)
+		dto.Capacity = &seats
+	}
+
+	return nil
+}
+
+// setVenuePhotos заменяет фотографии площадки, сохраняя их порядок
+func setVenuePhotos(tx *sql.Tx, venueID int64, photos []string) error {
+	if _, err := tx.Exec("DELETE FROM VenuePhoto WHERE VenueID = ?", venueID); err != nil {
+		return fmt.Errorf("failed to delete photos: %w", err)
+	}
+
+	for i, url := range photos {
+		_, err := tx.Exec("INSERT INTO VenuePhoto (VenueID, URL, Position) VALUES (?, ?, ?)", venueID, url, i)
+		if err != nil {
+			return fmt.Errorf("failed to add photo: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// attachVenuePhotos загружает фотографии площадок одним запросом
+func attachVenuePhotos(q queryer, venues []storage.Venue) error {
+	if len(venues) == 0 {
+		return nil
+	}
+
+	index := make(map[int64]int, len(venues))
+	args := make([]any, len(venues))
+	for i, v := range venues {
+		index[v.ID] = i
+		args[i] = v.ID
+	}
+
+	rows, err := q.Query(`
+		SELECT VenueID, URL FROM VenuePhoto
+		WHERE VenueID IN (`+placeholders(len(venues))+`)
+		ORDER BY VenueID, Position
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query photos: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var venueID int64
+		var url string
+		if err := rows.Scan(&venueID, &url); err != nil {
+			return fmt.Errorf("failed to scan photo: %w", err)
+		}
+		i := index[venueID]
+		venues[i].Photos = append(venues[i].Photos, url)
+	}
+
+	return rows.Err()
+}
@@ -1,18 +1,302 @@
 package storage
 
-import "time"
+import (
+	"Backend/internal/lib/geo"
+	"errors"
+	"slices"
+	"time"
+)
+
+var (
+	ErrEventNotFound        = errors.New("event not found")
+	ErrAlreadyRegistered    = errors.New("user already registered")
+	ErrRegistrationNotFound = errors.New("registration not found")
+	ErrInvalidTransition    = errors.New("invalid event status transition")
+	ErrEventNotOpen         = errors.New("event is not open for registration")
+	ErrEventClosed          = errors.New("event is cancelled or completed")
+	ErrNotRecurring         = errors.New("event is not part of a series")
+	ErrInvalidScope         = errors.New("invalid edit scope")
+	ErrNoOccurrences        = errors.New("recurrence rule has no occurrences")
+	ErrCategoryNotFound     = errors.New("category not found")
+	ErrInvalidCursor        = errors.New("invalid page cursor")
+	ErrVenueNotFound        = errors.New("venue not found")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrStaffNotFound        = errors.New("staff member not found")
+	ErrAlreadyOwner         = errors.New("user already owns the event")
+	ErrInvitationNotFound   = errors.New("invitation not found")
+	ErrQuestionNotFound     = errors.New("question not found")
+	ErrApplicationNotFound  = errors.New("application not found")
+	ErrTicketNotValid       = errors.New("ticket is not valid for the event")
+	ErrAlreadyCheckedIn     = errors.New("attendee already checked in")
+)
+
+// Статусы жизненного цикла события: draft -> published -> cancelled/completed
+const (
+	EventDraft     = "draft"
+	EventPublished = "published"
+	EventCancelled = "cancelled"
+	EventCompleted = "completed"
+)
+
+// Области изменения повторяющегося события
+const (
+	ScopeThis      = "this"
+	ScopeFollowing = "following"
+	ScopeAll       = "all"
+)
+
+// DefaultTimeZone - часовой пояс событий, для которых он не указан
+const DefaultTimeZone = "Europe/Moscow"
+
+// Сортировки списков событий
+const (
+	SortDate       = "date"
+	SortPopularity = "popularity"
+	SortCreated    = "created"
+	// SortRelevance упорядочивает результаты полнотекстового поиска в порядке EventFilter.IDs
+	SortRelevance = "relevance"
+	// SortDistance упорядочивает события по удалению от EventFilter.Near
+	SortDistance = "distance"
+)
+
+// Направления сортировки
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// DefaultOrder возвращает направление сортировки по умолчанию: ближайшие события и лучшие
+// результаты поиска первыми, популярные и новые - тоже первыми
+func DefaultOrder(sort string) string {
+	switch sort {
+	case SortPopularity, SortCreated:
+		return OrderDesc
+	default:
+		return OrderAsc
+	}
+}
+
+// Размер страницы списков событий
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Видимость события
+const (
+	// VisibilityPublic - событие есть в общем списке и поиске
+	VisibilityPublic = "public"
+	// VisibilityUnlisted - событие доступно каждому, у кого есть ссылка, но не попадает в списки
+	VisibilityUnlisted = "unlisted"
+	// VisibilityInviteOnly - событие видят и могут записаться только приглашенные
+	VisibilityInviteOnly = "invite-only"
+)
+
+// Статусы приглашения на событие
+const (
+	InvitationInvited  = "invited"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+)
+
+// Роли команды события помимо создателя
+const (
+	// StaffCoOrganizer изменяет событие, видит участников и отмечает их на входе
+	StaffCoOrganizer = "co-organizer"
+	// StaffCheckIn видит участников и отмечает их на входе
+	StaffCheckIn = "checkin"
+	// StaffOwner - роль создателя события в списке организаторов
+	StaffOwner = "organizer"
+)
+
+// Статусы участника команды события: права роли действуют только после принятия приглашения
+const (
+	StaffInvited  = "invited"
+	StaffAccepted = "accepted"
+)
+
+// Типы вопросов анкеты регистрации
+const (
+	QuestionText     = "text"
+	QuestionSingle   = "single"
+	QuestionMulti    = "multi"
+	QuestionCheckbox = "checkbox"
+)
+
+// Статусы регистрации на событие
+const (
+	RegistrationConfirmed  = "confirmed"
+	RegistrationWaitlisted = "waitlisted"
+	// RegistrationPending - заявка на событие с одобрением ждет решения организатора
+	RegistrationPending = "pending"
+	// RegistrationRejected - заявка отклонена организатором
+	RegistrationRejected = "rejected"
+)
 
 type Event struct {
-	EventID       int64     `json:"eventId"`
-	Title         string    `json:"title"`
-	Description   string    `json:"description"`
-	EventDate     time.Time `json:"eventDate"`
-	EventAddress  string    `json:"eventAddress"`
-	CreatorUserID int64     `json:"creatorUserId"`
-	VKLink        string    `json:"vkLink"`
-	TGLink        string    `json:"tgLink"`
-	ImageURL      string    `json:"imageUrl"`
-	UsersCount    int       `json:"usersCount"`
+	EventID            int64     `json:"eventId"`
+	Title              string    `json:"title"`
+	Description        string    `json:"description"`
+	EventDate          time.Time `json:"eventDate"`
+	EventAddress       string    `json:"eventAddress"`
+	CreatorUserID      int64     `json:"creatorUserId"`
+	VKLink             string    `json:"vkLink"`
+	TGLink             string    `json:"tgLink"`
+	ImageURL           string    `json:"imageUrl"`
+	UsersCount         int       `json:"usersCount"`
+	Capacity           *int      `json:"capacity"` // nil - без ограничения мест
+	WaitlistCount      int       `json:"waitlistCount"`
+	RegistrationStatus string    `json:"registrationStatus,omitempty"` // только в списке записей пользователя
+	Status             string    `json:"status"`
+	CancelReason       string    `json:"cancelReason,omitempty"`
+	SeriesID           *int64    `json:"seriesId,omitempty"`
+	Recurrence         string    `json:"recurrence,omitempty"` // правило серии, только на странице события
+	Category           *Category `json:"category,omitempty"`
+	Tags               []string  `json:"tags"`
+	CreatedAt          time.Time `json:"createdAt"`
+	// TimeZone - часовой пояс IANA, в котором проходит событие. EventDate возвращается в нем же.
+	TimeZone string `json:"timeZone"`
+	// Location - координаты места проведения, nil если адрес не удалось найти
+	Location *geo.Point `json:"location,omitempty"`
+	// DistanceKm - расстояние до точки поиска, только в поиске рядом
+	DistanceKm *float64 `json:"distanceKm,omitempty"`
+	// DurationMinutes - продолжительность события, nil если не указана
+	DurationMinutes *int      `json:"durationMinutes,omitempty"`
+	Venue           *VenueRef `json:"venue,omitempty"`
+	// Organizers - создатель и команда события, только на странице события
+	Organizers []Organizer `json:"organizers,omitempty"`
+	Visibility string      `json:"visibility"`
+	// InviteVersion - текущая версия ссылок-приглашений, ссылки прежних версий недействительны
+	InviteVersion int `json:"-"`
+	// Questions - анкета регистрации, только на странице события
+	Questions []Question `json:"questions,omitempty"`
+	// RequiresApproval - запись на событие создает заявку, которую одобряет организатор
+	RequiresApproval bool `json:"requiresApproval"`
+}
+
+// Question - вопрос анкеты регистрации на событие
+type Question struct {
+	// ID не указывается для нового вопроса, при изменении анкеты вопрос с ID сохраняет ответы на него
+	ID       int64  `json:"id,omitempty"`
+	Type     string `json:"type" validate:"required,oneof=text single multi checkbox"`
+	Label    string `json:"label" validate:"required,max=255"`
+	Required bool   `json:"required"`
+	// Options - варианты ответа для single и multi
+	Options []string `json:"options,omitempty" validate:"max=50,dive,required,max=255"`
+	// MaxLength и Pattern (регулярное выражение RE2) ограничивают ответ на вопрос text
+	MaxLength *int   `json:"maxLength,omitempty" validate:"omitempty,min=1,max=5000"`
+	Pattern   string `json:"pattern,omitempty" validate:"max=255"`
+}
+
+// Answer - ответ участника на вопрос анкеты
+type Answer struct {
+	QuestionID int64 `json:"questionId" validate:"required"`
+	// Value - текст, выбранный вариант или true/false для отметки
+	Value string `json:"value,omitempty"`
+	// Values - выбранные варианты для multi
+	Values []string `json:"values,omitempty"`
+}
+
+// Organizer - участник команды события
+type Organizer struct {
+	UserID    int64  `json:"userId"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	ImageUrl  string `json:"imageUrl"`
+	// Role - StaffOwner для создателя, иначе роль в команде
+	Role string `json:"role"`
+	// Status - StaffInvited, пока пользователь не принял приглашение в команду, иначе StaffAccepted
+	Status string `json:"status"`
+}
+
+// Invitation - приглашение на событие по email
+type Invitation struct {
+	Email string `json:"email"`
+	// UserID заполнен, если у приглашенного есть учетная запись
+	UserID      *int64     `json:"userId,omitempty"`
+	Status      string     `json:"status"`
+	InvitedAt   time.Time  `json:"invitedAt"`
+	RespondedAt *time.Time `json:"respondedAt,omitempty"`
+}
+
+// Attendee - зарегистрированный на событие пользователь в списке для команды события
+type Attendee struct {
+	UserID       int64     `json:"userId"`
+	Email        string    `json:"email"`
+	FirstName    string    `json:"firstName"`
+	LastName     string    `json:"lastName"`
+	Status       string    `json:"status"`
+	RegisteredAt time.Time `json:"registeredAt"`
+	// Answers - ответы на анкету регистрации в порядке вопросов
+	Answers []Answer `json:"answers"`
+	// CheckedInAt - время отметки на входе, nil если участник еще не пришел
+	CheckedInAt *time.Time `json:"checkedInAt,omitempty"`
+}
+
+// Ticket - билет участника на событие
+type Ticket struct {
+	EventID int64 `json:"eventId"`
+	UserID  int64 `json:"userId"`
+	// Status - статус регистрации, билет действителен только для confirmed
+	Status      string     `json:"status"`
+	Nonce       string     `json:"-"`
+	CheckedInAt *time.Time `json:"checkedInAt,omitempty"`
+}
+
+// CheckInStats - счетчики отметок на входе события
+type CheckInStats struct {
+	Confirmed int `json:"confirmed"`
+	CheckedIn int `json:"checkedIn"`
+}
+
+// CheckInClosesAfter - сколько после окончания события еще принимаются отметки на входе
+const CheckInClosesAfter = 2 * time.Hour
+
+// CheckIn - результат отметки участника на входе
+type CheckIn struct {
+	Attendee    Attendee     `json:"attendee"`
+	CheckedInAt time.Time    `json:"checkedInAt"`
+	Stats       CheckInStats `json:"stats"`
+}
+
+// Статусы отметок, загруженных с устройства после сканирования без сети
+const (
+	// SyncAccepted - отметка сохранена, в том числе заменив более позднюю отметку того же билета
+	SyncAccepted = "accepted"
+	// SyncAlreadySynced - эта же отметка уже загружена, например при повторной отправке
+	SyncAlreadySynced = "synced"
+	// SyncDuplicate - билет уже отмечен раньше другим сканированием
+	SyncDuplicate = "duplicate"
+	// SyncInvalid - билет недействителен или регистрация не подтверждена
+	SyncInvalid = "invalid"
+)
+
+// SnapshotTicket - действующий билет в выгрузке для отметки без сети
+type SnapshotTicket struct {
+	UserID      int64      `json:"userId"`
+	FirstName   string     `json:"firstName"`
+	LastName    string     `json:"lastName"`
+	Nonce       string     `json:"-"`
+	CheckedInAt *time.Time `json:"checkedInAt,omitempty"`
+}
+
+// OfflineScan - сканирование билета на устройстве без сети
+type OfflineScan struct {
+	UserID    int64
+	Nonce     string
+	ScannedAt time.Time
+}
+
+// SyncResult - итог загрузки одного сканирования
+type SyncResult struct {
+	UserID int64  `json:"userId,omitempty"`
+	Status string `json:"status"`
+	// CheckedInAt и DeviceID - отметка билета, действующая после загрузки
+	CheckedInAt *time.Time `json:"checkedInAt,omitempty"`
+	DeviceID    string     `json:"deviceId,omitempty"`
+	// ReplacedAt и ReplacedDevice - более поздняя отметка, которую заменило это сканирование
+	ReplacedAt     *time.Time `json:"replacedAt,omitempty"`
+	ReplacedDevice string     `json:"replacedDevice,omitempty"`
 }
 
 // EventCreateDto представляет собой DTO для создания события
@@ -25,22 +309,130 @@ type EventCreateDto struct {
 	VKLink        string    `json:"vkLink"`
 	TGLink        string    `json:"tgLink"`
 	ImageURL      string    `json:"imageUrl"`
+	Capacity      *int      `json:"capacity" validate:"omitempty,min=1"`
+	// Status учитывается только при создании: событие можно создать черновиком, по умолчанию оно опубликовано
+	Status string `json:"status" validate:"omitempty,oneof=draft published"`
+	// Recurrence превращает событие в серию повторений, первое из которых приходится на EventDate
+	Recurrence *RecurrenceDto `json:"recurrence,omitempty"`
+	// Category - slug категории из справочника
+	Category string   `json:"category" validate:"omitempty,max=64"`
+	Tags     []string `json:"tags" validate:"max=10,dive,max=32"`
+	// TimeZone - часовой пояс IANA. При создании по умолчанию DefaultTimeZone, при изменении пустое значение сохраняет прежний.
+	TimeZone string `json:"timeZone" validate:"omitempty,timezone"`
+	// Location задает координаты явно, иначе они определяются по адресу
+	Location *geo.Point `json:"location,omitempty"`
+	// VenueID привязывает событие к площадке: адрес и координаты берутся из нее,
+	// а если Capacity не указана, событие получает вместимость площадки
+	VenueID         *int64 `json:"venueId,omitempty"`
+	DurationMinutes *int   `json:"durationMinutes,omitempty" validate:"omitempty,min=1,max=10080"`
+	// Visibility при создании по умолчанию VisibilityPublic, при изменении пустое значение сохраняет прежнюю
+	Visibility string `json:"visibility" validate:"omitempty,oneof=public unlisted invite-only"`
+	// RequiresApproval при изменении сохраняет прежнее значение, если не указан
+	RequiresApproval *bool `json:"requiresApproval,omitempty"`
+	// Clear перечисляет поля, которые нужно сбросить при изменении. Остальные неуказанные поля сохраняют
+	// прежние значения, а теги сохраняются, если Tags не передан (пустой список удаляет их).
+	Clear []string `json:"clear,omitempty" validate:"max=5,dive,oneof=capacity category venue location durationMinutes"`
+}
+
+// Поля события, которые можно сбросить через EventCreateDto.Clear
+const (
+	FieldCapacity        = "capacity"
+	FieldCategory        = "category"
+	FieldVenue           = "venue"
+	FieldLocation        = "location"
+	FieldDurationMinutes = "durationMinutes"
+)
+
+// Clears сообщает, нужно ли сбросить поле при изменении события
+func (d EventCreateDto) Clears(field string) bool {
+	return slices.Contains(d.Clear, field)
+}
+
+// RecurrenceDto - правило повторения серии
+type RecurrenceDto struct {
+	// RRule - подмножество RRULE из RFC 5545, например "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10"
+	RRule string `json:"rrule" validate:"required,max=255"`
+	// Exceptions - даты повторений, которые нужно пропустить
+	Exceptions []time.Time `json:"exceptions"`
 }
 
 type EventCardProps struct {
-	ID         int64  `json:"id"`
-	Name       string `json:"name"`
-	Date       string `json:"date"`
-	Address    string `json:"address"`
-	UsersCount int    `json:"usersCount"`
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// Date - время начала в RFC 3339 со смещением часового пояса события
+	Date       string    `json:"date"`
+	TimeZone   string    `json:"timeZone"`
+	Address    string    `json:"address"`
+	UsersCount int       `json:"usersCount"`
+	Capacity   *int      `json:"capacity"`
+	Status     string    `json:"status"`
+	Category   *Category `json:"category,omitempty"`
+	Tags       []string  `json:"tags"`
+	// Highlights - фрагменты с подсвеченными совпадениями поискового запроса по полям
+	Highlights map[string][]string `json:"highlights,omitempty"`
+	Location   *geo.Point          `json:"location,omitempty"`
+	DistanceKm *float64            `json:"distanceKm,omitempty"`
+	Venue      *VenueRef           `json:"venue,omitempty"`
+}
+
+// Venue - площадка, на которой проводятся события
+type Venue struct {
+	ID          int64      `json:"id"`
+	OwnerUserID int64      `json:"ownerUserId"`
+	Name        string     `json:"name"`
+	Address     string     `json:"address"`
+	Location    *geo.Point `json:"location,omitempty"`
+	// Capacity - вместимость по умолчанию для событий площадки, nil - без ограничения мест
+	Capacity           *int      `json:"capacity"`
+	AccessibilityNotes string    `json:"accessibilityNotes"`
+	Photos             []string  `json:"photos"`
+	CreatedAt          time.Time `json:"createdAt"`
+}
+
+// VenueRef - краткие сведения о площадке события
+type VenueRef struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// VenueDto - данные для создания и изменения площадки
+type VenueDto struct {
+	Name    string `json:"name" validate:"required,max=128"`
+	Address string `json:"address" validate:"required,max=255"`
+	// Location задает координаты явно, иначе они определяются по адресу
+	Location           *geo.Point `json:"location,omitempty"`
+	Capacity           *int       `json:"capacity" validate:"omitempty,min=1"`
+	AccessibilityNotes string     `json:"accessibilityNotes" validate:"max=2000"`
+	// Photos - ссылки на фотографии в нужном порядке. При изменении фотографии, которых нет
+	// в списке, удаляются, а загруженные вместе с запросом добавляются в конец.
+	Photos      []string `json:"photos" validate:"max=20"`
+	OwnerUserID int64    `json:"-"`
+}
+
+// VenueFilter - условия поиска площадок
+type VenueFilter struct {
+	// Search ищет по названию и адресу
+	Search string
+	// OwnerUserID оставляет площадки одного пользователя, 0 - всех
+	OwnerUserID int64
+	Limit       int
 }
 
-type CrateUserDto struct {
+// VenueConflict - пересечение события с другим событием на той же площадке
+type VenueConflict struct {
+	EventID            int64     `json:"eventId"`
+	ConflictingEventID int64     `json:"conflictingEventId"`
+	Title              string    `json:"title"`
+	Start              time.Time `json:"start"`
+	End                time.Time `json:"end"`
+}
+
+// SyncedUser - копия учетной записи из AuthService, которая является источником пользователей
+type SyncedUser struct {
+	ID        int64  `json:"id"`
 	Email     string `json:"email"`
 	FirstName string `json:"firstName"`
 	LastName  string `json:"lastName"`
-	Pasword   string `json:"password"`
-	ImageURL  string `json:"imageUrl"`
 }
 
 type UserInfo struct {
@@ -51,8 +443,160 @@ type UserInfo struct {
 	Id        int    `json:"id"`
 }
 
+// ProfileInfo содержит первые страницы событий пользователя, остальные запрашиваются отдельно по курсорам
 type ProfileInfo struct {
-	User             UserInfo `json:"userInfo"`
-	Events           []Event  `json:"events"`
-	RegisteredEvents []Event  `json:"registeredEvents"`
+	User                 UserInfo `json:"userInfo"`
+	Events               []Event  `json:"events"`
+	EventsPage           PageInfo `json:"eventsPage"`
+	RegisteredEvents     []Event  `json:"registeredEvents"`
+	RegisteredEventsPage PageInfo `json:"registeredEventsPage"`
+}
+
+// PageRequest - параметры постраничной выборки. Cursor пуст для первой страницы и
+// действителен только с теми же Sort и Order, с которыми был получен.
+type PageRequest struct {
+	Sort   string
+	Order  string
+	Limit  int
+	Cursor string
+}
+
+// PageInfo описывает полученную страницу
+type PageInfo struct {
+	// NextCursor пуст на последней странице
+	NextCursor string `json:"nextCursor,omitempty"`
+	// Total - число элементов во всей выборке без учета курсора
+	Total int `json:"total"`
+}
+
+// EventPage - страница списка событий
+type EventPage struct {
+	Events []Event
+	PageInfo
+}
+
+// RegistrationResult - результат записи на событие
+type RegistrationResult struct {
+	Status string
+	// Position - место в листе ожидания, начиная с 1
+	Position  int
+	UserEmail string
+	EventName string
+	EventDate time.Time
+	// TicketNonce входит в код билета, который участник получает при подтверждении места
+	TicketNonce string
+}
+
+// ApplicationDecision - решение организатора по заявке на событие
+type ApplicationDecision struct {
+	UserID int64 `json:"userId"`
+	// Status - confirmed или waitlisted для одобренной заявки, rejected для отклоненной
+	Status string `json:"status"`
+	// Position - место в листе ожидания, если одобренному участнику не хватило места
+	Position    int       `json:"waitlistPosition,omitempty"`
+	UserEmail   string    `json:"-"`
+	EventName   string    `json:"-"`
+	EventDate   time.Time `json:"-"`
+	TicketNonce string    `json:"-"`
+}
+
+// Promotion - пользователь, переведенный из листа ожидания на освободившееся место
+type Promotion struct {
+	EventID     int64
+	UserID      int64
+	UserEmail   string
+	EventName   string
+	EventDate   time.Time
+	TicketNonce string
+}
+
+// CancelResult - результат отмены регистрации
+type CancelResult struct {
+	// ReminderIDs - напоминания отмененной регистрации, которые нужно удалить в EmailSenderService
+	ReminderIDs []int64
+	Promoted    []Promotion
+}
+
+// Registrant - участник или пользователь из листа ожидания события
+type Registrant struct {
+	UserID    int64
+	UserEmail string
+}
+
+// EventCancellation - результат отмены события
+type EventCancellation struct {
+	EventName   string
+	EventDate   time.Time
+	Reason      string
+	Registrants []Registrant
+	// ReminderIDs - напоминания всех регистраций, которые нужно удалить в EmailSenderService
+	ReminderIDs []int64
+}
+
+// EditResult - результат изменения события или серии
+type EditResult struct {
+	Promoted []Promotion
+	// Cancelled - повторения, исключенные новым правилом серии, участников которых нужно уведомить
+	Cancelled []EventCancellation
+}
+
+// Category - категория событий из справочника
+type Category struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// EventFilter - условия поиска событий в общем списке
+type EventFilter struct {
+	Title   string
+	Address string
+	// From и To включительно ограничивают момент начала события
+	From *time.Time
+	To   *time.Time
+	// FromDate и ToDate (YYYY-MM-DD) включительно ограничивают дату начала по местному времени события
+	FromDate string
+	ToDate   string
+	// TimeFrom и TimeTo (HH:MM) ограничивают время начала по местному времени события.
+	// Если TimeFrom позже TimeTo, интервал проходит через полночь.
+	TimeFrom string
+	TimeTo   string
+	// Categories - slug категорий, событие подходит, если относится к любой из них
+	Categories []string
+	Tags       []string
+	// MatchAllTags требует наличия всех тегов вместо любого из них
+	MatchAllTags bool
+	// IDs ограничивает выборку результатами полнотекстового поиска. nil - без ограничения,
+	// пустой срез - ни одного события.
+	IDs []int64
+	// Near и RadiusKm оставляют события не дальше RadiusKm километров от точки
+	Near     *geo.Point
+	RadiusKm float64
+	// BBox оставляет события внутри прямоугольника карты
+	BBox *geo.BBox
+	// VenueID оставляет события одной площадки
+	VenueID *int64
+}
+
+// SearchDocument - данные события для поискового индекса
+type SearchDocument struct {
+	ID          int64
+	Title       string
+	Description string
+	Address     string
+	Tags        []string
+	UpdatedAt   time.Time
+}
+
+// FacetCount - количество событий с одним значением фасета
+type FacetCount struct {
+	Value string `json:"value"`
+	Name  string `json:"name,omitempty"`
+	Count int    `json:"count"`
+}
+
+// Facets - количество подходящих событий по категориям и тегам. Счетчики фасета
+// учитывают все условия фильтра, кроме условия по самому фасету.
+type Facets struct {
+	Categories []FacetCount `json:"categories"`
+	Tags       []FacetCount `json:"tags"`
 }
@@ -0,0 +1,25 @@
+-- Прежний идентификатор возвращается, если его еще не заняла учетная запись AuthService
+UPDATE `User` AS u
+LEFT JOIN `User` AS taken ON taken.`UserID` = u.`LegacyUserID`
+SET u.`UserID` = u.`LegacyUserID`
+WHERE u.`LegacyUserID` IS NOT NULL AND taken.`UserID` IS NULL;
+
+ALTER TABLE `User`
+    DROP COLUMN `LegacyUserID`;
+
+ALTER TABLE `Registration`
+    DROP FOREIGN KEY `fk_registration_user`,
+    ADD CONSTRAINT `Registration_ibfk_1` FOREIGN KEY (`UserID`) REFERENCES `User`(`UserID`) ON DELETE CASCADE;
+
+ALTER TABLE `Event`
+    DROP FOREIGN KEY `fk_event_creator`,
+    ADD CONSTRAINT `Event_ibfk_1` FOREIGN KEY (`CreatorUserID`) REFERENCES `User`(`UserID`) ON DELETE CASCADE;
+
+ALTER TABLE `User`
+    DROP COLUMN `Version`,
+    MODIFY `FirstName` VARCHAR(64) NOT NULL,
+    MODIFY `LastName` VARCHAR(64) NOT NULL;
+
+SET FOREIGN_KEY_CHECKS = 0;
+ALTER TABLE `User` MODIFY `UserID` INT NOT NULL AUTO_INCREMENT;
+SET FOREIGN_KEY_CHECKS = 1;
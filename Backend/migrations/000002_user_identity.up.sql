@@ -0,0 +1,31 @@
+-- Идентификатор пользователя выдает AuthService, поэтому автоинкремент больше не нужен
+SET FOREIGN_KEY_CHECKS = 0;
+ALTER TABLE `User` MODIFY `UserID` INT NOT NULL;
+SET FOREIGN_KEY_CHECKS = 1;
+
+ALTER TABLE `User`
+    MODIFY `FirstName` VARCHAR(64) NOT NULL DEFAULT '',
+    MODIFY `LastName` VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN `Version` BIGINT NOT NULL DEFAULT 0;
+
+-- При первой синхронизации старые записи получают идентификатор из AuthService,
+-- поэтому ссылки на пользователя должны обновляться каскадно
+ALTER TABLE `Event`
+    DROP FOREIGN KEY `Event_ibfk_1`,
+    ADD CONSTRAINT `fk_event_creator` FOREIGN KEY (`CreatorUserID`) REFERENCES `User`(`UserID`)
+        ON DELETE CASCADE ON UPDATE CASCADE;
+
+ALTER TABLE `Registration`
+    DROP FOREIGN KEY `Registration_ibfk_1`,
+    ADD CONSTRAINT `fk_registration_user` FOREIGN KEY (`UserID`) REFERENCES `User`(`UserID`)
+        ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- Записи, созданные до AuthService, получали идентификаторы из собственного автоинкремента, и они
+-- могут совпадать с идентификаторами других учетных записей AuthService. Такие записи временно
+-- получают отрицательный идентификатор, а событие user.linked присваивает им идентификатор
+-- AuthService по email без конфликта первичного ключа. LegacyUserID хранит прежний идентификатор.
+ALTER TABLE `User`
+    ADD COLUMN `LegacyUserID` INT NULL;
+
+UPDATE `User`
+SET `LegacyUserID` = `UserID`, `UserID` = -`UserID`;
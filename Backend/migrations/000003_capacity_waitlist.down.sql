@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS `Reminder`;
+
+ALTER TABLE `Registration`
+    DROP INDEX `idx_registration_queue`,
+    DROP COLUMN `RegisteredAt`,
+    DROP COLUMN `Status`;
+
+ALTER TABLE `Event` DROP COLUMN `Capacity`;
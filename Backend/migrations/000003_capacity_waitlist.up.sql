@@ -0,0 +1,18 @@
+-- NULL означает, что количество мест не ограничено
+ALTER TABLE `Event` ADD COLUMN `Capacity` INT NULL;
+
+-- Регистрации сверх вместимости попадают в лист ожидания и продвигаются в порядке записи
+ALTER TABLE `Registration`
+    ADD COLUMN `Status` VARCHAR(16) NOT NULL DEFAULT 'confirmed',
+    ADD COLUMN `RegisteredAt` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    ADD INDEX `idx_registration_queue` (`EventID`, `Status`, `RegisteredAt`);
+
+-- Идентификаторы напоминаний в EmailSenderService, чтобы удалить их при отмене регистрации
+CREATE TABLE `Reminder` (
+    `UserID` INT NOT NULL,
+    `EventID` INT NOT NULL,
+    `NotificationID` BIGINT NOT NULL,
+    PRIMARY KEY (`UserID`, `EventID`, `NotificationID`),
+    FOREIGN KEY (`UserID`, `EventID`) REFERENCES `Registration`(`UserID`, `EventID`)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
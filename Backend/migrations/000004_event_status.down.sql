@@ -0,0 +1,4 @@
+ALTER TABLE `Event`
+    DROP INDEX `idx_event_status_date`,
+    DROP COLUMN `CancelReason`,
+    DROP COLUMN `Status`;
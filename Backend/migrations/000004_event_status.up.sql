@@ -0,0 +1,7 @@
+-- Жизненный цикл события: draft -> published -> cancelled/completed.
+-- Существующие события уже были видны всем, поэтому считаются опубликованными. Новые события тоже
+-- публикуются сразу, черновик создается только по явному запросу.
+ALTER TABLE `Event`
+    ADD COLUMN `Status` VARCHAR(16) NOT NULL DEFAULT 'published',
+    ADD COLUMN `CancelReason` TEXT NULL,
+    ADD INDEX `idx_event_status_date` (`Status`, `EventDate`);
@@ -0,0 +1,7 @@
+ALTER TABLE `Event`
+    DROP FOREIGN KEY `fk_event_series`,
+    DROP INDEX `idx_event_series`,
+    DROP COLUMN `OccurrenceDate`,
+    DROP COLUMN `SeriesID`;
+
+DROP TABLE IF EXISTS `EventSeries`;
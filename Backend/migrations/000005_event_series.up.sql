@@ -0,0 +1,24 @@
+-- Серия повторяющихся событий. Повторения хранятся как обычные события со ссылкой на серию,
+-- поэтому регистрации и напоминания относятся к конкретному повторению.
+CREATE TABLE `EventSeries` (
+    `SeriesID` INT AUTO_INCREMENT PRIMARY KEY,
+    `CreatorUserID` INT NOT NULL,
+    -- Начало первого повторения и смещение его часового пояса, в котором вычисляется правило
+    `StartDate` DATETIME NOT NULL,
+    `UTCOffset` INT NOT NULL DEFAULT 0,
+    `RRule` VARCHAR(255) NOT NULL,
+    -- Исключенные даты в формате EXDATE через запятую
+    `ExDates` TEXT NULL,
+    -- Повторения бесконечного правила создаются заранее до этой даты
+    `MaterializedUntil` DATETIME NOT NULL,
+    CONSTRAINT `fk_series_creator` FOREIGN KEY (`CreatorUserID`) REFERENCES `User`(`UserID`)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+-- OccurrenceDate - дата повторения по правилу, она не меняется при переносе отдельного повторения
+ALTER TABLE `Event`
+    ADD COLUMN `SeriesID` INT NULL,
+    ADD COLUMN `OccurrenceDate` DATETIME NULL,
+    ADD CONSTRAINT `fk_event_series` FOREIGN KEY (`SeriesID`) REFERENCES `EventSeries`(`SeriesID`)
+        ON DELETE SET NULL,
+    ADD INDEX `idx_event_series` (`SeriesID`, `OccurrenceDate`);
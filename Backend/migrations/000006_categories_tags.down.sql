@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS `EventTag`;
+DROP TABLE IF EXISTS `Tag`;
+
+ALTER TABLE `Event`
+    DROP FOREIGN KEY `fk_event_category`,
+    DROP COLUMN `CategoryID`;
+
+DROP TABLE IF EXISTS `Category`;
@@ -0,0 +1,37 @@
+-- Справочник категорий. Slug используется в фильтрах и API, Name показывается пользователю.
+CREATE TABLE `Category` (
+    `CategoryID` INT AUTO_INCREMENT PRIMARY KEY,
+    `Slug` VARCHAR(64) NOT NULL UNIQUE,
+    `Name` VARCHAR(64) NOT NULL
+);
+
+INSERT INTO `Category` (`Slug`, `Name`) VALUES
+    ('music', 'Музыка'),
+    ('sport', 'Спорт'),
+    ('education', 'Образование'),
+    ('meetup', 'Встречи'),
+    ('exhibition', 'Выставки'),
+    ('theatre', 'Театр'),
+    ('games', 'Игры'),
+    ('volunteering', 'Волонтерство'),
+    ('other', 'Другое');
+
+ALTER TABLE `Event`
+    ADD COLUMN `CategoryID` INT NULL,
+    ADD CONSTRAINT `fk_event_category` FOREIGN KEY (`CategoryID`) REFERENCES `Category`(`CategoryID`)
+        ON DELETE SET NULL;
+
+-- Свободные теги хранятся в нижнем регистре, чтобы "Python" и "python" были одним тегом
+CREATE TABLE `Tag` (
+    `TagID` INT AUTO_INCREMENT PRIMARY KEY,
+    `Name` VARCHAR(32) NOT NULL UNIQUE
+);
+
+CREATE TABLE `EventTag` (
+    `EventID` INT NOT NULL,
+    `TagID` INT NOT NULL,
+    PRIMARY KEY (`EventID`, `TagID`),
+    INDEX `idx_event_tag_tag` (`TagID`),
+    FOREIGN KEY (`EventID`) REFERENCES `Event`(`EventID`) ON DELETE CASCADE,
+    FOREIGN KEY (`TagID`) REFERENCES `Tag`(`TagID`) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+ALTER TABLE `Event`
+    DROP INDEX `idx_event_updated`,
+    DROP COLUMN `UpdatedAt`;
@@ -0,0 +1,4 @@
+-- Время последнего изменения события нужно для инкрементальной синхронизации поискового индекса
+ALTER TABLE `Event`
+    ADD COLUMN `UpdatedAt` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+    ADD INDEX `idx_event_updated` (`UpdatedAt`, `EventID`);
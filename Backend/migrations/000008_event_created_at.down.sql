@@ -0,0 +1,4 @@
+ALTER TABLE `Event`
+    DROP INDEX `idx_event_date`,
+    DROP INDEX `idx_event_created`,
+    DROP COLUMN `CreatedAt`;
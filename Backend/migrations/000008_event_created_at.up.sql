@@ -0,0 +1,6 @@
+-- Время создания события для сортировки списков. Для существующих событий оно неизвестно,
+-- поэтому они получают время применения миграции и упорядочиваются между собой по EventID.
+ALTER TABLE `Event`
+    ADD COLUMN `CreatedAt` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    ADD INDEX `idx_event_created` (`CreatedAt`, `EventID`),
+    ADD INDEX `idx_event_date` (`EventDate`, `EventID`);
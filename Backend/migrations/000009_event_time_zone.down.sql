@@ -0,0 +1,6 @@
+ALTER TABLE `EventSeries` DROP COLUMN `TimeZone`;
+
+ALTER TABLE `Event`
+    DROP INDEX `idx_event_local_start`,
+    DROP COLUMN `LocalStart`,
+    DROP COLUMN `TimeZone`;
@@ -0,0 +1,17 @@
+-- Часовой пояс события в формате IANA. Сервис работает в России, поэтому существующие события
+-- считаются московскими.
+ALTER TABLE `Event`
+    ADD COLUMN `TimeZone` VARCHAR(64) NOT NULL DEFAULT 'Europe/Moscow',
+    ADD COLUMN `LocalStart` DATETIME NULL;
+
+-- LocalStart - время начала по часам места проведения для фильтров по дате и времени суток.
+-- Москва живет по UTC+3 без перехода на летнее время.
+UPDATE `Event` SET `LocalStart` = `EventDate` + INTERVAL 3 HOUR;
+
+ALTER TABLE `Event`
+    MODIFY COLUMN `LocalStart` DATETIME NOT NULL,
+    ADD INDEX `idx_event_local_start` (`LocalStart`);
+
+-- Серия с часовым поясом сохраняет время повторений при переходе на летнее время.
+-- NULL означает фиксированное смещение UTCOffset, как у серий, созданных раньше.
+ALTER TABLE `EventSeries` ADD COLUMN `TimeZone` VARCHAR(64) NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE `Event`
+    DROP INDEX `idx_event_location`,
+    DROP COLUMN `Longitude`,
+    DROP COLUMN `Latitude`,
+    MODIFY COLUMN `EventAddress` VARCHAR(64) NOT NULL;
@@ -0,0 +1,7 @@
+-- Координаты места проведения для поиска рядом и карты. Адрес расширяется, потому что
+-- полные адреса с городом и номером дома не помещались в 64 символа.
+ALTER TABLE `Event`
+    MODIFY COLUMN `EventAddress` VARCHAR(255) NOT NULL,
+    ADD COLUMN `Latitude` DOUBLE NULL,
+    ADD COLUMN `Longitude` DOUBLE NULL,
+    ADD INDEX `idx_event_location` (`Latitude`, `Longitude`);
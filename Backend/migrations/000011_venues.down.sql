@@ -0,0 +1,8 @@
+ALTER TABLE `Event`
+    DROP FOREIGN KEY `fk_event_venue`,
+    DROP INDEX `idx_event_venue_date`,
+    DROP COLUMN `DurationMinutes`,
+    DROP COLUMN `VenueID`;
+
+DROP TABLE `VenuePhoto`;
+DROP TABLE `Venue`;
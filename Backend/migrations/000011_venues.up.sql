@@ -0,0 +1,33 @@
+-- Площадки, которые организаторы используют повторно вместо ввода адреса для каждого события
+CREATE TABLE `Venue` (
+    `VenueID` INT AUTO_INCREMENT PRIMARY KEY,
+    `OwnerUserID` INT NOT NULL,
+    `Name` VARCHAR(128) NOT NULL,
+    `Address` VARCHAR(255) NOT NULL,
+    `Latitude` DOUBLE NULL,
+    `Longitude` DOUBLE NULL,
+    `Capacity` INT NULL,
+    `AccessibilityNotes` TEXT,
+    `CreatedAt` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX `idx_venue_name` (`Name`),
+    CONSTRAINT `fk_venue_owner` FOREIGN KEY (`OwnerUserID`) REFERENCES `User`(`UserID`)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE `VenuePhoto` (
+    `PhotoID` INT AUTO_INCREMENT PRIMARY KEY,
+    `VenueID` INT NOT NULL,
+    `URL` VARCHAR(255) NOT NULL,
+    `Position` INT NOT NULL,
+    INDEX `idx_venue_photo_venue` (`VenueID`, `Position`),
+    FOREIGN KEY (`VenueID`) REFERENCES `Venue`(`VenueID`) ON DELETE CASCADE
+);
+
+-- DurationMinutes нужна для поиска пересечений событий на одной площадке.
+-- Адрес и координаты площадки копируются в событие, поэтому после удаления площадки событие их сохраняет.
+ALTER TABLE `Event`
+    ADD COLUMN `VenueID` INT NULL,
+    ADD COLUMN `DurationMinutes` INT NULL,
+    ADD INDEX `idx_event_venue_date` (`VenueID`, `EventDate`),
+    ADD CONSTRAINT `fk_event_venue` FOREIGN KEY (`VenueID`) REFERENCES `Venue`(`VenueID`)
+        ON DELETE SET NULL;
@@ -0,0 +1,15 @@
+-- Команда события помимо создателя: соорганизаторы и сотрудники, которые отмечают участников на входе.
+-- Добавленный пользователь получает права только после того, как примет приглашение.
+CREATE TABLE `EventStaff` (
+    `EventID` INT NOT NULL,
+    `UserID` INT NOT NULL,
+    `Role` VARCHAR(16) NOT NULL,
+    `Status` VARCHAR(16) NOT NULL DEFAULT 'invited',
+    `AddedAt` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    `AcceptedAt` DATETIME(6) NULL,
+    PRIMARY KEY (`EventID`, `UserID`),
+    INDEX `idx_event_staff_user` (`UserID`),
+    FOREIGN KEY (`EventID`) REFERENCES `Event`(`EventID`) ON DELETE CASCADE,
+    CONSTRAINT `fk_event_staff_user` FOREIGN KEY (`UserID`) REFERENCES `User`(`UserID`)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
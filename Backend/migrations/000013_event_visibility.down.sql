@@ -0,0 +1,5 @@
+DROP TABLE `EventInvitation`;
+
+ALTER TABLE `Event`
+    DROP COLUMN `InviteVersion`,
+    DROP COLUMN `Visibility`;
@@ -0,0 +1,21 @@
+-- Видимость события: public попадает в общий список, unlisted доступно по ссылке,
+-- invite-only - только приглашенным. InviteVersion отзывает выданные ссылки-приглашения.
+ALTER TABLE `Event`
+    ADD COLUMN `Visibility` VARCHAR(16) NOT NULL DEFAULT 'public',
+    ADD COLUMN `InviteVersion` INT NOT NULL DEFAULT 0;
+
+-- Приглашения по email и ответы на них. Приглашение не требует учетной записи,
+-- пользователь сопоставляется по email при входе.
+CREATE TABLE `EventInvitation` (
+    `EventID` INT NOT NULL,
+    `Email` VARCHAR(255) NOT NULL,
+    `Status` VARCHAR(16) NOT NULL DEFAULT 'invited',
+    `InvitedByUserID` INT NULL,
+    `InvitedAt` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    `RespondedAt` DATETIME(6) NULL,
+    PRIMARY KEY (`EventID`, `Email`),
+    INDEX `idx_event_invitation_email` (`Email`),
+    FOREIGN KEY (`EventID`) REFERENCES `Event`(`EventID`) ON DELETE CASCADE,
+    CONSTRAINT `fk_invitation_invited_by` FOREIGN KEY (`InvitedByUserID`) REFERENCES `User`(`UserID`)
+        ON DELETE SET NULL ON UPDATE CASCADE
+);
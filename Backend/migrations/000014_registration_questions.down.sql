@@ -0,0 +1,2 @@
+DROP TABLE `RegistrationAnswer`;
+DROP TABLE `RegistrationQuestion`;
@@ -0,0 +1,27 @@
+-- Анкета регистрации на событие. Options хранит варианты ответа в JSON для вопросов с выбором.
+CREATE TABLE `RegistrationQuestion` (
+    `QuestionID` INT AUTO_INCREMENT PRIMARY KEY,
+    `EventID` INT NOT NULL,
+    `Position` INT NOT NULL,
+    `Type` VARCHAR(16) NOT NULL,
+    `Label` VARCHAR(255) NOT NULL,
+    `Required` BOOLEAN NOT NULL DEFAULT FALSE,
+    `Options` JSON NULL,
+    `MaxLength` INT NULL,
+    `Pattern` VARCHAR(255) NULL,
+    INDEX `idx_registration_question_event` (`EventID`, `Position`),
+    FOREIGN KEY (`EventID`) REFERENCES `Event`(`EventID`) ON DELETE CASCADE
+);
+
+-- Ответы участника удаляются вместе с его регистрацией. Value хранит текст, выбранный вариант,
+-- JSON-массив вариантов для множественного выбора или true/false для отметки.
+CREATE TABLE `RegistrationAnswer` (
+    `UserID` INT NOT NULL,
+    `EventID` INT NOT NULL,
+    `QuestionID` INT NOT NULL,
+    `Value` TEXT NOT NULL,
+    PRIMARY KEY (`UserID`, `EventID`, `QuestionID`),
+    FOREIGN KEY (`UserID`, `EventID`) REFERENCES `Registration`(`UserID`, `EventID`)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (`QuestionID`) REFERENCES `RegistrationQuestion`(`QuestionID`) ON DELETE CASCADE
+);
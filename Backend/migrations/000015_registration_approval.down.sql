@@ -0,0 +1,8 @@
+DELETE FROM `Registration` WHERE `Status` IN ('pending', 'rejected');
+
+ALTER TABLE `Registration`
+    DROP COLUMN `ReviewMessage`,
+    DROP COLUMN `ReviewedAt`;
+
+ALTER TABLE `Event`
+    DROP COLUMN `RequiresApproval`;
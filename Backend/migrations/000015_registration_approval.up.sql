@@ -0,0 +1,8 @@
+-- Запись на событие с RequiresApproval создает заявку со статусом pending, которую рассматривает организатор.
+-- Отклоненные заявки остаются со статусом rejected, чтобы пользователь не подал ее повторно.
+ALTER TABLE `Event`
+    ADD COLUMN `RequiresApproval` BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE `Registration`
+    ADD COLUMN `ReviewedAt` DATETIME(6) NULL,
+    ADD COLUMN `ReviewMessage` VARCHAR(1000) NULL;
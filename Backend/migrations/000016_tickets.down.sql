@@ -0,0 +1,5 @@
+ALTER TABLE `Registration`
+    DROP FOREIGN KEY `fk_registration_checked_in_by`,
+    DROP COLUMN `CheckedInByUserID`,
+    DROP COLUMN `CheckedInAt`,
+    DROP COLUMN `TicketNonce`;
@@ -0,0 +1,11 @@
+-- Билеты: TicketNonce входит в подписанный код билета, его смена аннулирует выданный билет.
+-- Отметка на входе хранит время и сотрудника, отметившего участника.
+ALTER TABLE `Registration`
+    ADD COLUMN `TicketNonce` VARCHAR(32) NULL,
+    ADD COLUMN `CheckedInAt` DATETIME(6) NULL,
+    ADD COLUMN `CheckedInByUserID` INT NULL,
+    ADD CONSTRAINT `fk_registration_checked_in_by` FOREIGN KEY (`CheckedInByUserID`) REFERENCES `User`(`UserID`)
+        ON DELETE SET NULL ON UPDATE CASCADE;
+
+-- Существующие регистрации получают случайный nonce
+UPDATE `Registration` SET `TicketNonce` = TO_BASE64(RANDOM_BYTES(12));
@@ -0,0 +1,2 @@
+ALTER TABLE `Registration`
+    DROP COLUMN `CheckedInDevice`;
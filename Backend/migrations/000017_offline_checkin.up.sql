@@ -0,0 +1,4 @@
+-- Устройство, с которого отмечен участник. Для отметок, загруженных без сети, по нему
+-- разрешается конфликт при одинаковом времени сканирования.
+ALTER TABLE `Registration`
+    ADD COLUMN `CheckedInDevice` VARCHAR(64) NULL;
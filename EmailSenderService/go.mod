@@ -2,10 +2,12 @@ module EmailSenderService
 
 go 1.23.4
 
-require github.com/go-sql-driver/mysql v1.8.1
+require (
+	github.com/TkMaxim9/EventOrganizationApp/proto v0.0.0-20250217173252-428784ef2eca
+	github.com/go-sql-driver/mysql v1.8.1
+)
 
 require (
-	github.com/TkMaxim9/EventOrganizationApp/proto v0.0.0-20250217173252-428784ef2eca // indirect
 	github.com/hashicorp/errwrap v1.1.0 // indirect
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
 	go.uber.org/atomic v1.7.0 // indirect
@@ -22,3 +24,5 @@ require (
 	google.golang.org/grpc v1.70.0
 	google.golang.org/protobuf v1.36.5 // indirect
 )
+
+replace github.com/TkMaxim9/EventOrganizationApp/proto => ../proto
@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -91,42 +92,23 @@ func (s *Storage) AddNotification(dto storage.CreateNotificationDto) ([]int64, e
 	return ids, nil
 }
 
-func (s *Storage) DeleteNotifications(id1, id2 int64) error {
+// DeleteNotifications удаляет уведомления по идентификаторам.
+// Уже отправленные уведомления к этому моменту удалены, поэтому отсутствующие идентификаторы не считаются ошибкой.
+func (s *Storage) DeleteNotifications(ids []int64) error {
 	const op = "storage.DeleteNotifications"
 
-	// Функция для удаления одной записи по ID
-	deleteNotification := func(id int64) error {
-		stmt, err := s.Db.Prepare("DELETE FROM notifications WHERE id = ?")
-		if err != nil {
-			return fmt.Errorf("%s: failed to prepare statement: %w", op, err)
-		}
-		defer stmt.Close()
-
-		res, err := stmt.Exec(id)
-		if err != nil {
-			return fmt.Errorf("%s: failed to delete notification with ID %d: %w", op, id, err)
-		}
-
-		rowsAffected, err := res.RowsAffected()
-		if err != nil {
-			return fmt.Errorf("%s: failed to check rows affected for ID %d: %w", op, id, err)
-		}
-
-		if rowsAffected == 0 {
-			return fmt.Errorf("%s: no notification found with ID %d", op, id)
-		}
-
+	if len(ids) == 0 {
 		return nil
 	}
 
-	// Удаляем первую запись
-	if err := deleteNotification(id1); err != nil {
-		return fmt.Errorf("%s: failed to delete first notification: %w", op, err)
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
 	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
 
-	// Удаляем вторую запись
-	if err := deleteNotification(id2); err != nil {
-		return fmt.Errorf("%s: failed to delete second notification: %w", op, err)
+	if _, err := s.Db.Exec("DELETE FROM notifications WHERE id IN ("+placeholders+")", args...); err != nil {
+		return fmt.Errorf("%s: failed to delete notifications: %w", op, err)
 	}
 
 	return nil
@@ -1,15 +1,16 @@
 package emailsender
 
 import (
-	// "EmailSenderService/pkg/services/eventemail"
 	"EmailSenderService/config"
 	"EmailSenderService/internal/storage"
+	"EmailSenderService/pkg/services/eventemail"
 	"context"
 	"log"
-	"os"
 	"time"
 
 	pb "github.com/TkMaxim9/EventOrganizationApp/proto/notifications"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type GRPCServer struct {
@@ -28,8 +29,8 @@ func (s *GRPCServer) CreateNotification(ctx context.Context, req *pb.CreateNotif
 
 	ids, err := config.GlobalStorage.AddNotification(dto)
 	if err != nil {
-		log.Fatalf("error while adding notification")
-		os.Exit(1)
+		log.Printf("Failed to add notification: %v", err)
+		return nil, status.Error(codes.Internal, "failed to add notification")
 	}
 
 	return &pb.CreateNotificationResponse{
@@ -38,16 +39,37 @@ func (s *GRPCServer) CreateNotification(ctx context.Context, req *pb.CreateNotif
 }
 
 func (s *GRPCServer) DeleteNotifications(ctx context.Context, req *pb.DeleteNotificationsRequest) (*pb.DeleteNotificationsResponse, error) {
-	ids := req.GetNotificationIds()
-
-	err := config.GlobalStorage.DeleteNotifications(ids[0], ids[1])
-
+	err := config.GlobalStorage.DeleteNotifications(req.GetNotificationIds())
 	if err != nil {
-		log.Fatalf("error while deleting notification")
-		os.Exit(1)
+		log.Printf("Failed to delete notifications: %v", err)
+		return nil, status.Error(codes.Internal, "failed to delete notifications")
 	}
 
 	return &pb.DeleteNotificationsResponse{
 		Success: true,
 	}, nil
 }
+
+func (s *GRPCServer) SendEmail(ctx context.Context, req *pb.SendEmailRequest) (*pb.SendEmailResponse, error) {
+	if req.GetTo() == "" || req.GetSubject() == "" {
+		return nil, status.Error(codes.InvalidArgument, "recipient and subject are required")
+	}
+
+	attachments := make([]eventemail.Attachment, 0, len(req.GetAttachments()))
+	for _, a := range req.GetAttachments() {
+		attachments = append(attachments, eventemail.Attachment{
+			Filename:    a.GetFilename(),
+			ContentType: a.GetContentType(),
+			Content:     a.GetContent(),
+		})
+	}
+
+	if err := eventemail.SendEmailWithAttachments(req.GetTo(), req.GetSubject(), req.GetBody(), attachments); err != nil {
+		log.Printf("Failed to send email to %s: %v", req.GetTo(), err)
+		return nil, status.Error(codes.Unavailable, "failed to send email")
+	}
+
+	return &pb.SendEmailResponse{
+		Success: true,
+	}, nil
+}
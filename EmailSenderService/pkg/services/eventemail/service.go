@@ -1,12 +1,40 @@
 package eventemail
 
 import (
+	"bytes"
 	"crypto/tls"
+	"encoding/base64"
 	"fmt"
+	"mime"
+	"mime/multipart"
 	"net/smtp"
+	"net/textproto"
 )
 
+// Attachment - файл, прикладываемый к письму
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
 func SendEventNotification(address string, event string, date string) (bool, error) {
+	body := "Привет, " + event + " состоится уже " + date + " не забудь!!!"
+
+	if err := SendEmail(address, "Уведомление о событии", body); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// SendEmail отправляет письмо с произвольной темой и текстом
+func SendEmail(address string, subject string, body string) error {
+	return SendEmailWithAttachments(address, subject, body, nil)
+}
+
+// SendEmailWithAttachments отправляет письмо с вложениями. Без вложений письмо остается простым текстом.
+func SendEmailWithAttachments(address string, subject string, body string, attachments []Attachment) error {
 	// Конфигурация SMTP
 	smtpHost := "smtp.yandex.ru"
 	smtpPort := "465"
@@ -22,54 +50,114 @@ func SendEventNotification(address string, event string, date string) (bool, err
 	// Установление соединения с сервером
 	conn, err := tls.Dial("tcp", smtpHost+":"+smtpPort, tlsConfig)
 	if err != nil {
-		return false, fmt.Errorf("failed to connect to SMTP server: %v", err)
+		return fmt.Errorf("failed to connect to SMTP server: %v", err)
 	}
 	defer conn.Close()
 
 	// Создание клиента SMTP поверх TLS
 	client, err := smtp.NewClient(conn, smtpHost)
 	if err != nil {
-		return false, fmt.Errorf("failed to create SMTP client: %v", err)
+		return fmt.Errorf("failed to create SMTP client: %v", err)
 	}
 
 	// Аутентификация
 	auth := smtp.PlainAuth("", from, password, smtpHost)
 	if err := client.Auth(auth); err != nil {
-		return false, fmt.Errorf("authentication failed: %v", err)
+		return fmt.Errorf("authentication failed: %v", err)
 	}
 
 	// Установка адреса отправителя
 	if err := client.Mail(from); err != nil {
-		return false, fmt.Errorf("failed to set sender: %v", err)
+		return fmt.Errorf("failed to set sender: %v", err)
 	}
 
 	// Установка адресата
 	if err := client.Rcpt(address); err != nil {
-		return false, fmt.Errorf("failed to set recipient: %v", err)
+		return fmt.Errorf("failed to set recipient: %v", err)
 	}
 
 	// Отправка сообщения
 	w, err := client.Data()
 	if err != nil {
-		return false, fmt.Errorf("failed to start data transfer: %v", err)
+		return fmt.Errorf("failed to start data transfer: %v", err)
 	}
 
-	fullMessageText := "Subject: Уведомление о событии\r\n" +
-		"Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n" +
-		"Привет, " + event + " состоится уже " + date + " не забудь!!!"
+	fullMessageText, err := buildMessage(subject, body, attachments)
+	if err != nil {
+		return fmt.Errorf("failed to build message: %v", err)
+	}
 
-	if _, err := w.Write([]byte(fullMessageText)); err != nil {
-		return false, fmt.Errorf("failed to write message: %v", err)
+	if _, err := w.Write(fullMessageText); err != nil {
+		return fmt.Errorf("failed to write message: %v", err)
 	}
 
 	if err := w.Close(); err != nil {
-		return false, fmt.Errorf("failed to close message writer: %v", err)
+		return fmt.Errorf("failed to close message writer: %v", err)
 	}
 
 	// Завершение сессии
 	if err := client.Quit(); err != nil {
-		return false, fmt.Errorf("failed to close connection: %v", err)
+		return fmt.Errorf("failed to close connection: %v", err)
 	}
 
-	return true, nil
+	return nil
+}
+
+// buildMessage собирает заголовки и тело письма. Вложения передаются в multipart/mixed в base64.
+func buildMessage(subject string, body string, attachments []Attachment) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+
+	if len(attachments) == 0 {
+		buf.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+		buf.WriteString(body)
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: multipart/mixed; boundary=\"" + mw.Boundary() + "\"\r\n\r\n")
+
+	text, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=\"utf-8\""}})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := text.Write([]byte(body)); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range attachments {
+		contentType := attachment.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		filename := mime.QEncoding.Encode("utf-8", attachment.Filename)
+
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {fmt.Sprintf("%s; name=\"%s\"", contentType, filename)},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=\"%s\"", filename)},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Строки base64 в письме не должны быть длиннее 76 символов
+		encoded := base64.StdEncoding.EncodeToString(attachment.Content)
+		for len(encoded) > 76 {
+			if _, err := part.Write([]byte(encoded[:76] + "\r\n")); err != nil {
+				return nil, err
+			}
+			encoded = encoded[76:]
+		}
+		if _, err := part.Write([]byte(encoded + "\r\n")); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
 }
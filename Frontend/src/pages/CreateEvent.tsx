@@ -1,6 +1,6 @@
 import React, { useState } from 'react';
 import { BACKEND_PATH } from '../../constants/constants';
-import useUserStore from '../store/UserStore';
+import useUserStore, { authFetch } from '../store/UserStore';
 import { useNavigate } from 'react-router-dom';
 
 interface IEvent {
@@ -123,7 +123,7 @@ const CreateEvent: React.FC = () => {
                 }
             }
     
-            const response = await fetch(BACKEND_PATH + "/event", {
+            const response = await authFetch(BACKEND_PATH + "/event", {
                 method: "POST",
                 body: formData,
             });
@@ -1,6 +1,6 @@
 import React, { useState, useEffect } from 'react';
 import { BACKEND_PATH } from '../../constants/constants';
-import useUserStore from '../store/UserStore';
+import useUserStore, { authFetch } from '../store/UserStore';
 import { useNavigate, useParams } from 'react-router-dom';
 
 interface IEvent {
@@ -35,7 +35,7 @@ const EditEvent: React.FC = () => {
     useEffect(() => {
         const fetchEvent = async () => {
             try {
-                const response = await fetch(`${BACKEND_PATH}/event/${id}`);
+                const response = await authFetch(`${BACKEND_PATH}/event/${id}`);
                 
                 if (!response.ok) {
                     throw new Error('Не удалось загрузить данные мероприятия');
@@ -154,7 +154,7 @@ const EditEvent: React.FC = () => {
                 formData.append("keepOriginalImage", "true");
             }
     
-            const response = await fetch(`${BACKEND_PATH}/profile`, {
+            const response = await authFetch(`${BACKEND_PATH}/event/${id}`, {
                 method: "PUT",
                 body: formData,
             });
@@ -162,7 +162,7 @@ const EditEvent: React.FC = () => {
             const result = await response.json();
     
             if (!response.ok) {
-                throw new Error(result.message || "Ошибка при обновлении мероприятия");
+                throw new Error(result.error || "Ошибка при обновлении мероприятия");
             }
     
             alert("Мероприятие успешно обновлено!");
@@ -1,6 +1,7 @@
 import React, { useState } from "react";
 import { Link, useNavigate } from "react-router-dom";
 import { BACKEND_PATH } from "../../constants/constants";
+import { authFetch } from "../store/UserStore.ts";
 
 export type EventCardProps = {
     id: number;
@@ -38,7 +39,7 @@ const EventCard: React.FC<EventCardProps> = ({
         try {
             setIsDeleting(true);
 
-            const response = await fetch(BACKEND_PATH + "/event/" + id, {
+            const response = await authFetch(BACKEND_PATH + "/event/" + id, {
                 method: "DELETE",
             });
     
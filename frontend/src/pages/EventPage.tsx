@@ -1,6 +1,6 @@
 import React, { useEffect, useState } from 'react';
 import { useParams, Link, useNavigate } from 'react-router-dom';
-import useUserStore from "../store/UserStore.ts";
+import useUserStore, { authFetch } from "../store/UserStore.ts";
 import {BACKEND_PATH} from "../../constants/constants.ts";
 import { IUserInfo } from './Profile.tsx';
 
@@ -63,11 +63,10 @@ const EventPage: React.FC = () => {
         }
 
         try {
-            const response = await fetch(`${BACKEND_PATH}/participate`, {
+            const response = await authFetch(`${BACKEND_PATH}/participate`, {
                 method: 'POST',
                 headers: {
-                    'Content-Type': 'application/json',
-                    'Authorization': `Bearer ${token}`
+                    'Content-Type': 'application/json'
                 },
                 body: JSON.stringify({
                     eventId: Number(id), 
@@ -95,11 +94,10 @@ const EventPage: React.FC = () => {
         }
         
         try {
-            const response = await fetch(`${BACKEND_PATH}/registration`, {
+            const response = await authFetch(`${BACKEND_PATH}/registration`, {
                 method: 'DELETE',
                 headers: {
-                    'Content-Type': 'application/json',
-                    'Authorization': `Bearer ${token}`
+                    'Content-Type': 'application/json'
                 },
                 body: JSON.stringify({
                     event_id: Number(id),
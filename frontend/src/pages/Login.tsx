@@ -48,7 +48,7 @@ const Login: React.FC = () => {
                 throw new Error(data.message || "Ошибка при входе в систему");
             }
     
-            login(data.user.id, data.token);
+            login(data);
 
             navigate(redirectPath);
         } catch (err) {
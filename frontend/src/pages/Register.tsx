@@ -1,7 +1,7 @@
 import React, { useState } from "react";
 import { useNavigate, Link } from "react-router-dom";
 import useUserStore from "../store/UserStore.ts";
-import { BACKEND_PATH } from "../../constants/constants.ts";
+import { AUTH_PATH, BACKEND_PATH } from "../../constants/constants.ts";
 
 const Register: React.FC = () => {
     const [firstName, setFirstName] = useState("");
@@ -54,36 +54,34 @@ const Register: React.FC = () => {
 
         setLoading(true);
         
-        const formData = new FormData();
-
-        // Создаем объект пользователя
-        const user = {
-            firstName,
-            lastName,
-            email,
-            password
-        };
-        
-        // Добавляем объект user в FormData как строку (JSON)
-        formData.append("user", JSON.stringify(user));
-
-        if (avatar) {
-            formData.append("image", avatar);
-        }
-
         try {
-            const response = await fetch(BACKEND_PATH + "/register", {
+            // Учетная запись создается в AuthService, профиль в Backend появляется с тем же id
+            const response = await fetch(AUTH_PATH + "/register", {
                 method: "POST",
-                body: formData,
+                headers: { "Content-Type": "application/json" },
+                body: JSON.stringify({ firstName, lastName, email, password }),
             });
 
+            if (!response.ok) {
+                const message = await response.text();
+                throw new Error(message || "Ошибка при регистрации");
+            }
+
             const data = await response.json();
 
-            if (!response.ok) {
-                throw new Error(data.error || "Ошибка при регистрации");
+            if (avatar) {
+                const formData = new FormData();
+                formData.append("image", avatar);
+
+                // Аватар не обязателен, поэтому ошибка загрузки не прерывает регистрацию
+                await fetch(BACKEND_PATH + "/profile/avatar", {
+                    method: "PUT",
+                    headers: { Authorization: "Bearer " + data.token },
+                    body: formData,
+                }).catch(() => undefined);
             }
 
-            login(data.userId, data.token);
+            login(data);
             navigate("/");
         } catch (err) {
             setError(err instanceof Error ? err.message : "Произошла ошибка при регистрации");
@@ -1,30 +1,135 @@
 import { create } from "zustand";
+import { AUTH_PATH } from "../../constants/constants.ts";
+
+// Ответ AuthService при входе, регистрации и обновлении токенов
+export interface ISession {
+    token: string;
+    expires: string;
+    refreshToken: string;
+    user: { id: number };
+}
 
 interface StoreState {
     token: string | null;
+    refreshToken: string | null;
+    // expires - время истечения access-токена в миллисекундах
+    expires: number | null;
     userId: number | null;
-    login: (userId: number, token: string) => void;
+    login: (session: ISession) => void;
     logout: () => void;
 }
 
-const useUserStore = create<StoreState>((set) => {
+const useUserStore = create<StoreState>((set, get) => {
     const storedToken = localStorage.getItem("token");
+    const storedRefreshToken = localStorage.getItem("refreshToken");
+    const storedExpires = localStorage.getItem("expires");
     const storedUserId = localStorage.getItem("userId");
 
     return {
         token: storedToken || null,
+        refreshToken: storedRefreshToken || null,
+        expires: storedExpires ? Number(storedExpires) : null,
         userId: storedUserId ? Number(storedUserId) : null,
-        login: (userId, token) => {
-            localStorage.setItem("token", token);
-            localStorage.setItem("userId", userId.toString());
-            set({ userId, token });
+        login: (session) => {
+            const expires = Date.parse(session.expires);
+            localStorage.setItem("token", session.token);
+            localStorage.setItem("refreshToken", session.refreshToken);
+            localStorage.setItem("expires", expires.toString());
+            localStorage.setItem("userId", session.user.id.toString());
+            set({ userId: session.user.id, token: session.token, refreshToken: session.refreshToken, expires });
         },
         logout: () => {
+            const refreshToken = get().refreshToken;
+            if (refreshToken) {
+                // Сессия завершается и на сервере, ошибка не мешает выйти локально
+                fetch(AUTH_PATH + "/logout", {
+                    method: "POST",
+                    headers: { "Content-Type": "application/json" },
+                    body: JSON.stringify({ refreshToken }),
+                }).catch(() => undefined);
+            }
+
             localStorage.removeItem("token");
+            localStorage.removeItem("refreshToken");
+            localStorage.removeItem("expires");
             localStorage.removeItem("userId");
-            set({ userId: null, token: null });
+            set({ userId: null, token: null, refreshToken: null, expires: null });
         },
     };
 });
 
+// Токен обновляется заранее, чтобы он не истек по дороге к серверу
+const REFRESH_MARGIN_MS = 30 * 1000;
+
+// Обновление идет одно на всех: refresh-токен одноразовый, и повторное
+// использование того же токена сервер считает кражей и завершает сессию
+let refreshing: Promise<string | null> | null = null;
+
+// refreshSession меняет refresh-токен на новую пару токенов. При отказе сервера пользователь выходит.
+const refreshSession = (): Promise<string | null> => {
+    if (refreshing) {
+        return refreshing;
+    }
+
+    const { refreshToken, login, logout } = useUserStore.getState();
+    if (!refreshToken) {
+        return Promise.resolve(null);
+    }
+
+    refreshing = (async () => {
+        try {
+            const response = await fetch(AUTH_PATH + "/refresh", {
+                method: "POST",
+                headers: { "Content-Type": "application/json" },
+                body: JSON.stringify({ refreshToken }),
+            });
+
+            if (response.status === 401) {
+                logout();
+                return null;
+            }
+            if (!response.ok) {
+                return null;
+            }
+
+            const session: ISession = await response.json();
+            login(session);
+            return session.token;
+        } catch {
+            return null;
+        } finally {
+            refreshing = null;
+        }
+    })();
+
+    return refreshing;
+};
+
+// authFetch выполняет запрос с access-токеном, обновляя его перед истечением
+// и повторяя запрос один раз, если сервер ответил 401
+export const authFetch = async (input: string, init: RequestInit = {}): Promise<Response> => {
+    const { token, expires } = useUserStore.getState();
+
+    let current = token;
+    if (current && expires && expires - Date.now() < REFRESH_MARGIN_MS) {
+        current = (await refreshSession()) ?? current;
+    }
+
+    const send = (accessToken: string | null) => {
+        const headers = new Headers(init.headers);
+        if (accessToken) {
+            headers.set("Authorization", `Bearer ${accessToken}`);
+        }
+        return fetch(input, { ...init, headers });
+    };
+
+    const response = await send(current);
+    if (response.status !== 401 || !current) {
+        return response;
+    }
+
+    const refreshed = await refreshSession();
+    return refreshed ? send(refreshed) : response;
+};
+
 export default useUserStore;
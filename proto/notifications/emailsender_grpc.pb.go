@@ -21,6 +21,7 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	NotificationService_CreateNotification_FullMethodName  = "/notifications.NotificationService/CreateNotification"
 	NotificationService_DeleteNotifications_FullMethodName = "/notifications.NotificationService/DeleteNotifications"
+	NotificationService_SendEmail_FullMethodName           = "/notifications.NotificationService/SendEmail"
 )
 
 // NotificationServiceClient is the client API for NotificationService service.
@@ -33,6 +34,8 @@ type NotificationServiceClient interface {
 	CreateNotification(ctx context.Context, in *CreateNotificationRequest, opts ...grpc.CallOption) (*CreateNotificationResponse, error)
 	// Метод для удаления уведомлений
 	DeleteNotifications(ctx context.Context, in *DeleteNotificationsRequest, opts ...grpc.CallOption) (*DeleteNotificationsResponse, error)
+	// Метод для немедленной отправки письма (сброс пароля, подтверждение почты и т.п.)
+	SendEmail(ctx context.Context, in *SendEmailRequest, opts ...grpc.CallOption) (*SendEmailResponse, error)
 }
 
 type notificationServiceClient struct {
@@ -63,6 +66,16 @@ func (c *notificationServiceClient) DeleteNotifications(ctx context.Context, in
 	return out, nil
 }
 
+func (c *notificationServiceClient) SendEmail(ctx context.Context, in *SendEmailRequest, opts ...grpc.CallOption) (*SendEmailResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SendEmailResponse)
+	err := c.cc.Invoke(ctx, NotificationService_SendEmail_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NotificationServiceServer is the server API for NotificationService service.
 // All implementations must embed UnimplementedNotificationServiceServer
 // for forward compatibility.
@@ -73,6 +86,8 @@ type NotificationServiceServer interface {
 	CreateNotification(context.Context, *CreateNotificationRequest) (*CreateNotificationResponse, error)
 	// Метод для удаления уведомлений
 	DeleteNotifications(context.Context, *DeleteNotificationsRequest) (*DeleteNotificationsResponse, error)
+	// Метод для немедленной отправки письма (сброс пароля, подтверждение почты и т.п.)
+	SendEmail(context.Context, *SendEmailRequest) (*SendEmailResponse, error)
 	mustEmbedUnimplementedNotificationServiceServer()
 }
 
@@ -89,6 +104,9 @@ func (UnimplementedNotificationServiceServer) CreateNotification(context.Context
 func (UnimplementedNotificationServiceServer) DeleteNotifications(context.Context, *DeleteNotificationsRequest) (*DeleteNotificationsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteNotifications not implemented")
 }
+func (UnimplementedNotificationServiceServer) SendEmail(context.Context, *SendEmailRequest) (*SendEmailResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendEmail not implemented")
+}
 func (UnimplementedNotificationServiceServer) mustEmbedUnimplementedNotificationServiceServer() {}
 func (UnimplementedNotificationServiceServer) testEmbeddedByValue()                             {}
 
@@ -146,6 +164,24 @@ func _NotificationService_DeleteNotifications_Handler(srv interface{}, ctx conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _NotificationService_SendEmail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendEmailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).SendEmail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_SendEmail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).SendEmail(ctx, req.(*SendEmailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // NotificationService_ServiceDesc is the grpc.ServiceDesc for NotificationService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -161,6 +197,10 @@ var NotificationService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteNotifications",
 			Handler:    _NotificationService_DeleteNotifications_Handler,
 		},
+		{
+			MethodName: "SendEmail",
+			Handler:    _NotificationService_SendEmail_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "EmailSenderService/api/proto/emailsender.proto",